@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/klog/v2"
+)
+
+// healthShutdownTimeout is how long the health server waits for open requests on shutdown.
+const healthShutdownTimeout = 5 * time.Second
+
+// healthServer serves the liveness, readiness and metrics endpoints of the daemon.
+type healthServer struct {
+	server *http.Server
+	// ready is set once labels have been output successfully.
+	ready atomic.Bool
+}
+
+// newHealthServer creates a health server for the specified port. It serves /healthz, which
+// succeeds while the process is alive, /readyz, which succeeds once labels have been output,
+// and /metrics with the process metrics.
+func newHealthServer(port int) *healthServer {
+	s := &healthServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready.Load() {
+			http.Error(w, "labels not output yet", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+
+	s.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// start listens on the port of the server and serves requests in a separate goroutine.
+func (s *healthServer) start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on health port: %w", err)
+	}
+	go func() {
+		klog.Infof("Serving health endpoints on %s", s.server.Addr)
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Errorf("Health server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// setReady marks the daemon as ready.
+func (s *healthServer) setReady() {
+	s.ready.Store(true)
+}
+
+// shutdown stops the server, waiting up to healthShutdownTimeout for open requests.
+func (s *healthServer) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), healthShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		klog.Warningf("Failed to shut down health server: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	coreclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/config"
+)
+
+const (
+	// leaseDuration is how long the lease is valid without being renewed.
+	leaseDuration = 15 * time.Second
+	// leaseRenewDeadline is how long the leader retries renewing the lease before giving it up.
+	leaseRenewDeadline = 10 * time.Second
+	// leaseRetryPeriod is how often the lease is renewed, or an attempt is made to acquire it.
+	leaseRetryPeriod = 2 * time.Second
+)
+
+// leaderElection competes for the lease of a node, so that only one instance on the node
+// outputs labels.
+type leaderElection struct {
+	cancel context.CancelFunc
+	// done is closed when the election has stopped.
+	done chan struct{}
+	// leading is closed when the lease is acquired.
+	leading chan struct{}
+	// lost is closed when the lease is lost or released.
+	lost chan struct{}
+}
+
+// startLeaderElection starts competing for the lease named ix-feature-discovery-<nodename>
+// in the namespace of the node config.
+func startLeaderElection(client coreclientset.Interface, nodeConfig config.NodeConfig) (*leaderElection, error) {
+	// The hostname is the pod name, unless the pod uses the host network. A random suffix
+	// keeps the identities of the instances on a node distinct in either case.
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname for leader election identity: %w", err)
+	}
+	identity := hostname + "_" + utilrand.String(5)
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      "ix-feature-discovery-" + nodeConfig.Name,
+			Namespace: nodeConfig.Namespace,
+		},
+		Client:     client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	e := &leaderElection{
+		done:    make(chan struct{}),
+		leading: make(chan struct{}),
+		lost:    make(chan struct{}),
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   leaseRenewDeadline,
+		RetryPeriod:     leaseRetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { close(e.leading) },
+			OnStoppedLeading: func() { close(e.lost) },
+			OnNewLeader: func(leader string) {
+				klog.Infof("Lease %s is held by %s", lock.Describe(), leader)
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leader elector: %w", err)
+	}
+
+	var ctx context.Context
+	ctx, e.cancel = context.WithCancel(context.Background())
+	go func() {
+		defer close(e.done)
+		elector.Run(ctx)
+	}()
+
+	klog.Infof("Waiting to acquire lease %s as %s", lock.Describe(), identity)
+	return e, nil
+}
+
+// wait blocks until the lease is acquired or a signal is received. If a signal is received
+// first, acquired is false and restart reports whether the signal requests a restart.
+// SIGUSR1 and SIGUSR2 are ignored, as no labels are generated before the lease is acquired.
+func (e *leaderElection) wait(sigs chan os.Signal) (acquired bool, restart bool) {
+	for {
+		select {
+		case <-e.leading:
+			klog.Info("Acquired lease, start labeling.")
+			return true, false
+		case s := <-sigs:
+			switch s {
+			case syscall.SIGHUP:
+				klog.Info("Received SIGHUP while waiting for lease, restarting.")
+				return false, true
+			case syscall.SIGUSR1, syscall.SIGUSR2:
+				klog.Infof("Received signal %v while waiting for lease, ignoring.", s)
+			default:
+				klog.Infof("Received signal %v while waiting for lease, shutting down gracefully.", s)
+				return false, false
+			}
+		}
+	}
+}
+
+// stop stops competing for the lease, releasing it if it is held, and waits for the election
+// to finish.
+func (e *leaderElection) stop() {
+	e.cancel()
+	<-e.done
+}
@@ -18,10 +18,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"runtime"
 	"syscall"
 	"time"
 
@@ -38,6 +43,9 @@ import (
 type Config struct {
 	kubeClientConfig config.KubeClientConfig
 	nodeConfig       config.NodeConfig
+	// healthPort is the port of the health server. Unlike the other options it cannot be
+	// changed by a restart, as the server runs for the lifetime of the process.
+	healthPort int
 
 	// flags stores the CLI flags for later processing.
 	flags []cli.Flag
@@ -52,8 +60,23 @@ func main() {
 	app.Action = func(ctx *cli.Context) error {
 		return start(ctx, config)
 	}
+	app.Commands = []*cli.Command{
+		{
+			Name:  "one-shot",
+			Usage: "generate and output the labels once and exit, failing if any GPU is unhealthy",
+			Action: func(ctx *cli.Context) error {
+				return oneShot(ctx, config)
+			},
+		},
+	}
 
 	config.flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"config-file"},
+			Usage:   "a path to a YAML or JSON config file; flags set on the command line or in the environment override its values",
+			EnvVars: []string{"CONFIG_FILE"},
+		},
 		&cli.BoolFlag{
 			Name:    "no-timestamp",
 			Value:   false,
@@ -66,10 +89,65 @@ func main() {
 			Usage:   "Time to sleep between labeling",
 			EnvVars: []string{"SLEEP_INTERVAL"},
 		},
+		&cli.IntFlag{
+			Name:    "sleep-jitter-percent",
+			Value:   10,
+			Usage:   "Maximum random time added to the sleep interval, as a percentage of the interval, so that nodes do not update in lockstep",
+			EnvVars: []string{"SLEEP_JITTER_PERCENT"},
+		},
+		&cli.DurationFlag{
+			Name:    "cycle-timeout",
+			Value:   0,
+			Usage:   "Maximum duration of a labeling cycle, 0 for no limit",
+			EnvVars: []string{"CYCLE_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:    "max-labeler-concurrency",
+			Value:   runtime.NumCPU(),
+			Usage:   "Maximum number of labelers that generate labels concurrently",
+			EnvVars: []string{"MAX_LABELER_CONCURRENCY"},
+		},
+		&cli.DurationFlag{
+			Name:    "ixml-timeout",
+			Value:   30 * time.Second,
+			Usage:   "Maximum duration of an IXML call, 0 for no limit",
+			EnvVars: []string{"IXML_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:    "circuit-breaker-threshold",
+			Value:   3,
+			Usage:   "Number of consecutive IXML failures after which IXML is not called for the circuit breaker cooldown, 0 to disable",
+			EnvVars: []string{"CIRCUIT_BREAKER_THRESHOLD"},
+		},
+		&cli.DurationFlag{
+			Name:    "circuit-breaker-cooldown",
+			Value:   5 * time.Minute,
+			Usage:   "Duration for which IXML is not called once the circuit breaker opens",
+			EnvVars: []string{"CIRCUIT_BREAKER_COOLDOWN"},
+		},
+		&cli.DurationFlag{
+			Name:    "hardware-labels-ttl",
+			Value:   time.Hour,
+			Usage:   "Duration for which the labels of static device attributes, e.g. serial numbers and VBIOS versions, are reused before the devices are queried again, 0 to query them every cycle",
+			EnvVars: []string{"HARDWARE_LABELS_TTL"},
+		},
+		&cli.IntFlag{
+			Name:    "labeler-retry-attempts",
+			Value:   3,
+			Usage:   "Maximum number of attempts of a labeler whose IXML queries fail, including the first one",
+			EnvVars: []string{"LABELER_RETRY_ATTEMPTS"},
+		},
+		&cli.DurationFlag{
+			Name:    "labeler-retry-delay",
+			Value:   time.Second,
+			Usage:   "Duration to wait between the attempts of a labeler whose IXML queries fail",
+			EnvVars: []string{"LABELER_RETRY_DELAY"},
+		},
 		&cli.StringFlag{
 			Name:    "output-file",
 			Aliases: []string{"output", "o"},
-			Value:   "/etc/kubernetes/node-feature-discovery/features.d/ix-features",
+			Value:   "",
+			Usage:   "a path to a file to write the labels to in the NFD features.d format, e.g. /etc/kubernetes/node-feature-discovery/features.d/ix-features",
 			EnvVars: []string{"OUTPUT_FILE"},
 		},
 		&cli.StringFlag{
@@ -78,6 +156,165 @@ func main() {
 			Usage:   "a path to a file that contains the DMI (SMBIOS) information for the node",
 			EnvVars: []string{"MACHINE_TYPE_FILE"},
 		},
+		&cli.StringFlag{
+			Name:    "device-plugin-config-file",
+			Value:   "",
+			Usage:   "a path to the device plugin config file to read the GPU sharing settings from",
+			EnvVars: []string{"DEVICE_PLUGIN_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "video-capabilities-file",
+			Value:   "",
+			Usage:   "a path to a file that overrides the video encoder, decoder and codec support of GPU products",
+			EnvVars: []string{"VIDEO_CAPABILITIES_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "kernel-module-version-file",
+			Value:   "/sys/module/bi_driver/version",
+			Usage:   "a path to the file that contains the version of the loaded IX kernel driver module",
+			EnvVars: []string{"KERNEL_MODULE_VERSION_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "static-labels-file",
+			Value:   "",
+			Usage:   "a path to a file of key=value labels to add to the node, reloaded when it changes",
+			EnvVars: []string{"STATIC_LABELS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "external-labeler-command",
+			Value:   "",
+			Usage:   "a command to run on every cycle that prints key=value labels to add to the node",
+			EnvVars: []string{"EXTERNAL_LABELER_COMMAND"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "external-labeler-args",
+			Usage:   "an argument of the external labeler command, can be repeated",
+			EnvVars: []string{"EXTERNAL_LABELER_ARGS"},
+		},
+		&cli.DurationFlag{
+			Name:    "external-labeler-timeout",
+			Value:   30 * time.Second,
+			Usage:   "Maximum duration of the external labeler command, 0 for no limit",
+			EnvVars: []string{"EXTERNAL_LABELER_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-ecc-error-labels",
+			Value:   false,
+			Usage:   "Do not add the ECC error count labels, which may change on every labeling",
+			EnvVars: []string{"NO_ECC_ERROR_LABELS"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-partitioning-labels",
+			Value:   false,
+			Usage:   "Do not add the GPU partitioning labels",
+			EnvVars: []string{"NO_PARTITIONING_LABELS"},
+		},
+		&cli.BoolFlag{
+			Name:    "per-device-labels",
+			Value:   false,
+			Usage:   "Add the product and memory labels of every GPU, keyed by the GPU index",
+			EnvVars: []string{"PER_DEVICE_LABELS"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-driver-labels",
+			Value:   false,
+			Usage:   "Do not add the driver and IXML version labels",
+			EnvVars: []string{"NO_DRIVER_LABELS"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-cuda-labels",
+			Value:   false,
+			Usage:   "Do not add the CUDA runtime version and compute capability labels",
+			EnvVars: []string{"NO_CUDA_LABELS"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-gpu-labels",
+			Value:   false,
+			Usage:   "Do not add the GPU device labels",
+			EnvVars: []string{"NO_GPU_LABELS"},
+		},
+		&cli.BoolFlag{
+			Name:    "no-machine-labels",
+			Value:   false,
+			Usage:   "Do not add the machine type labels",
+			EnvVars: []string{"NO_MACHINE_LABELS"},
+		},
+		&cli.IntFlag{
+			Name:    "kube-retry-attempts",
+			Value:   5,
+			Usage:   "Maximum number of attempts of the Kubernetes API requests to update the NodeFeature object",
+			EnvVars: []string{"KUBE_RETRY_ATTEMPTS"},
+		},
+		&cli.DurationFlag{
+			Name:    "kube-retry-base-delay",
+			Value:   time.Second,
+			Usage:   "Delay before the first retry of a transiently failed Kubernetes API request, doubled on each further retry up to 30s",
+			EnvVars: []string{"KUBE_RETRY_BASE_DELAY"},
+		},
+		&cli.BoolFlag{
+			Name:    "use-sysfs",
+			Value:   false,
+			Usage:   "Discover the GPUs from sysfs instead of IXML; only the properties exposed by sysfs are labeled",
+			EnvVars: []string{"USE_SYSFS"},
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Value:   false,
+			Usage:   "Print the labels to stdout instead of writing them to the output file or Kubernetes",
+			EnvVars: []string{"DRY_RUN"},
+		},
+		&cli.BoolFlag{
+			Name:    "best-effort",
+			Value:   false,
+			Usage:   "Skip the labels that cannot be generated instead of failing, and count them in the gpu.discovery-errors label",
+			EnvVars: []string{"BEST_EFFORT"},
+		},
+		&cli.BoolFlag{
+			Name:    "leader-elect",
+			Value:   false,
+			Usage:   "Only label while holding a per-node lease, so that several instances on a node do not conflict",
+			EnvVars: []string{"LEADER_ELECT"},
+		},
+		&cli.StringFlag{
+			Name:    "webhook-url",
+			Usage:   "a URL to which the labels are POSTed as JSON",
+			EnvVars: []string{"WEBHOOK_URL"},
+		},
+		&cli.DurationFlag{
+			Name:    "webhook-timeout",
+			Value:   10 * time.Second,
+			Usage:   "Timeout of each request to the webhook",
+			EnvVars: []string{"WEBHOOK_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:    "metrics-port",
+			Value:   0,
+			Usage:   "Port on which the labels are exposed as Prometheus metrics, 0 to disable",
+			EnvVars: []string{"METRICS_PORT"},
+		},
+		&cli.IntFlag{
+			Name:        "health-port",
+			Value:       8080,
+			Usage:       "Port on which the /healthz, /readyz and /metrics endpoints are served, 0 to disable",
+			Destination: &config.healthPort,
+			EnvVars:     []string{"HEALTH_PORT"},
+		},
+		&cli.StringFlag{
+			Name:    "label-prefix",
+			Value:   label.DefaultLabelPrefix,
+			Usage:   "Prefix of the generated label keys, must be a valid DNS subdomain",
+			EnvVars: []string{"LABEL_PREFIX"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "label-include",
+			Usage:   "Regular expression of the label keys to generate, can be repeated; all labels are generated if not set",
+			EnvVars: []string{"LABEL_INCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "label-exclude",
+			Usage:   "Regular expression of the label keys not to generate, can be repeated",
+			EnvVars: []string{"LABEL_EXCLUDE"},
+		},
 	}
 
 	config.flags = append(config.flags, config.kubeClientConfig.Flags()...)
@@ -91,6 +328,22 @@ func main() {
 	}
 }
 
+// newClientSets creates the Kubernetes clientsets. No clientsets are created in dry-run mode,
+// or in standalone mode, where another output is configured and no Kubernetes API server is,
+// in which case labels are only written to the other outputs.
+func (cfg *Config) newClientSets(conf *config.Config) (config.ClientSets, error) {
+	if *conf.Flags.DryRun {
+		return config.ClientSets{}, nil
+	}
+
+	otherOutput := *conf.Flags.OutputFile != "" || *conf.Flags.WebhookURL != "" || *conf.Flags.MetricsPort != 0
+	if otherOutput && !cfg.kubeClientConfig.Configured() {
+		klog.Info("No kubeconfig set and not running in a cluster, not writing labels to Kubernetes")
+		return config.ClientSets{}, nil
+	}
+	return cfg.kubeClientConfig.NewClientSets()
+}
+
 // loadConfig loads the config from the spec file.
 func (cfg *Config) loadConfig(ctx *cli.Context) (*config.Config, error) {
 	conf, err := config.NewConfig(ctx, cfg.flags)
@@ -106,7 +359,15 @@ func start(ctx *cli.Context, cfg *Config) error {
 	}()
 
 	klog.Info("Initializing OS signal watcher.")
-	sigs := utils.Signals(syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	sigs := utils.Signals(syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	health := newHealthServer(cfg.healthPort)
+	if cfg.healthPort != 0 {
+		if err := health.start(); err != nil {
+			return err
+		}
+		defer health.shutdown()
+	}
 
 	for {
 		// Load the configuration file
@@ -122,9 +383,9 @@ func start(ctx *cli.Context, cfg *Config) error {
 		}
 		klog.Infof("\nRunning with the following configuration:\n%s", string(configJSON))
 
-		manager := resource.NewIXMLManager()
+		manager := newManager(config)
 
-		clientSets, err := cfg.kubeClientConfig.NewClientSets()
+		clientSets, err := cfg.newClientSets(config)
 		if err != nil {
 			return fmt.Errorf("failed to create clientsets: %w", err)
 		}
@@ -138,13 +399,54 @@ func start(ctx *cli.Context, cfg *Config) error {
 			return fmt.Errorf("failed to create label outputer: %w", err)
 		}
 
+		labeler, err := label.NewLabelers(manager, config)
+		if err != nil {
+			return fmt.Errorf("failed to create labelers: %w", err)
+		}
+
 		klog.Info("Start running")
 		d := &ixfd{
-			manager:       manager,
+			labeler:       labeler,
 			config:        config,
 			labelOutputer: labelOutputer,
+			health:        health,
+		}
+		var election *leaderElection
+		if *config.Flags.LeaderElect {
+			if clientSets.Core == nil {
+				klog.Warning("No Kubernetes client available, running without leader election.")
+			} else {
+				election, err = startLeaderElection(clientSets.Core, cfg.nodeConfig)
+				if err != nil {
+					return err
+				}
+				acquired, restart := election.wait(sigs)
+				if !acquired {
+					election.stop()
+					if restart {
+						clientSets.Close()
+						continue
+					}
+					return nil
+				}
+				d.leaseLost = election.lost
+			}
+		}
+		var watcher io.Closer
+		if path := *config.Flags.StaticLabelsFile; path != "" {
+			d.staticLabelsChanges, watcher, err = utils.WatchFile(path)
+			if err != nil {
+				klog.Warningf("Failed to watch static labels file %s, changes are applied on the next cycle: %v", path, err)
+			}
 		}
 		restart, err := d.run(sigs)
+		if watcher != nil {
+			watcher.Close()
+		}
+		if election != nil {
+			election.stop()
+		}
+		clientSets.Close()
 		if err != nil {
 			return err
 		}
@@ -155,15 +457,88 @@ func start(ctx *cli.Context, cfg *Config) error {
 	}
 }
 
+// newManager creates the resource manager for the config. IXML is used, falling back to
+// sysfs if it cannot be initialized, unless sysfs is requested. The circuit breaker wraps
+// the timeout, so that IXML calls that time out count as failures.
+func newManager(config *config.Config) resource.Manager {
+	timeout := time.Duration(*config.Flags.IXMLTimeout)
+	sysfsManager := resource.NewTimeoutManager(resource.NewSysfsManager(), timeout)
+	if *config.Flags.UseSysfs {
+		return resource.NewCachedManager(sysfsManager)
+	}
+
+	var ixmlManager resource.Manager = resource.NewTimeoutManager(resource.NewIXMLManager(), timeout)
+	if threshold := *config.Flags.CircuitBreakerThreshold; threshold > 0 {
+		ixmlManager = resource.NewCircuitBreakerManager(ixmlManager, threshold, time.Duration(*config.Flags.CircuitBreakerCooldown))
+	}
+	return resource.NewCachedManager(resource.NewFallbackManager(ixmlManager, sysfsManager))
+}
+
+// oneShot generates and outputs the labels once, without entering the labeling loop. An
+// error is returned if labeling fails or if any GPU is unhealthy or of unknown health.
+func oneShot(ctx *cli.Context, cfg *Config) error {
+	config, err := cfg.loadConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load config: %v", err)
+	}
+
+	clientSets, err := cfg.newClientSets(config)
+	if err != nil {
+		return fmt.Errorf("failed to create clientsets: %w", err)
+	}
+	defer clientSets.Close()
+
+	labelOutputer, err := label.NewOutputer(config, cfg.nodeConfig, clientSets)
+	if err != nil {
+		return fmt.Errorf("failed to create label outputer: %w", err)
+	}
+
+	manager := newManager(config)
+	labeler, err := label.NewLabelers(manager, config)
+	if err != nil {
+		return fmt.Errorf("failed to create labelers: %w", err)
+	}
+
+	d := &ixfd{
+		labeler:       labeler,
+		config:        config,
+		labelOutputer: labelOutputer,
+	}
+	if _, err := d.label(label.NewTimestampLabeler(config)); err != nil {
+		return err
+	}
+	// The health is checked on the devices rather than read from the labels, which may be
+	// disabled, filtered or transformed.
+	if err := resource.CheckHealth(manager); err != nil {
+		return fmt.Errorf("some GPUs are unhealthy or their health is unknown: %w", err)
+	}
+	return nil
+}
+
 type ixfd struct {
-	manager       resource.Manager
+	// labeler generates the labels of every cycle. It is created once per config load, so
+	// that it keeps its state across cycles.
+	labeler       label.Labeler
 	config        *config.Config
 	labelOutputer label.Outputer
+	// health, if set, is marked ready once labels have been output.
+	health *healthServer
+	// staticLabelsChanges signals changes to the static labels file, if it is watched.
+	staticLabelsChanges <-chan struct{}
+	// leaseLost is closed when the leader election lease is lost, if leader election is enabled.
+	leaseLost <-chan struct{}
 }
 
 func (d *ixfd) run(sigs chan os.Signal) (restart bool, err error) {
 	defer func() {
-		if d.config.Flags.OutputFile != nil && *d.config.Flags.OutputFile == "" {
+		// On shutdown remove the labels, so that they do not outlive the pod, e.g. when it is
+		// evicted or its node is drained.
+		if !restart && err == nil {
+			if err := d.labelOutputer.Cleanup(); err != nil {
+				klog.Warningf("Failed to clean up labels: %v", err)
+			}
+		}
+		if *d.config.Flags.DryRun || *d.config.Flags.OutputFile == "" {
 			return
 		}
 		err := removeOutputFile(*d.config.Flags.OutputFile)
@@ -174,45 +549,46 @@ func (d *ixfd) run(sigs chan os.Signal) (restart bool, err error) {
 
 	timestampLabeler := label.NewTimestampLabeler(d.config)
 rerun:
-	loopLabelers, err := label.NewLabelers(d.manager, d.config)
-	if err != nil {
-		return false, err
-	}
-
-	labelers := label.Merge(
-		timestampLabeler,
-		loopLabelers,
-	)
-
-	labels, err := labelers.Labels()
-	if err != nil {
-		return false, fmt.Errorf("error generating labels: %v", err)
-	}
-
-	if len(labels) <= 1 {
-		klog.Warning("No labels generated from any source")
-	}
-
-	klog.Info("Applying generated labels to the node.")
-	if err := d.labelOutputer.Output(labels); err != nil {
-		return false, err
+	if _, err := d.label(timestampLabeler); err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return false, err
+		}
+		klog.Warningf("Labeling did not complete within the cycle timeout: %v", err)
 	}
 
-	klog.Infof("Sleeping for %s before re-evaluating labels.", time.Duration(*d.config.Flags.SleepInterval).String())
-	rerunTimeout := time.After(time.Duration(*d.config.Flags.SleepInterval))
+	sleepInterval := jitteredInterval(time.Duration(*d.config.Flags.SleepInterval), *d.config.Flags.SleepJitterPercent)
+	klog.Infof("Sleeping for %s before re-evaluating labels.", sleepInterval.String())
+	rerunTimeout := time.After(sleepInterval)
 
 	for {
 		select {
 		case <-rerunTimeout:
 			goto rerun
 
+		case <-d.staticLabelsChanges:
+			klog.Info("Static labels file changed, re-evaluating labels.")
+			goto rerun
+
+		// On losing the lease, restart to wait for the lease again instead of exiting.
+		case <-d.leaseLost:
+			klog.Warning("Lost lease, restarting.")
+			return true, nil
+
 		// Watch for any signals from the OS. On SIGHUP trigger a reload of the config.
+		// On SIGUSR1 re-evaluate the labels immediately. On SIGUSR2 log the current labels
+		// without outputting them or resetting the sleep timer.
 		// On all other signals, exit the loop and exit the program.
 		case s := <-sigs:
 			switch s {
 			case syscall.SIGHUP:
 				klog.Info("Received SIGHUP, restarting.")
 				return true, nil
+			case syscall.SIGUSR1:
+				klog.Info("Received SIGUSR1, re-evaluating labels.")
+				goto rerun
+			case syscall.SIGUSR2:
+				klog.Info("Received SIGUSR2, dumping current labels.")
+				d.dumpLabels(timestampLabeler)
 			default:
 				klog.Infof("Received signal %v, shutting down gracefully.", s)
 				return false, nil
@@ -221,6 +597,86 @@ rerun:
 	}
 }
 
+// label generates the labels, outputs them and returns them. If a cycle timeout is set, the
+// labeling is aborted with context.DeadlineExceeded once it expires.
+func (d *ixfd) label(timestampLabeler label.Labeler) (label.Labels, error) {
+	ctx, cancel := d.cycleContext()
+	defer cancel()
+
+	labels, err := d.generateLabels(ctx, timestampLabeler)
+	if err != nil {
+		return nil, err
+	}
+
+	if !label.GPUPresent(d.config, labels) {
+		klog.Warning("No GPUs detected, GPU labels are omitted")
+	}
+
+	if errs := label.ValidateLabels(labels); len(errs) != 0 {
+		return nil, fmt.Errorf("invalid labels generated: %w", errors.Join(errs...))
+	}
+
+	klog.Info("Applying generated labels to the node.")
+	if err := d.labelOutputer.Output(ctx, labels); err != nil {
+		return nil, err
+	}
+	if d.health != nil {
+		d.health.setReady()
+	}
+	return labels, nil
+}
+
+// dumpLabels generates the labels and logs them as JSON, without outputting them.
+func (d *ixfd) dumpLabels(timestampLabeler label.Labeler) {
+	ctx, cancel := d.cycleContext()
+	defer cancel()
+
+	labels, err := d.generateLabels(ctx, timestampLabeler)
+	if err != nil {
+		klog.Warningf("Failed to generate labels to dump: %v", err)
+		return
+	}
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		klog.Warningf("Failed to marshal labels to JSON: %v", err)
+		return
+	}
+	klog.Infof("Current labels: %s", labelsJSON)
+}
+
+// generateLabels generates the labels from all sources.
+func (d *ixfd) generateLabels(ctx context.Context, timestampLabeler label.Labeler) (label.Labels, error) {
+	labelers := label.Merge(
+		timestampLabeler,
+		d.labeler,
+	)
+
+	labels, err := labelers.Labels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error generating labels: %w", err)
+	}
+	return labels, nil
+}
+
+// cycleContext returns the context of a labeling cycle, which expires after the cycle
+// timeout if one is set.
+func (d *ixfd) cycleContext() (context.Context, context.CancelFunc) {
+	if timeout := time.Duration(*d.config.Flags.CycleTimeout); timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// jitteredInterval returns the interval with a random jitter of up to jitterPercent percent
+// of the interval added.
+func jitteredInterval(interval time.Duration, jitterPercent int) time.Duration {
+	maxJitter := interval * time.Duration(jitterPercent) / 100
+	if maxJitter <= 0 {
+		return interval
+	}
+	return interval + rand.N(maxJitter)
+}
+
 func removeOutputFile(path string) error {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	nfdfake "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/config"
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/label"
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/utils"
+)
+
+func ptr[T any](x T) *T {
+	return &x
+}
+
+// newTestConfig returns a config for running the labeling loop, with a sleep interval long
+// enough that only signals trigger further cycles.
+func newTestConfig(opts ...func(*config.Flags)) *config.Config {
+	flags := &config.Flags{
+		NoTimestamp:        ptr(true),
+		LabelPrefix:        ptr(label.DefaultLabelPrefix),
+		SleepInterval:      ptr(config.Duration(time.Hour)),
+		SleepJitterPercent: ptr(0),
+		CycleTimeout:       ptr(config.Duration(0)),
+		DryRun:             ptr(false),
+		OutputFile:         ptr(""),
+		WebhookURL:         ptr(""),
+		MetricsPort:        ptr(0),
+		KubeRetryAttempts:  ptr(1),
+		KubeRetryBaseDelay: ptr(config.Duration(0)),
+	}
+	for _, opt := range opts {
+		opt(flags)
+	}
+	return &config.Config{Flags: flags}
+}
+
+// recordingOutputer is an outputer that signals every output on a channel.
+type recordingOutputer struct {
+	outputs chan label.Labels
+}
+
+// Output sends the labels on the outputs channel
+func (o *recordingOutputer) Output(ctx context.Context, labels label.Labels) error {
+	o.outputs <- labels
+	return nil
+}
+
+// Cleanup does nothing
+func (o *recordingOutputer) Cleanup() error {
+	return nil
+}
+
+func TestJitteredInterval(t *testing.T) {
+	const interval = 60 * time.Second
+
+	testCases := []struct {
+		description   string
+		jitterPercent int
+		max           time.Duration
+	}{
+		{
+			description:   "default jitter",
+			jitterPercent: 10,
+			max:           66 * time.Second,
+		},
+		{
+			description:   "large jitter",
+			jitterPercent: 50,
+			max:           90 * time.Second,
+		},
+		{
+			description:   "no jitter",
+			jitterPercent: 0,
+			max:           interval,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := jitteredInterval(interval, tc.jitterPercent)
+				if d < interval || d > tc.max {
+					t.Fatalf("cycle %d: expected a sleep duration between %s and %s, got %s", i, interval, tc.max, d)
+				}
+			}
+		})
+	}
+}
+
+func TestRunRelabelsOnSIGUSR1(t *testing.T) {
+	sigs := utils.Signals(syscall.SIGUSR1)
+	defer signal.Stop(sigs)
+	outputer := &recordingOutputer{outputs: make(chan label.Labels, 1)}
+	d := &ixfd{
+		labeler:       label.Labels{label.DefaultLabelPrefix + "/gpu.present": "true"},
+		config:        newTestConfig(),
+		labelOutputer: outputer,
+	}
+
+	type result struct {
+		restart bool
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		restart, err := d.run(sigs)
+		done <- result{restart, err}
+	}()
+
+	waitForOutput := func(cycle string) {
+		t.Helper()
+		select {
+		case <-outputer.outputs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no labels output for the %s cycle", cycle)
+		}
+	}
+	waitForOutput("first")
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("failed to send SIGUSR1: %v", err)
+	}
+	waitForOutput("SIGUSR1")
+
+	sigs <- syscall.SIGTERM
+	select {
+	case r := <-done:
+		if r.restart || r.err != nil {
+			t.Errorf("expected a clean shutdown, got restart=%v err=%v", r.restart, r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after SIGTERM")
+	}
+}
+
+func TestRunCleansUpOnShutdown(t *testing.T) {
+	testCases := []struct {
+		description     string
+		signal          os.Signal
+		expectedRestart bool
+		expectedDeletes int
+	}{
+		{
+			description:     "SIGTERM deletes the NodeFeature object",
+			signal:          syscall.SIGTERM,
+			expectedDeletes: 1,
+		},
+		{
+			description:     "SIGHUP keeps the NodeFeature object",
+			signal:          syscall.SIGHUP,
+			expectedRestart: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			client := nfdfake.NewSimpleClientset()
+			created := make(chan struct{}, 1)
+			client.PrependReactor("create", "nodefeatures", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				created <- struct{}{}
+				return false, nil, nil
+			})
+			deletes := 0
+			client.PrependReactor("delete", "nodefeatures", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				deletes++
+				return false, nil, nil
+			})
+
+			conf := newTestConfig()
+			outputer, err := label.NewOutputer(conf, config.NodeConfig{Name: "node", Namespace: "ix-feature-discovery"}, config.ClientSets{NFD: client})
+			if err != nil {
+				t.Fatalf("failed to create outputer: %v", err)
+			}
+			d := &ixfd{
+				labeler:       label.Labels{label.DefaultLabelPrefix + "/gpu.present": "true"},
+				config:        conf,
+				labelOutputer: outputer,
+			}
+
+			sigs := make(chan os.Signal, 1)
+			type result struct {
+				restart bool
+				err     error
+			}
+			done := make(chan result, 1)
+			go func() {
+				restart, err := d.run(sigs)
+				done <- result{restart, err}
+			}()
+
+			select {
+			case <-created:
+			case <-time.After(5 * time.Second):
+				t.Fatal("NodeFeature object was not created")
+			}
+			sigs <- tc.signal
+
+			select {
+			case r := <-done:
+				if r.err != nil {
+					t.Fatalf("unexpected error: %v", r.err)
+				}
+				if r.restart != tc.expectedRestart {
+					t.Errorf("expected restart %v, got %v", tc.expectedRestart, r.restart)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("run did not return after %v", tc.signal)
+			}
+			if deletes != tc.expectedDeletes {
+				t.Errorf("expected %d delete calls, got %d", tc.expectedDeletes, deletes)
+			}
+		})
+	}
+}
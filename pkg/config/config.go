@@ -17,31 +17,156 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
 )
 
 type Config struct {
-	Flags *Flags `json:"flags,omitempty"     static:"flags,omitempty"`
+	Flags      *Flags           `json:"flags,omitempty"      static:"flags,omitempty"`
+	Transforms []LabelTransform `json:"transforms,omitempty" static:"transforms,omitempty"`
 }
 
+// LabelTransform describes a substitution applied to the values of the labels whose keys
+// match the Key regular expression. Every match of the Value regular expression in the
+// label value is replaced by Replacement, which can refer to submatches as in
+// regexp.Regexp.ReplaceAllString. Transforms can only be set in the config file.
+type LabelTransform struct {
+	Key         string `json:"key"         static:"key"`
+	Value       string `json:"value"       static:"value"`
+	Replacement string `json:"replacement" static:"replacement"`
+}
+
+// NewConfig builds the config from the config file, if any, and the CLI flags. Flags set on
+// the command line or through environment variables override values from the config file.
 func NewConfig(c *cli.Context, flags []cli.Flag) (*Config, error) {
-	config := &Config{}
+	config, err := parseConfig(c.String("config"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config file: %v", err)
+	}
 
 	if config.Flags == nil {
 		config.Flags = &Flags{}
 	}
 	config.Flags.UpdateFromCLIFlags(c, flags)
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
+// validate checks that the flags and the label transforms hold valid values.
+func (c *Config) validate() error {
+	if err := c.Flags.validate(); err != nil {
+		return err
+	}
+	for i, t := range c.Transforms {
+		if t.Key == "" || t.Value == "" {
+			return fmt.Errorf("invalid label transform %d: key and value must be set", i)
+		}
+		if _, err := regexp.Compile(t.Key); err != nil {
+			return fmt.Errorf("invalid key pattern %q of label transform %d: %v", t.Key, i, err)
+		}
+		if _, err := regexp.Compile(t.Value); err != nil {
+			return fmt.Errorf("invalid value pattern %q of label transform %d: %v", t.Value, i, err)
+		}
+	}
+	return nil
+}
+
+// parseConfig parses a config file in YAML or JSON format. The file has the same structure
+// as the JSON encoding of Config. An empty config is returned if no file is specified or the
+// file does not exist.
+func parseConfig(configFile string) (*Config, error) {
+	var config Config
+
+	if configFile == "" {
+		return &config, nil
+	}
+
+	data, err := os.ReadFile(configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		klog.Infof("Config file %s does not exist, using flags only", configFile)
+		return &config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read file: %v", err)
+	}
+
+	if filepath.Ext(configFile) != ".json" {
+		// Convert YAML to JSON so that the JSON field names and unmarshalers of Config apply.
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("unmarshal YAML: %v", err)
+		}
+		if v == nil {
+			return &config, nil
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("convert YAML to JSON: %v", err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("unmarshal JSON: %v", err)
+	}
+
+	return &config, nil
+}
+
 // Flags holds the full list of flags used to configure the ix-feature-discovery.
 type Flags struct {
-	NoTimestamp     *bool     `json:"noTimestamp"     static:"noTimestamp"`
-	SleepInterval   *Duration `json:"sleepInterval"   static:"sleepInterval"`
-	OutputFile      *string   `json:"outputFile"      static:"outputFile"`
-	MachineTypeFile *string   `json:"machineTypeFile" static:"machineTypeFile"`
+	NoTimestamp             *bool     `json:"noTimestamp"             static:"noTimestamp"`
+	SleepInterval           *Duration `json:"sleepInterval"           static:"sleepInterval"`
+	SleepJitterPercent      *int      `json:"sleepJitterPercent"      static:"sleepJitterPercent"`
+	CycleTimeout            *Duration `json:"cycleTimeout"            static:"cycleTimeout"`
+	MaxLabelerConcurrency   *int      `json:"maxLabelerConcurrency"   static:"maxLabelerConcurrency"`
+	IXMLTimeout             *Duration `json:"ixmlTimeout"             static:"ixmlTimeout"`
+	CircuitBreakerThreshold *int      `json:"circuitBreakerThreshold" static:"circuitBreakerThreshold"`
+	CircuitBreakerCooldown  *Duration `json:"circuitBreakerCooldown"  static:"circuitBreakerCooldown"`
+	HardwareLabelsTTL       *Duration `json:"hardwareLabelsTTL"       static:"hardwareLabelsTTL"`
+	LabelerRetryAttempts    *int      `json:"labelerRetryAttempts"    static:"labelerRetryAttempts"`
+	LabelerRetryDelay       *Duration `json:"labelerRetryDelay"       static:"labelerRetryDelay"`
+	OutputFile              *string   `json:"outputFile"              static:"outputFile"`
+	MachineTypeFile         *string   `json:"machineTypeFile"         static:"machineTypeFile"`
+	DevicePluginConfigFile  *string   `json:"devicePluginConfigFile"  static:"devicePluginConfigFile"`
+	VideoCapabilitiesFile   *string   `json:"videoCapabilitiesFile"   static:"videoCapabilitiesFile"`
+	KernelModuleVersionFile *string   `json:"kernelModuleVersionFile" static:"kernelModuleVersionFile"`
+	StaticLabelsFile        *string   `json:"staticLabelsFile"        static:"staticLabelsFile"`
+	ExternalLabelerCommand  *string   `json:"externalLabelerCommand"  static:"externalLabelerCommand"`
+	ExternalLabelerArgs     *[]string `json:"externalLabelerArgs"     static:"externalLabelerArgs"`
+	ExternalLabelerTimeout  *Duration `json:"externalLabelerTimeout"  static:"externalLabelerTimeout"`
+	NoECCErrorLabels        *bool     `json:"noECCErrorLabels"        static:"noECCErrorLabels"`
+	DryRun                  *bool     `json:"dryRun"                  static:"dryRun"`
+	BestEffort              *bool     `json:"bestEffort"              static:"bestEffort"`
+	LeaderElect             *bool     `json:"leaderElect"             static:"leaderElect"`
+	WebhookURL              *string   `json:"webhookURL"              static:"webhookURL"`
+	WebhookTimeout          *Duration `json:"webhookTimeout"          static:"webhookTimeout"`
+	MetricsPort             *int      `json:"metricsPort"             static:"metricsPort"`
+	NoPartitioningLabels    *bool     `json:"noPartitioningLabels"    static:"noPartitioningLabels"`
+	LabelPrefix             *string   `json:"labelPrefix"             static:"labelPrefix"`
+	LabelInclude            *[]string `json:"labelInclude"            static:"labelInclude"`
+	LabelExclude            *[]string `json:"labelExclude"            static:"labelExclude"`
+	NoDriverLabels          *bool     `json:"noDriverLabels"          static:"noDriverLabels"`
+	NoCudaLabels            *bool     `json:"noCudaLabels"            static:"noCudaLabels"`
+	NoGPULabels             *bool     `json:"noGPULabels"             static:"noGPULabels"`
+	NoMachineLabels         *bool     `json:"noMachineLabels"         static:"noMachineLabels"`
+	KubeRetryAttempts       *int      `json:"kubeRetryAttempts"       static:"kubeRetryAttempts"`
+	KubeRetryBaseDelay      *Duration `json:"kubeRetryBaseDelay"      static:"kubeRetryBaseDelay"`
+	UseSysfs                *bool     `json:"useSysfs"                static:"useSysfs"`
+	PerDeviceLabels         *bool     `json:"perDeviceLabels"         static:"perDeviceLabels"`
 }
 
 // UpdateFromCLIFlags updates Flags from settings in the cli Flags if they are set.
@@ -53,13 +178,115 @@ func (f *Flags) UpdateFromCLIFlags(c *cli.Context, flags []cli.Flag) {
 				updateFromCLIFlag(&f.OutputFile, c, n)
 			case "sleep-interval":
 				updateFromCLIFlag(&f.SleepInterval, c, n)
+			case "sleep-jitter-percent":
+				updateFromCLIFlag(&f.SleepJitterPercent, c, n)
+			case "cycle-timeout":
+				updateFromCLIFlag(&f.CycleTimeout, c, n)
+			case "max-labeler-concurrency":
+				updateFromCLIFlag(&f.MaxLabelerConcurrency, c, n)
+			case "ixml-timeout":
+				updateFromCLIFlag(&f.IXMLTimeout, c, n)
+			case "circuit-breaker-threshold":
+				updateFromCLIFlag(&f.CircuitBreakerThreshold, c, n)
+			case "circuit-breaker-cooldown":
+				updateFromCLIFlag(&f.CircuitBreakerCooldown, c, n)
+			case "hardware-labels-ttl":
+				updateFromCLIFlag(&f.HardwareLabelsTTL, c, n)
+			case "labeler-retry-attempts":
+				updateFromCLIFlag(&f.LabelerRetryAttempts, c, n)
+			case "labeler-retry-delay":
+				updateFromCLIFlag(&f.LabelerRetryDelay, c, n)
 			case "no-timestamp":
 				updateFromCLIFlag(&f.NoTimestamp, c, n)
 			case "machine-type-file":
 				updateFromCLIFlag(&f.MachineTypeFile, c, n)
+			case "device-plugin-config-file":
+				updateFromCLIFlag(&f.DevicePluginConfigFile, c, n)
+			case "video-capabilities-file":
+				updateFromCLIFlag(&f.VideoCapabilitiesFile, c, n)
+			case "kernel-module-version-file":
+				updateFromCLIFlag(&f.KernelModuleVersionFile, c, n)
+			case "static-labels-file":
+				updateFromCLIFlag(&f.StaticLabelsFile, c, n)
+			case "external-labeler-command":
+				updateFromCLIFlag(&f.ExternalLabelerCommand, c, n)
+			case "external-labeler-args":
+				updateFromCLIFlag(&f.ExternalLabelerArgs, c, n)
+			case "external-labeler-timeout":
+				updateFromCLIFlag(&f.ExternalLabelerTimeout, c, n)
+			case "no-ecc-error-labels":
+				updateFromCLIFlag(&f.NoECCErrorLabels, c, n)
+			case "dry-run":
+				updateFromCLIFlag(&f.DryRun, c, n)
+			case "best-effort":
+				updateFromCLIFlag(&f.BestEffort, c, n)
+			case "leader-elect":
+				updateFromCLIFlag(&f.LeaderElect, c, n)
+			case "webhook-url":
+				updateFromCLIFlag(&f.WebhookURL, c, n)
+			case "webhook-timeout":
+				updateFromCLIFlag(&f.WebhookTimeout, c, n)
+			case "metrics-port":
+				updateFromCLIFlag(&f.MetricsPort, c, n)
+			case "no-partitioning-labels":
+				updateFromCLIFlag(&f.NoPartitioningLabels, c, n)
+			case "label-prefix":
+				updateFromCLIFlag(&f.LabelPrefix, c, n)
+			case "label-include":
+				updateFromCLIFlag(&f.LabelInclude, c, n)
+			case "label-exclude":
+				updateFromCLIFlag(&f.LabelExclude, c, n)
+			case "no-driver-labels":
+				updateFromCLIFlag(&f.NoDriverLabels, c, n)
+			case "no-cuda-labels":
+				updateFromCLIFlag(&f.NoCudaLabels, c, n)
+			case "no-gpu-labels":
+				updateFromCLIFlag(&f.NoGPULabels, c, n)
+			case "no-machine-labels":
+				updateFromCLIFlag(&f.NoMachineLabels, c, n)
+			case "kube-retry-attempts":
+				updateFromCLIFlag(&f.KubeRetryAttempts, c, n)
+			case "kube-retry-base-delay":
+				updateFromCLIFlag(&f.KubeRetryBaseDelay, c, n)
+			case "use-sysfs":
+				updateFromCLIFlag(&f.UseSysfs, c, n)
+			case "per-device-labels":
+				updateFromCLIFlag(&f.PerDeviceLabels, c, n)
+			}
+		}
+	}
+}
+
+// validate checks that the flags hold valid values.
+func (f *Flags) validate() error {
+	if f.KubeRetryAttempts != nil && *f.KubeRetryAttempts < 1 {
+		return fmt.Errorf("invalid kube retry attempts %d: must be at least 1", *f.KubeRetryAttempts)
+	}
+	if f.LabelerRetryAttempts != nil && *f.LabelerRetryAttempts < 1 {
+		return fmt.Errorf("invalid labeler retry attempts %d: must be at least 1", *f.LabelerRetryAttempts)
+	}
+	if f.SleepJitterPercent != nil && *f.SleepJitterPercent < 0 {
+		return fmt.Errorf("invalid sleep jitter percent %d: must not be negative", *f.SleepJitterPercent)
+	}
+	if f.MaxLabelerConcurrency != nil && *f.MaxLabelerConcurrency < 1 {
+		return fmt.Errorf("invalid max labeler concurrency %d: must be at least 1", *f.MaxLabelerConcurrency)
+	}
+	if f.LabelPrefix != nil {
+		if errs := validation.IsDNS1123Subdomain(*f.LabelPrefix); len(errs) != 0 {
+			return fmt.Errorf("invalid label prefix %q: %s", *f.LabelPrefix, strings.Join(errs, "; "))
+		}
+	}
+	for _, patterns := range []*[]string{f.LabelInclude, f.LabelExclude} {
+		if patterns == nil {
+			continue
+		}
+		for _, p := range *patterns {
+			if _, err := regexp.Compile(p); err != nil {
+				return fmt.Errorf("invalid label filter pattern %q: %v", p, err)
 			}
 		}
 	}
+	return nil
 }
 
 // prt returns a reference to whatever type is passed into it
@@ -77,6 +304,8 @@ func updateFromCLIFlag[T any](pflag **T, c *cli.Context, flagName string) {
 			*flag = ptr(c.StringSlice(flagName))
 		case **bool:
 			*flag = ptr(c.Bool(flagName))
+		case **int:
+			*flag = ptr(c.Int(flagName))
 		case **Duration:
 			*flag = ptr(Duration(c.Duration(flagName)))
 		default:
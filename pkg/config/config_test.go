@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// newTestConfig parses the args with a subset of the ix-feature-discovery flags and returns
+// the resulting config.
+func newTestConfig(t *testing.T, args ...string) *Config {
+	t.Helper()
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			EnvVars: []string{"CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "output-file",
+			Value:   "",
+			EnvVars: []string{"OUTPUT_FILE"},
+		},
+		&cli.DurationFlag{
+			Name:    "sleep-interval",
+			Value:   60 * time.Second,
+			EnvVars: []string{"SLEEP_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:    "label-prefix",
+			Value:   "iluvatar.ai",
+			EnvVars: []string{"LABEL_PREFIX"},
+		},
+	}
+
+	var config *Config
+	app := &cli.App{
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			var err error
+			config, err = NewConfig(c, flags)
+			return err
+		},
+	}
+	if err := app.Run(append([]string{"ix-feature-discovery"}, args...)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return config
+}
+
+// writeConfigFile writes a config file with the name and content to a temporary directory
+// and returns its path.
+func writeConfigFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestNewConfigPrecedence(t *testing.T) {
+	yamlFile := writeConfigFile(t, "config.yaml", "flags:\n  sleepInterval: 30s\n  labelPrefix: example.com\n")
+	jsonFile := writeConfigFile(t, "config.json", `{"flags": {"sleepInterval": "30s", "labelPrefix": "example.com"}}`)
+
+	testCases := []struct {
+		description           string
+		args                  []string
+		env                   map[string]string
+		expectedSleepInterval time.Duration
+		expectedLabelPrefix   string
+	}{
+		{
+			description:           "defaults without a config file",
+			expectedSleepInterval: 60 * time.Second,
+			expectedLabelPrefix:   "iluvatar.ai",
+		},
+		{
+			description:           "missing config file is ignored",
+			args:                  []string{"--config", filepath.Join(t.TempDir(), "missing.yaml")},
+			expectedSleepInterval: 60 * time.Second,
+			expectedLabelPrefix:   "iluvatar.ai",
+		},
+		{
+			description:           "YAML file overrides defaults",
+			args:                  []string{"--config", yamlFile},
+			expectedSleepInterval: 30 * time.Second,
+			expectedLabelPrefix:   "example.com",
+		},
+		{
+			description:           "JSON file overrides defaults",
+			args:                  []string{"--config", jsonFile},
+			expectedSleepInterval: 30 * time.Second,
+			expectedLabelPrefix:   "example.com",
+		},
+		{
+			description:           "environment overrides file",
+			args:                  []string{"--config", yamlFile},
+			env:                   map[string]string{"SLEEP_INTERVAL": "45s"},
+			expectedSleepInterval: 45 * time.Second,
+			expectedLabelPrefix:   "example.com",
+		},
+		{
+			description:           "flag overrides environment and file",
+			args:                  []string{"--config", yamlFile, "--sleep-interval", "15s"},
+			env:                   map[string]string{"SLEEP_INTERVAL": "45s"},
+			expectedSleepInterval: 15 * time.Second,
+			expectedLabelPrefix:   "example.com",
+		},
+		{
+			description:           "config file from the environment",
+			env:                   map[string]string{"CONFIG_FILE": yamlFile, "LABEL_PREFIX": "example.org"},
+			expectedSleepInterval: 30 * time.Second,
+			expectedLabelPrefix:   "example.org",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			config := newTestConfig(t, tc.args...)
+			if sleepInterval := time.Duration(*config.Flags.SleepInterval); sleepInterval != tc.expectedSleepInterval {
+				t.Errorf("expected sleep interval %v, got %v", tc.expectedSleepInterval, sleepInterval)
+			}
+			if *config.Flags.LabelPrefix != tc.expectedLabelPrefix {
+				t.Errorf("expected label prefix %q, got %q", tc.expectedLabelPrefix, *config.Flags.LabelPrefix)
+			}
+		})
+	}
+}
+
+func TestParseConfigRejectsUnknownFields(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "flags:\n  sleepIntervall: 30s\n")
+	if _, err := parseConfig(path); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
@@ -18,13 +18,18 @@ package config
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/urfave/cli/v2"
 
+	corev1 "k8s.io/api/core/v1"
 	coreclientset "k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
+	nfdscheme "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/scheme"
 )
 
 type KubeClientConfig struct {
@@ -36,6 +41,17 @@ type KubeClientConfig struct {
 type ClientSets struct {
 	Core coreclientset.Interface
 	NFD  nfdclientset.Interface
+	// EventRecorder records events about the NodeFeature objects.
+	EventRecorder record.EventRecorder
+
+	eventBroadcaster record.EventBroadcaster
+}
+
+// Close stops recording events.
+func (c ClientSets) Close() {
+	if c.eventBroadcaster != nil {
+		c.eventBroadcaster.Shutdown()
+	}
 }
 
 func (k *KubeClientConfig) Flags() []cli.Flag {
@@ -68,6 +84,12 @@ func (k *KubeClientConfig) Flags() []cli.Flag {
 	return flags
 }
 
+// Configured reports whether a Kubernetes API server is configured, either by a kubeconfig
+// or by running in a cluster.
+func (k *KubeClientConfig) Configured() bool {
+	return k.KubeConfig != "" || os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
 func (k *KubeClientConfig) NewClientSetConfig() (*rest.Config, error) {
 	var csconfig *rest.Config
 
@@ -106,8 +128,14 @@ func (k *KubeClientConfig) NewClientSets() (ClientSets, error) {
 		return ClientSets{}, fmt.Errorf("create nfd client: %w", err)
 	}
 
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: coreclient.CoreV1().Events("")})
+	eventRecorder := eventBroadcaster.NewRecorder(nfdscheme.Scheme, corev1.EventSource{Component: "ix-feature-discovery"})
+
 	return ClientSets{
-		Core: coreclient,
-		NFD:  nfdclient,
+		Core:             coreclient,
+		NFD:              nfdclient,
+		EventRecorder:    eventRecorder,
+		eventBroadcaster: eventBroadcaster,
 	}, nil
 }
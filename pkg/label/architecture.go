@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import "strings"
+
+// architectures maps GPU product names, as reported by resource.Device.GetName, to
+// their architecture family.
+var architectures = map[string]string{
+	"BI-V100":  "ivcore10",
+	"BI-V150":  "ivcore11",
+	"BI-V150S": "ivcore11",
+	"MR-V100":  "ivcore11",
+}
+
+// getArchitecture returns the architecture family of the specified product, or
+// unknown if the product is not known.
+func getArchitecture(product string) string {
+	if arch, ok := architectures[strings.ToUpper(product)]; ok {
+		return arch
+	}
+	return labelValueUnknown
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import "testing"
+
+func TestGetArchitecture(t *testing.T) {
+	testCases := []struct {
+		product  string
+		expected string
+	}{
+		{"BI-V100", "ivcore10"},
+		{"BI-V150", "ivcore11"},
+		{"BI-V150S", "ivcore11"},
+		{"MR-V100", "ivcore11"},
+		{"bi-v150", "ivcore11"},
+		{"BI-V200", labelValueUnknown},
+		{"", labelValueUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.product, func(t *testing.T) {
+			if arch := getArchitecture(tc.product); arch != tc.expected {
+				t.Errorf("expected architecture %q, got %q", tc.expected, arch)
+			}
+		})
+	}
+}
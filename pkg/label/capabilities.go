@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// driverCapabilityProbes holds a probe for each driver capability, which returns an error
+// if the corresponding IXML subsystem does not respond for a device.
+var driverCapabilityProbes = map[string]func(resource.Device) error{
+	"compute":  probeComputeCapability,
+	"video":    probeVideoCapability,
+	"graphics": probeGraphicsCapability,
+}
+
+// probeComputeCapability probes the compute queries of a device.
+func probeComputeCapability(dev resource.Device) error {
+	_, _, err := dev.GetCudaComputeCapability()
+	return err
+}
+
+// probeVideoCapability probes the video encoder and decoder queries of a device. The
+// capability is present if either responds.
+func probeVideoCapability(dev resource.Device) error {
+	if _, err := dev.GetVideoEncoderCount(); err == nil {
+		return nil
+	}
+	_, err := dev.GetVideoDecoderCount()
+	return err
+}
+
+// probeGraphicsCapability probes the display queries of a device.
+func probeGraphicsCapability(dev resource.Device) error {
+	_, err := dev.GetDisplayActive()
+	return err
+}
+
+// newDriverCapabilitiesLabeler creates a labeler for the capabilities of the driver, which
+// container runtimes use to decide what to mount. A capability is reported if its probe
+// succeeds for any device. The label is omitted if no probe succeeds.
+func newDriverCapabilitiesLabeler(prefix string, devices []resource.Device) Labeler {
+	var capabilities []string
+	for _, name := range slices.Sorted(maps.Keys(driverCapabilityProbes)) {
+		for i, dev := range devices {
+			err := runCapabilityProbe(driverCapabilityProbes[name], dev)
+			if err == nil {
+				capabilities = append(capabilities, name)
+				break
+			}
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to probe %s capability for device %d: %v", name, i, err)
+			}
+		}
+	}
+
+	if len(capabilities) == 0 {
+		return empty{}
+	}
+
+	joined, err := joinLabelValues(capabilities)
+	if err != nil {
+		klog.Warningf("Skipping label %s: %v", "ix.driver.capabilities", err)
+		return empty{}
+	}
+	return Labels{
+		prefix + "/ix.driver.capabilities": joined,
+	}
+}
+
+// runCapabilityProbe runs a probe for a device, turning a panic into an error so that a
+// misbehaving subsystem does not abort labeling.
+func runCapabilityProbe(probe func(resource.Device) error, dev resource.Device) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("probe panicked: %v", r)
+		}
+	}()
+	return probe(dev)
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"errors"
+	"testing"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// panickingDevice is a mock device whose display queries panic.
+type panickingDevice struct {
+	*resource.MockDevice
+}
+
+// GetDisplayActive panics
+func (d panickingDevice) GetDisplayActive() (bool, error) {
+	panic("display query crashed")
+}
+
+func TestDriverCapabilityProbes(t *testing.T) {
+	notSupported := func(methods ...string) map[string]error {
+		errs := make(map[string]error)
+		for _, m := range methods {
+			errs[m] = resource.ErrNotSupported
+		}
+		return errs
+	}
+
+	testCases := []struct {
+		description string
+		probe       string
+		device      *resource.MockDevice
+		expectError bool
+	}{
+		{
+			description: "compute responds",
+			probe:       "compute",
+			device:      &resource.MockDevice{},
+		},
+		{
+			description: "compute does not respond",
+			probe:       "compute",
+			device:      &resource.MockDevice{Errors: notSupported("GetCudaComputeCapability")},
+			expectError: true,
+		},
+		{
+			description: "video encoder responds",
+			probe:       "video",
+			device:      &resource.MockDevice{Errors: notSupported("GetVideoDecoderCount")},
+		},
+		{
+			description: "video decoder responds",
+			probe:       "video",
+			device:      &resource.MockDevice{Errors: notSupported("GetVideoEncoderCount")},
+		},
+		{
+			description: "video does not respond",
+			probe:       "video",
+			device:      &resource.MockDevice{Errors: notSupported("GetVideoEncoderCount", "GetVideoDecoderCount")},
+			expectError: true,
+		},
+		{
+			description: "graphics responds",
+			probe:       "graphics",
+			device:      &resource.MockDevice{},
+		},
+		{
+			description: "graphics does not respond",
+			probe:       "graphics",
+			device:      &resource.MockDevice{Errors: notSupported("GetDisplayActive")},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := driverCapabilityProbes[tc.probe](tc.device)
+			if tc.expectError && !errors.Is(err, resource.ErrNotSupported) {
+				t.Errorf("expected ErrNotSupported, got %v", err)
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunCapabilityProbe(t *testing.T) {
+	err := runCapabilityProbe(probeGraphicsCapability, panickingDevice{&resource.MockDevice{}})
+	if err == nil {
+		t.Error("expected an error from a panicking probe")
+	}
+}
+
+func TestDriverCapabilitiesLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []resource.Device
+		expected    string
+	}{
+		{
+			description: "all subsystems respond",
+			devices:     asDevices(&resource.MockDevice{}),
+			expected:    "compute_graphics_video",
+		},
+		{
+			description: "capability of any device is reported",
+			devices: asDevices(
+				&resource.MockDevice{Errors: map[string]error{"GetDisplayActive": resource.ErrNotSupported}},
+				&resource.MockDevice{Errors: map[string]error{"GetCudaComputeCapability": errTest}},
+			),
+			expected: "compute_graphics_video",
+		},
+		{
+			description: "compute only",
+			devices: asDevices(&resource.MockDevice{Errors: map[string]error{
+				"GetVideoEncoderCount": resource.ErrNotSupported,
+				"GetVideoDecoderCount": resource.ErrNotSupported,
+				"GetDisplayActive":     resource.ErrNotSupported,
+			}}),
+			expected: "compute",
+		},
+		{
+			description: "panicking probe does not abort labeling",
+			devices:     []resource.Device{panickingDevice{&resource.MockDevice{}}},
+			expected:    "compute_video",
+		},
+		{
+			description: "no subsystem responds",
+			devices:     asDevices(&resource.MockDevice{Error: errTest}),
+			expected:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newDriverCapabilitiesLabeler(DefaultLabelPrefix, tc.devices))
+			checkLabel(t, labels, "ix.driver.capabilities", tc.expected)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// newClockLabeler creates a labeler for the current SM and memory clocks. On a node
+// with a single GPU model the clocks of device 0 are reported, and the labels are omitted
+// if they cannot be retrieved. On a node with multiple GPU models the lowest clocks across
+// all devices are reported, skipping the devices whose clocks cannot be retrieved. A node
+// where the name of any device cannot be retrieved counts as having multiple GPU models.
+func newClockLabeler(prefix string, devices []resource.Device) Labeler {
+	smClocks := make(map[int]uint)
+	memClocks := make(map[int]uint)
+	homogeneous := true
+	var first string
+	for i, dev := range devices {
+		name, err := dev.GetName()
+		if err != nil {
+			klog.Warningf("Failed to retrieve name for device %d: %v", i, err)
+			homogeneous = false
+		} else if i == 0 {
+			first = name
+		} else if name != first {
+			homogeneous = false
+		}
+
+		sm, mem, err := dev.GetClocksMHz()
+		if err != nil {
+			klog.Warningf("Failed to retrieve clocks for device %d: %v", i, err)
+			continue
+		}
+		smClocks[i] = sm
+		memClocks[i] = mem
+	}
+
+	if len(smClocks) == 0 {
+		return empty{}
+	}
+
+	var sm, mem uint
+	if homogeneous {
+		var ok bool
+		if sm, ok = smClocks[0]; !ok {
+			klog.Warning("Clocks of device 0 not available, skipping clock labels")
+			return empty{}
+		}
+		mem = memClocks[0]
+	} else {
+		sm = slices.Min(slices.Collect(maps.Values(smClocks)))
+		mem = slices.Min(slices.Collect(maps.Values(memClocks)))
+	}
+
+	return Labels{
+		prefix + "/gpu.clock-sm-mhz":  strconv.FormatUint(uint64(sm), 10),
+		prefix + "/gpu.clock-mem-mhz": strconv.FormatUint(uint64(mem), 10),
+	}
+}
+
+// newMaxSMClockLabeler creates a labeler for the maximum SM clock. The lowest maximum
+// across all devices is reported. Devices that do not support the query are skipped,
+// and the label is omitted if no device reports a maximum.
+func newMaxSMClockLabeler(prefix string, devices []resource.Device) Labeler {
+	clock, ok := minMaxClock(devices, resource.ClockSM)
+	if !ok {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.clock.sm.max": strconv.FormatUint(uint64(clock), 10),
+	}
+}
+
+// newMaxMemoryClockLabeler creates a labeler for the maximum memory clock. If the devices
+// report different maximums, the lowest one is reported, as for the maximum SM clock, so
+// the label holds for every device. Devices that do not support the query are skipped,
+// and the label is omitted if no device reports a maximum.
+func newMaxMemoryClockLabeler(prefix string, devices []resource.Device) Labeler {
+	clock, ok := minMaxClock(devices, resource.ClockMemory)
+	if !ok {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.clock.memory.max": strconv.FormatUint(uint64(clock), 10),
+	}
+}
+
+// newMemoryBandwidthLabeler creates a labeler for the peak memory bandwidth in GB/s. IXML
+// does not report the bandwidth, so it is derived from the maximum memory clock and the
+// memory bus width. The lowest bandwidth across all devices is reported. The label is
+// omitted if any device fails or does not support either query.
+func newMemoryBandwidthLabeler(prefix string, devices []resource.Device) Labeler {
+	var bandwidths []uint
+	for i, dev := range devices {
+		clock, err := dev.GetMaxClock(resource.ClockMemory)
+		if err != nil {
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to retrieve max memory clock for device %d: %v", i, err)
+			}
+			return empty{}
+		}
+		width, err := dev.GetMemoryBusWidth()
+		if err != nil {
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to retrieve memory bus width for device %d: %v", i, err)
+			}
+			return empty{}
+		}
+		bandwidth := memoryBandwidthGBps(clock, width)
+		klog.V(2).Infof("Computed memory bandwidth of device %d: %d GB/s (%d MHz, %d bits)", i, bandwidth, clock, width)
+		bandwidths = append(bandwidths, bandwidth)
+	}
+
+	if len(bandwidths) == 0 {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.memory.bandwidth": strconv.FormatUint(uint64(slices.Min(bandwidths)), 10),
+	}
+}
+
+// memoryBandwidthGBps returns the peak memory bandwidth in GB/s for a memory clock in MHz
+// and a bus width in bits, assuming two transfers per clock cycle.
+func memoryBandwidthGBps(clockMHz, busWidthBits uint) uint {
+	return uint(uint64(clockMHz) * uint64(busWidthBits) * 2 / 8 / 1000)
+}
+
+// minMaxClock returns the lowest maximum clock of the specified clock domain across all
+// devices, and whether any device reported it.
+func minMaxClock(devices []resource.Device, clockType resource.ClockType) (uint, bool) {
+	var clocks []uint
+	for i, dev := range devices {
+		clock, err := dev.GetMaxClock(clockType)
+		if err != nil {
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to retrieve max clock for device %d: %v", i, err)
+			}
+			continue
+		}
+		clocks = append(clocks, clock)
+	}
+
+	if len(clocks) == 0 {
+		return 0, false
+	}
+	return slices.Min(clocks), true
+}
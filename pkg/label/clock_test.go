@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"testing"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+func TestClockLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expectedSM  string
+		expectedMem string
+	}{
+		{
+			description: "identical models report device 0",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", SMClockMHz: 1500, MemoryClockMHz: 1200},
+				{Name: "MR-V100", SMClockMHz: 1000, MemoryClockMHz: 800},
+			},
+			expectedSM:  "1500",
+			expectedMem: "1200",
+		},
+		{
+			description: "different models report the minimum",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", SMClockMHz: 1500, MemoryClockMHz: 800},
+				{Name: "BI-V150", SMClockMHz: 1000, MemoryClockMHz: 1200},
+			},
+			expectedSM:  "1000",
+			expectedMem: "800",
+		},
+		{
+			description: "device without a name counts as a different model",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", SMClockMHz: 1500, MemoryClockMHz: 1200},
+				{SMClockMHz: 1000, MemoryClockMHz: 800, Errors: map[string]error{"GetName": errTest}},
+			},
+			expectedSM:  "1000",
+			expectedMem: "800",
+		},
+		{
+			description: "labels are absent if device 0 fails on identical models",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", Errors: map[string]error{"GetClocksMHz": errTest}},
+				{Name: "MR-V100", SMClockMHz: 1000, MemoryClockMHz: 800},
+			},
+		},
+		{
+			description: "labels are absent if IXML returns an error",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", Errors: map[string]error{"GetClocksMHz": errTest}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newClockLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.clock-sm-mhz", tc.expectedSM)
+			checkLabel(t, labels, "gpu.clock-mem-mhz", tc.expectedMem)
+		})
+	}
+}
+
+func TestClockErrorDoesNotBlockOtherLabels(t *testing.T) {
+	device := &resource.MockDevice{
+		Name:          "MR-V100",
+		TotalMemoryMB: 32768,
+		Errors:        map[string]error{"GetClocksMHz": errTest},
+	}
+	l := NewIXDeviceLabeler(resource.NewMockManager(resource.WithDevices(device)), newTestConfig())
+
+	labels := generateLabels(t, l)
+	checkLabel(t, labels, "gpu.clock-sm-mhz", "")
+	checkLabel(t, labels, "gpu.clock-mem-mhz", "")
+	checkLabel(t, labels, "gpu.product", "MR-V100")
+	checkLabel(t, labels, "gpu.memory", "32768")
+}
+
+func TestMaxClockLabelers(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expectedSM  string
+		expectedMem string
+	}{
+		{
+			description: "single device",
+			devices: []*resource.MockDevice{
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockSM: 1600, resource.ClockMemory: 1200}},
+			},
+			expectedSM:  "1600",
+			expectedMem: "1200",
+		},
+		{
+			// The lowest maximum wins, so the labels hold for every device of the node.
+			description: "slightly different clocks report the lowest",
+			devices: []*resource.MockDevice{
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockSM: 1600, resource.ClockMemory: 1215}},
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockSM: 1590, resource.ClockMemory: 1200}},
+			},
+			expectedSM:  "1590",
+			expectedMem: "1200",
+		},
+		{
+			description: "memory clock only",
+			devices: []*resource.MockDevice{
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockMemory: 1200}},
+			},
+			expectedMem: "1200",
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetMaxClock": errTest}},
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockSM: 1600, resource.ClockMemory: 1200}},
+			},
+			expectedSM:  "1600",
+			expectedMem: "1200",
+		},
+		{
+			description: "labels are absent if not supported",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetMaxClock": resource.ErrNotSupported}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			devices := asDevices(tc.devices...)
+			checkLabel(t, generateLabels(t, newMaxSMClockLabeler(DefaultLabelPrefix, devices)), "gpu.clock.sm.max", tc.expectedSM)
+			checkLabel(t, generateLabels(t, newMaxMemoryClockLabeler(DefaultLabelPrefix, devices)), "gpu.clock.memory.max", tc.expectedMem)
+		})
+	}
+}
+
+func TestMemoryBandwidthGBps(t *testing.T) {
+	testCases := []struct {
+		clockMHz     uint
+		busWidthBits uint
+		expected     uint
+	}{
+		{clockMHz: 1200, busWidthBits: 4096, expected: 1228},
+		{clockMHz: 1600, busWidthBits: 4096, expected: 1638},
+		{clockMHz: 7000, busWidthBits: 256, expected: 448},
+		{clockMHz: 0, busWidthBits: 4096, expected: 0},
+		{clockMHz: 1200, busWidthBits: 0, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		if bandwidth := memoryBandwidthGBps(tc.clockMHz, tc.busWidthBits); bandwidth != tc.expected {
+			t.Errorf("memoryBandwidthGBps(%d, %d): expected %d, got %d", tc.clockMHz, tc.busWidthBits, tc.expected, bandwidth)
+		}
+	}
+}
+
+func TestMemoryBandwidthLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "single device",
+			devices: []*resource.MockDevice{
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockMemory: 1200}, MemoryBusWidth: 4096},
+			},
+			expected: "1228",
+		},
+		{
+			description: "lowest bandwidth across devices",
+			devices: []*resource.MockDevice{
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockMemory: 1600}, MemoryBusWidth: 4096},
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockMemory: 1200}, MemoryBusWidth: 4096},
+			},
+			expected: "1228",
+		},
+		{
+			description: "label is absent if the memory clock is unsupported",
+			devices: []*resource.MockDevice{
+				{MemoryBusWidth: 4096},
+			},
+		},
+		{
+			description: "label is absent if the bus width is unsupported on any device",
+			devices: []*resource.MockDevice{
+				{MaxClocksMHz: map[resource.ClockType]uint{resource.ClockMemory: 1200}, MemoryBusWidth: 4096},
+				{
+					MaxClocksMHz: map[resource.ClockType]uint{resource.ClockMemory: 1200},
+					Errors:       map[string]error{"GetMemoryBusWidth": resource.ErrNotSupported},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newMemoryBandwidthLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.memory.bandwidth", tc.expected)
+		})
+	}
+}
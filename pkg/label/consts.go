@@ -17,10 +17,21 @@
 package label
 
 const (
+	// DefaultLabelPrefix is the default prefix of the generated label keys.
+	DefaultLabelPrefix = "iluvatar.com"
+
 	nodeFeaturePrefix = "ix-features"
 
-	nodeLabelPrefix = "iluvatar.com"
-	nodeLabelSep    = "__"
+	nodeLabelSep = "__"
+
+	// labelValueListSep separates the elements of a list stored in a single label value.
+	// Commas are not valid in label values.
+	labelValueListSep = "_"
+	// maxLabelValueLength is the maximum length of a label value accepted by Kubernetes.
+	maxLabelValueLength = 63
 
 	machineTypeUnknown = "unknown"
+
+	// labelValueUnknown is used for labels whose value could not be determined.
+	labelValueUnknown = "unknown"
 )
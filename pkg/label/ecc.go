@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"errors"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// newECCLabeler creates a labeler for the ECC state of the devices. ECC is reported as
+// enabled only if it is enabled on every device; devices that do not support ECC count as
+// disabled. The node is flagged as pending if the ECC mode of any device changes on the
+// next reboot. Devices whose ECC mode cannot be retrieved are skipped. The enabled state
+// is published under both the gpu.ecc-enabled and the gpu.ecc.enabled keys, so that
+// selectors written against either keep matching.
+func newECCLabeler(prefix string, devices []resource.Device) Labeler {
+	labels := make(Labels)
+
+	enabled := true
+	pending := false
+	modeFound := false
+	for i, dev := range devices {
+		current, next, err := dev.GetECCMode()
+		if errors.Is(err, resource.ErrNotSupported) {
+			current, next = false, false
+		} else if err != nil {
+			klog.Warningf("Failed to retrieve ECC mode for device %d: %v", i, err)
+			continue
+		}
+		enabled = enabled && current
+		pending = pending || current != next
+		modeFound = true
+	}
+	if modeFound {
+		labels[prefix+"/gpu.ecc-enabled"] = strconv.FormatBool(enabled)
+		labels[prefix+"/gpu.ecc.enabled"] = strconv.FormatBool(enabled)
+		labels[prefix+"/gpu.ecc.pending"] = strconv.FormatBool(pending)
+	}
+
+	return labels
+}
+
+// newECCErrorLabeler creates a labeler for the aggregate ECC error counts. The single-bit
+// (corrected) and double-bit (uncorrected) counts are summed across all devices, so a node
+// with any uncorrectable errors has a nonzero double-bit and uncorrected error count.
+// Devices whose ECC errors cannot be retrieved are skipped.
+func newECCErrorLabeler(prefix string, devices []resource.Device) Labeler {
+	var single, double uint64
+	found := false
+	for i, dev := range devices {
+		s, d, err := dev.GetECCErrors()
+		if err != nil {
+			klog.Warningf("Failed to retrieve ECC errors for device %d: %v", i, err)
+			continue
+		}
+		single += s
+		double += d
+		found = true
+	}
+
+	if !found {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.ecc-errors-single":      strconv.FormatUint(single, 10),
+		prefix + "/gpu.ecc-errors-double":      strconv.FormatUint(double, 10),
+		prefix + "/gpu.ecc.uncorrected-errors": strconv.FormatUint(double, 10),
+	}
+}
+
+// newRetiredPagesLabeler creates a labeler for the retired memory pages of the devices.
+// The highest count of retired pages across all devices is reported, and the node is
+// flagged as pending if any device needs a reboot to complete page retirement. Devices
+// whose retired pages cannot be retrieved are skipped, e.g. if the driver does not support it.
+func newRetiredPagesLabeler(prefix string, devices []resource.Device) Labeler {
+	var highest uint
+	pending := false
+	found := false
+	for i, dev := range devices {
+		count, p, err := dev.GetRetiredPages()
+		if err != nil {
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to retrieve retired pages for device %d: %v", i, err)
+			}
+			continue
+		}
+		highest = max(highest, count)
+		pending = pending || p
+		found = true
+	}
+
+	if !found {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.retired-pages":         strconv.FormatUint(uint64(highest), 10),
+		prefix + "/gpu.retired-pages.pending": strconv.FormatBool(pending),
+	}
+}
@@ -17,9 +17,14 @@
 package label
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"k8s.io/klog/v2"
 
@@ -27,56 +32,295 @@ import (
 	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
 )
 
-// NewIXDeviceLabeler creates a new labeler for the specified resource manager.
-func NewIXDeviceLabeler(manager resource.Manager, config *config.Config) (Labeler, error) {
-	if err := manager.Init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize resource manager: %v", err)
+// ixDeviceLabeler is a labeler for the devices of a resource manager.
+type ixDeviceLabeler struct {
+	manager resource.Manager
+	prefix  string
+	// machineTypeLabeler generates the machine labels, which are generated even without
+	// devices, and deviceLabeler the labels of the devices.
+	machineTypeLabeler Labeler
+	deviceLabeler      Labeler
+}
+
+// NewIXDeviceLabeler creates a new labeler for the specified resource manager. The devices
+// are queried every time labels are generated rather than when the labeler is created, so
+// that the labeler can be kept across labeling cycles and a cycle that runs out of time
+// stops with the context's error.
+func NewIXDeviceLabeler(manager resource.Manager, config *config.Config) Labeler {
+	prefix := *config.Flags.LabelPrefix
+
+	var machineTypeLabeler Labeler = empty{}
+	if !*config.Flags.NoMachineLabels {
+		machineTypeLabeler = newLazyLabeler(func() (Labeler, error) {
+			return newMachineTypeLabeler(prefix, *config.Flags.MachineTypeFile)
+		})
+	}
+
+	labelers := []Labeler{
+		newLazyLabeler(func() (Labeler, error) {
+			return newDiscoverySourceLabeler(prefix, manager), nil
+		}),
+		newLazyLabeler(func() (Labeler, error) {
+			return newCircuitBreakerLabeler(prefix, manager), nil
+		}),
+	}
+
+	if !*config.Flags.NoDriverLabels {
+		labelers = append(labelers,
+			newConfigRetryLabeler(config, newLazyLabeler(func() (Labeler, error) {
+				return newDriverVersionLabeler(prefix, manager)
+			})),
+			newLazyLabeler(func() (Labeler, error) {
+				return newKernelModuleVersionLabeler(prefix, *config.Flags.KernelModuleVersionFile), nil
+			}),
+			newDevicesLabeler(manager, prefix, newDriverCapabilitiesLabeler),
+		)
+	}
+
+	if !*config.Flags.NoCudaLabels {
+		labelers = append(labelers,
+			newConfigRetryLabeler(config, newLazyLabeler(func() (Labeler, error) {
+				return newCudaVersionLabeler(prefix, manager)
+			})),
+			newDevicesLabeler(manager, prefix, newComputeCapabilityLabeler),
+		)
+	}
+
+	if !*config.Flags.NoGPULabels {
+		labelers = append(labelers, newGPULabelers(prefix, manager, config)...)
+		labelers = append(labelers, newDevicesLabeler(manager, prefix, newHealthLabeler))
+	}
+
+	return &ixDeviceLabeler{
+		manager:            manager,
+		prefix:             prefix,
+		machineTypeLabeler: machineTypeLabeler,
+		deviceLabeler:      mergeConcurrently(newMergeOptions(config), labelers...),
+	}
+}
+
+// Labels method initializes the resource manager for the labeling cycle and returns the
+// labels of its devices
+func (l *ixDeviceLabeler) Labels(ctx context.Context) (Labels, error) {
+	if err := l.manager.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize resource manager: %w", err)
 	}
 	defer func() {
-		if err := manager.Shutdown(); err != nil {
+		if err := l.manager.Shutdown(); err != nil {
 			klog.Errorf("failed to shutdown resource manager: %v", err)
 		}
 	}()
 
-	devices, err := manager.GetDevices()
+	devices, err := l.manager.GetDevices()
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving devices: %v", err)
+		return nil, fmt.Errorf("error retrieving devices: %w", err)
+	}
+
+	// The gpu.present and machine labels are generated even without devices, so that a node
+	// without GPUs can be told apart from a node where discovery is not running.
+	labelers := []Labeler{
+		Labels{l.prefix + "/gpu.present": strconv.FormatBool(len(devices) > 0)},
+		l.machineTypeLabeler,
 	}
 
 	if len(devices) == 0 {
-		klog.Info("No devices detected, returning empty labeler")
-		return empty{}, nil
+		klog.Info("No devices detected, setting gpu.present to false")
+		return Merge(labelers...).Labels(ctx)
 	}
+	klog.Info("Devices detected, setting gpu.present to true")
 
-	machineTypeLabeler, err := newMachineTypeLabeler(*config.Flags.MachineTypeFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to construct machine type labeler: %v", err)
+	return Merge(append(labelers, l.deviceLabeler)...).Labels(ctx)
+}
+
+// lazyLabeler is a labeler that creates its labeler every time labels are generated.
+type lazyLabeler func() (Labeler, error)
+
+// newLazyLabeler creates a labeler that calls newLabeler every time labels are generated,
+// so that the queries of newLabeler run in the labeling cycle rather than when the labeler
+// is created. Labeling stops with the context's error if the context is done first; the
+// queries cannot be interrupted and are left to finish in the background.
+func newLazyLabeler(newLabeler func() (Labeler, error)) Labeler {
+	return lazyLabeler(newLabeler)
+}
+
+// Labels method creates the labeler and returns its labels
+func (newLabeler lazyLabeler) Labels(ctx context.Context) (Labels, error) {
+	type result struct {
+		labels Labels
+		err    error
 	}
+	// The channel is buffered so that the goroutine can exit after the context is done.
+	done := make(chan result, 1)
+	go func() {
+		labeler, err := newLabeler()
+		if err != nil {
+			done <- result{nil, err}
+			return
+		}
+		labels, err := labeler.Labels(ctx)
+		done <- result{labels, err}
+	}()
 
-	versionLabeler, err := ixmlVersionLabeler(manager)
-	if err != nil {
-		return nil, fmt.Errorf("failed to construct version labeler: %v", err)
+	select {
+	case r := <-done:
+		return r.labels, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	ixResourceLabeler, err := newIXResourceLabeler(manager)
-	if err != nil {
-		return nil, fmt.Errorf("error creating resource labeler: %v", err)
+// newDevicesLabeler creates a lazy labeler that retrieves the devices of the manager and
+// creates the labeler for them with newLabeler every time labels are generated.
+func newDevicesLabeler(manager resource.Manager, prefix string, newLabeler func(prefix string, devices []resource.Device) Labeler) Labeler {
+	return newLazyLabeler(func() (Labeler, error) {
+		devices, err := manager.GetDevices()
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving devices: %w", err)
+		}
+		return newLabeler(prefix, devices), nil
+	})
+}
+
+// newDiscoverySourceLabeler creates a labeler for the backend that the manager discovered
+// the devices with, so that nodes labeled from sysfs can be told apart from IXML.
+func newDiscoverySourceLabeler(prefix string, manager resource.Manager) Labeler {
+	s, ok := manager.(resource.DiscoverySourcer)
+	if !ok || s.DiscoverySource() == "" {
+		return empty{}
+	}
+	return Labels{
+		prefix + "/gpu.discovery-source": s.DiscoverySource(),
+	}
+}
+
+// newCircuitBreakerLabeler creates a labeler that reports an open circuit breaker of the
+// manager, so that monitoring can alert on nodes where IXML keeps failing.
+func newCircuitBreakerLabeler(prefix string, manager resource.Manager) Labeler {
+	s, ok := manager.(resource.CircuitStater)
+	if !ok || !s.CircuitOpen() {
+		return empty{}
 	}
+	return Labels{
+		prefix + "/gpu.circuit-open": "true",
+	}
+}
+
+// newConfigRetryLabeler creates a labeler that retries inner as configured. It wraps the
+// labelers whose IXML queries fail the labeling, as the failures are often transient.
+func newConfigRetryLabeler(config *config.Config, inner Labeler) Labeler {
+	return NewRetryLabeler(inner, *config.Flags.LabelerRetryAttempts, time.Duration(*config.Flags.LabelerRetryDelay))
+}
 
-	l := Merge(
-		machineTypeLabeler,
-		versionLabeler,
-		ixResourceLabeler,
+// newGPULabelers creates the labelers for the properties of the GPU devices.
+func newGPULabelers(prefix string, manager resource.Manager, config *config.Config) []Labeler {
+	labelers := []Labeler{
+		newConfigRetryLabeler(config, newLazyLabeler(func() (Labeler, error) {
+			return newIXResourceLabeler(prefix, manager)
+		})),
+	}
+
+	for _, newLabeler := range []func(prefix string, devices []resource.Device) Labeler{
+		newTemperatureLabeler,
+		newPowerLabeler,
+		newPCIeLinkLabeler,
+		newECCLabeler,
+		newRetiredPagesLabeler,
+		newClockLabeler,
+		newPersistenceModeLabeler,
+		newComputeModeLabeler,
+		newDisplayActiveLabeler,
+		newSRIOVLabeler,
+		newVirtualizationModeLabeler,
+	} {
+		labelers = append(labelers, newDevicesLabeler(manager, prefix, newLabeler))
+	}
+
+	// The static attributes of the devices do not change while the devices are installed, so
+	// their labels are reused for a while rather than querying the devices every cycle.
+	ttl := time.Duration(*config.Flags.HardwareLabelsTTL)
+	for _, newLabeler := range []func(prefix string, devices []resource.Device) Labeler{
+		newUUIDLabeler,
+		newMaxPowerLimitLabeler,
+		newMaxSMClockLabeler,
+		newMaxMemoryClockLabeler,
+		newMemoryBandwidthLabeler,
+		newNumaCountLabeler,
+		newNumaNodeLabeler,
+		newDeviceMinorsLabeler,
+		newTensorCoreLabeler,
+		newPrecisionLabeler,
+		newVideoEngineLabeler,
+		newPCIBusIDLabeler,
+		newPCIVendorIDLabeler,
+		newPCIIDsLabeler,
+		newIOMMUGroupsLabeler,
+		newBoardLabeler,
+		newCoolingLabeler,
+		newInterconnectLabeler,
+		newSerialLabeler,
+		newBoardPartNumberLabeler,
+		newVBIOSVersionLabeler,
+	} {
+		labeler := newDevicesLabeler(manager, prefix, newLabeler)
+		if ttl > 0 {
+			labeler = NewCachedLabeler(labeler, ttl)
+		}
+		labelers = append(labelers, labeler)
+	}
+
+	labelers = append(labelers,
+		newLazyLabeler(func() (Labeler, error) {
+			return newSharingLabeler(prefix, *config.Flags.DevicePluginConfigFile), nil
+		}),
+		newDevicesLabeler(manager, prefix, func(prefix string, devices []resource.Device) Labeler {
+			return newVideoCapabilityLabeler(prefix, devices, *config.Flags.VideoCapabilitiesFile)
+		}),
 	)
 
-	return l, nil
+	if !*config.Flags.NoECCErrorLabels {
+		labelers = append(labelers, newDevicesLabeler(manager, prefix, newECCErrorLabeler))
+	}
+	if !*config.Flags.NoPartitioningLabels {
+		labelers = append(labelers, newDevicesLabeler(manager, prefix, newPartitioningLabeler))
+	}
+	if *config.Flags.PerDeviceLabels {
+		labelers = append(labelers, newDevicesLabeler(manager, prefix, newPerDeviceLabeler))
+	}
+
+	return labelers
+}
+
+// newHealthLabeler creates a labeler for the health of the devices. The node is healthy if
+// every device is, and the number of unhealthy devices is reported otherwise. A device
+// failing its health check does not prevent the labels of the other devices.
+func newHealthLabeler(prefix string, devices []resource.Device) Labeler {
+	unhealthy := 0
+	for i, dev := range devices {
+		if err := dev.CheckHealth(); err != nil {
+			klog.Warningf("Device %d is unhealthy: %v", i, err)
+			unhealthy++
+		}
+	}
+
+	labels := Labels{
+		prefix + "/gpu.healthy": strconv.FormatBool(unhealthy == 0),
+	}
+	if unhealthy > 0 {
+		labels[prefix+"/gpu.unhealthy-count"] = strconv.Itoa(unhealthy)
+	}
+
+	return labels
 }
 
-// ixmlVersionLabeler creates a labeler that generates the driver and runtime version labels.
-func ixmlVersionLabeler(manager resource.Manager) (Labeler, error) {
+// newDriverVersionLabeler creates a labeler that generates the driver and IXML version labels.
+func newDriverVersionLabeler(prefix string, manager resource.Manager) (Labeler, error) {
 	driverVersion, err := manager.GetIXDriverVersion()
+	if errors.Is(err, resource.ErrNotSupported) {
+		klog.Warningf("Driver version not supported, skipping driver version labels: %v", err)
+		return newIXMLVersionLabeler(prefix, manager), nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving ix driver version: %v", err)
+		return nil, fmt.Errorf("error retrieving ix driver version: %w", err)
 	}
 
 	driverVersionSplit := strings.Split(driverVersion, ".")
@@ -91,77 +335,671 @@ func ixmlVersionLabeler(manager resource.Manager) (Labeler, error) {
 		driverRev = driverVersionSplit[2]
 	}
 
-	cudaMajor, cudaMinor, err := manager.GetCudaRuntimeVersion()
+	labels := Labels{
+		prefix + "/ix.driver-version.full":     driverVersion,
+		prefix + "/ix.driver-version.major":    driverMajor,
+		prefix + "/ix.driver-version.minor":    driverMinor,
+		prefix + "/ix.driver-version.revision": driverRev,
+	}
+	return Merge(labels, newIXMLVersionLabeler(prefix, manager)), nil
+}
+
+// newCudaVersionLabeler creates a labeler that generates the CUDA driver and CUDA runtime
+// version labels. The driver version is the highest CUDA version supported by the driver.
+// The labels of a version that cannot be retrieved are skipped, and an error is returned
+// only if neither version can be retrieved.
+func newCudaVersionLabeler(prefix string, manager resource.Manager) (Labeler, error) {
+	labels := make(Labels)
+	var errs []error
+	for _, version := range []struct {
+		kind string
+		get  func() (*uint, *uint, error)
+	}{
+		{"driver", manager.GetCudaDriverVersion},
+		{"runtime", manager.GetCudaRuntimeVersion},
+	} {
+		major, minor, err := version.get()
+		if errors.Is(err, resource.ErrNotSupported) {
+			klog.Warningf("CUDA %s version not supported, skipping CUDA %s version labels: %v", version.kind, version.kind, err)
+			continue
+		}
+		if err != nil {
+			klog.Warningf("Failed to retrieve CUDA %s version, skipping CUDA %s version labels: %v", version.kind, version.kind, err)
+			errs = append(errs, fmt.Errorf("error retrieving CUDA %s version: %w", version.kind, err))
+			continue
+		}
+		key := prefix + "/cuda." + version.kind + "-version"
+		labels[key+".full"] = fmt.Sprintf("%d.%d", *major, *minor)
+		labels[key+".major"] = fmt.Sprintf("%d", *major)
+		labels[key+".minor"] = fmt.Sprintf("%d", *minor)
+	}
+	if len(errs) == 2 {
+		return nil, errors.Join(errs...)
+	}
+
+	return labels, nil
+}
+
+// newIXMLVersionLabeler creates a labeler for the IXML library version. The labels are
+// omitted if the version cannot be retrieved.
+func newIXMLVersionLabeler(prefix string, manager resource.Manager) Labeler {
+	version, err := manager.GetIXMLVersion()
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving CUDA runtime version: %v", err)
+		klog.Warningf("Failed to retrieve IXML version: %v", err)
+		return empty{}
 	}
 
 	labels := Labels{
-		nodeLabelPrefix + "/ix.driver-version.full":     driverVersion,
-		nodeLabelPrefix + "/ix.driver-version.major":    driverMajor,
-		nodeLabelPrefix + "/ix.driver-version.minor":    driverMinor,
-		nodeLabelPrefix + "/ix.driver-version.revision": driverRev,
-		nodeLabelPrefix + "/cuda.runtime-version.full":  fmt.Sprintf("%d.%d", *cudaMajor, *cudaMinor),
-		nodeLabelPrefix + "/cuda.runtime-version.major": fmt.Sprintf("%d", *cudaMajor),
-		nodeLabelPrefix + "/cuda.runtime-version.minor": fmt.Sprintf("%d", *cudaMinor),
+		prefix + "/ix.ixml-version.full": sanitise(version),
+	}
+	major, _, _ := strings.Cut(version, ".")
+	if _, err := strconv.Atoi(major); err == nil {
+		labels[prefix+"/ix.ixml-version.major"] = major
+	}
+
+	return labels
+}
+
+// newComputeCapabilityLabeler creates a labeler for the CUDA compute capability. The lowest
+// compute capability across all devices is reported, so that workloads selecting on it can
+// run on every device of the node. Devices whose compute capability cannot be retrieved are skipped.
+func newComputeCapabilityLabeler(prefix string, devices []resource.Device) Labeler {
+	var major, minor int
+	found := false
+	for i, dev := range devices {
+		ccMajor, ccMinor, err := dev.GetCudaComputeCapability()
+		if err != nil {
+			klog.Warningf("Failed to retrieve compute capability for device %d: %v", i, err)
+			continue
+		}
+		if !found || ccMajor < major || (ccMajor == major && ccMinor < minor) {
+			major, minor = ccMajor, ccMinor
+		}
+		found = true
+	}
+
+	if !found {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/cuda.compute-capability.full":  fmt.Sprintf("%d.%d", major, minor),
+		prefix + "/cuda.compute-capability.major": fmt.Sprintf("%d", major),
+		prefix + "/cuda.compute-capability.minor": fmt.Sprintf("%d", minor),
+	}
+}
+
+// newTensorCoreLabeler creates a labeler for the tensor core availability. Tensor cores
+// are reported as available only if every device has them.
+func newTensorCoreLabeler(prefix string, devices []resource.Device) Labeler {
+	return newAllDevicesLabeler(prefix, devices, "gpu.has-tensor-cores", "tensor core availability", resource.Device.HasTensorCores)
+}
+
+// newPrecisionLabeler creates a labeler for the FP16 and INT8 support. A precision is
+// reported as supported only if every device supports it.
+func newPrecisionLabeler(prefix string, devices []resource.Device) Labeler {
+	return Merge(
+		newAllDevicesLabeler(prefix, devices, "gpu.fp16-supported", "FP16 support", resource.Device.GetFP16Supported),
+		newAllDevicesLabeler(prefix, devices, "gpu.int8-supported", "INT8 support", resource.Device.GetINT8Supported),
+	)
+}
+
+// newAllDevicesLabeler creates a labeler for a boolean device property, which is reported
+// as true only if it holds for every device. Devices for which the property cannot be
+// retrieved are skipped, and the label is omitted if it cannot be retrieved for any device.
+func newAllDevicesLabeler(prefix string, devices []resource.Device, name string, description string, query func(resource.Device) (bool, error)) Labeler {
+	all := true
+	found := false
+	for i, dev := range devices {
+		value, err := query(dev)
+		if err != nil {
+			klog.Warningf("Failed to retrieve %s for device %d: %v", description, i, err)
+			continue
+		}
+		all = all && value
+		found = true
+	}
+
+	if !found {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/" + name: strconv.FormatBool(all),
 	}
-	return labels, nil
 }
 
 // newIXResourceLabeler creates a labeler for available IX resources.
-func newIXResourceLabeler(manager resource.Manager) (Labeler, error) {
+func newIXResourceLabeler(prefix string, manager resource.Manager) (Labeler, error) {
 	devices, err := manager.GetDevices()
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving devices: %v", err)
+		return nil, fmt.Errorf("error retrieving devices: %w", err)
 	}
 
 	var labelers labelerList
-	if len(devices) == 0 {
-		klog.Info("No GPUs detected, setting gpu.present to false")
-		labelers = append(labelers, Labels{nodeLabelPrefix + "/gpu.present": "false"})
-	} else {
-		klog.Info("GPUs detected, setting gpu.present to true")
-		labelers = append(labelers, Labels{nodeLabelPrefix + "/gpu.present": "true"})
-	}
-
 	counts := make(map[string]int)
-	memorys := make(map[string]string)
+	memorys := make(map[string]uint64)
+	utilizations := make(map[string]uint)
+	multiprocessors := make(map[string]string)
+	brands := make(map[string]string)
+	fullNames := make(map[string]string)
+	usedMemorys := make(map[string]uint64)
+	var totalMemory uint64
 	for _, dev := range devices {
 		name, err := dev.GetName()
 		if err != nil {
-			return nil, fmt.Errorf("error retrieving device name: %v", err)
+			return nil, fmt.Errorf("error retrieving device name: %w", err)
 		}
 		memory, err := dev.GetTotalMemoryMB()
 		if err != nil {
-			return nil, fmt.Errorf("error retrieving device memory: %v", err)
+			return nil, fmt.Errorf("error retrieving device memory: %w", err)
 		}
 		klog.Infof("Successfully retrieved memory for device %s: %d (MB)", name, memory)
 
 		counts[name]++
-		memorys[name] = strconv.Itoa(int(memory))
+		memorys[name] = memory
+		totalMemory += memory
+
+		used, err := dev.GetUsedMemoryMB()
+		if err != nil {
+			klog.Warningf("Failed to retrieve used memory for device %s: %v", name, err)
+		} else if u, ok := usedMemorys[name]; !ok || used > u {
+			usedMemorys[name] = used
+		}
+
+		brand, err := dev.GetBrand()
+		if err != nil {
+			klog.Warningf("Failed to retrieve brand for device %s: %v", name, err)
+		} else if brand != "" {
+			brands[name] = sanitise(brand)
+		}
+
+		fullName, err := dev.GetFullName()
+		if err != nil {
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to retrieve full name for device %s: %v", name, err)
+			}
+		} else if fullName = sanitise(fullName); fullName != "" {
+			fullNames[name] = fullName
+		}
+
+		multiprocessorCount, err := dev.GetMultiprocessorCount()
+		if err != nil {
+			klog.Warningf("Failed to retrieve multiprocessor count for device %s: %v", name, err)
+		} else {
+			multiprocessors[name] = strconv.FormatUint(uint64(multiprocessorCount), 10)
+		}
+
+		utilization, err := dev.GetUtilizationPercent()
+		if err != nil {
+			klog.Warningf("Failed to retrieve utilization for device %s: %v", name, err)
+			continue
+		}
+		if u, ok := utilizations[name]; !ok || utilization > u {
+			utilizations[name] = utilization
+		}
+	}
+
+	names := make([]string, 0, len(counts))
+	for n := range counts {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	if len(names) > 1 {
+		klog.Warningf("Multiple device types detected, setting gpu.product-count to %d: %v", len(names), names)
 	}
+	labelers = append(labelers, Labels{
+		prefix + "/gpu.product-count": strconv.Itoa(len(names)),
+	})
 
-	if len(counts) > 1 {
-		var names []string
-		for n := range counts {
-			names = append(names, n)
+	// On a node with several device types, each type gets a set of labels suffixed with its
+	// index in name order, and the unsuffixed labels describe the most numerous type.
+	if len(names) > 1 {
+		for i, name := range names {
+			l := Labels{
+				fmt.Sprintf("%s/gpu.product.%d", prefix, i): name,
+				fmt.Sprintf("%s/gpu.count.%d", prefix, i):   strconv.Itoa(counts[name]),
+				fmt.Sprintf("%s/gpu.memory.%d", prefix, i):  strconv.FormatUint(memorys[name], 10),
+			}
+			if utilization, ok := utilizations[name]; ok {
+				l[fmt.Sprintf("%s/gpu.utilization.%d", prefix, i)] = strconv.FormatUint(uint64(clampPercent(utilization)), 10)
+			}
+			labelers = append(labelers, l)
 		}
-		klog.Warningf("Multiple device types detected: %v", names)
 	}
 
-	for name, count := range counts {
+	if name, ok := mostNumerousProduct(names, counts); ok {
 		l := Labels{
-			nodeLabelPrefix + "/gpu.product": name,
-			nodeLabelPrefix + "/gpu.count":   strconv.Itoa(count),
-			nodeLabelPrefix + "/gpu.memory":  memorys[name],
+			prefix + "/gpu.product":      name,
+			prefix + "/gpu.count":        strconv.Itoa(counts[name]),
+			prefix + "/gpu.memory":       strconv.FormatUint(memorys[name], 10),
+			prefix + "/gpu.memory.gib":   strconv.FormatUint(memoryMBToGiB(memorys[name]), 10),
+			prefix + "/gpu.product.sku":  productSKU(name, memorys[name]),
+			prefix + "/gpu.architecture": getArchitecture(name),
+		}
+		l[prefix+"/gpu.brand"] = labelValueUnknown
+		if brand, ok := brands[name]; ok {
+			l[prefix+"/gpu.brand"] = brand
+		}
+		if fullName, ok := fullNames[name]; ok {
+			l[prefix+"/gpu.product.full"] = fullName
+		}
+		// The used memory is a snapshot of the most used device, and the free memory is
+		// derived from it so that gpu.memory-free = gpu.memory - gpu.memory-used.
+		if used, ok := usedMemorys[name]; ok {
+			l[prefix+"/gpu.memory-used"] = strconv.FormatUint(used, 10)
+			l[prefix+"/gpu.memory-free"] = strconv.FormatUint(memorys[name]-min(used, memorys[name]), 10)
+		}
+		if multiprocessorCount, ok := multiprocessors[name]; ok {
+			l[prefix+"/gpu.multiprocessors"] = multiprocessorCount
+		}
+		// The utilization is a snapshot taken at discovery time, not a moving average.
+		if utilization, ok := utilizations[name]; ok {
+			l[prefix+"/gpu.utilization"] = strconv.FormatUint(uint64(clampPercent(utilization)), 10)
 		}
 		labelers = append(labelers, l)
 	}
 
-	labels, err := labelers.Labels()
-	if err != nil {
-		return nil, err
+	// The total memory covers all devices, including those of different models.
+	if len(devices) > 0 {
+		labelers = append(labelers, Labels{
+			prefix + "/gpu.memory.total": strconv.FormatUint(totalMemory, 10),
+		})
 	}
 
-	return Merge(labels), nil
+	return labelers, nil
+}
+
+// newTemperatureLabeler creates a labeler for the temperature of the hottest device.
+// Devices whose temperature cannot be retrieved are skipped.
+func newTemperatureLabeler(prefix string, devices []resource.Device) Labeler {
+	var hottest uint
+	found := false
+	for i, dev := range devices {
+		temp, err := dev.GetTemperatureCelsius()
+		if err != nil {
+			klog.Warningf("Failed to retrieve temperature for device %d: %v", i, err)
+			continue
+		}
+		if !found || temp > hottest {
+			hottest = temp
+			found = true
+		}
+	}
+
+	if !found {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.temperature": strconv.FormatUint(uint64(hottest), 10),
+	}
+}
+
+// newUUIDLabeler creates a labeler for the UUIDs of the devices. UUIDs consist of
+// alphanumerics and hyphens, which are valid in label values, so they are only lowercased.
+// Devices whose UUID cannot be retrieved are skipped.
+func newUUIDLabeler(prefix string, devices []resource.Device) Labeler {
+	uuids := make(map[int]string)
+	for i, dev := range devices {
+		uuid, err := dev.GetUUID()
+		if err != nil {
+			klog.Warningf("Failed to retrieve UUID for device %d: %v", i, err)
+			continue
+		}
+		uuids[i] = strings.ToLower(uuid)
+	}
+
+	return perDeviceLabels(prefix, "uuid", len(devices), uuids)
+}
+
+// newPersistenceModeLabeler creates a labeler for the persistence mode of the devices.
+// Persistence mode is reported as enabled only if it is enabled on every device.
+func newPersistenceModeLabeler(prefix string, devices []resource.Device) Labeler {
+	return newAllDevicesLabeler(prefix, devices, "gpu.persistence-mode", "persistence mode", resource.Device.GetPersistenceMode)
+}
+
+// newDisplayActiveLabeler creates a labeler for whether a display is active on any device.
+// Devices that do not support the query are treated as having no active display, and
+// devices whose display state cannot be retrieved are skipped.
+func newDisplayActiveLabeler(prefix string, devices []resource.Device) Labeler {
+	active := false
+	found := false
+	for i, dev := range devices {
+		a, err := dev.GetDisplayActive()
+		if errors.Is(err, resource.ErrNotSupported) {
+			a = false
+		} else if err != nil {
+			klog.Warningf("Failed to retrieve display active for device %d: %v", i, err)
+			continue
+		}
+		active = active || a
+		found = true
+	}
+
+	if !found {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.display-active": strconv.FormatBool(active),
+	}
+}
+
+// computeModes lists the compute modes from the least to the most restrictive.
+var computeModes = []resource.ComputeMode{
+	resource.ComputeModeDefault,
+	resource.ComputeModeExclusiveProcess,
+	resource.ComputeModeProhibited,
+}
+
+// newComputeModeLabeler creates a labeler for the compute mode of the devices. If the
+// devices are in different modes, the most restrictive mode is reported and the node is
+// flagged as mixed. Devices whose compute mode cannot be retrieved are skipped.
+func newComputeModeLabeler(prefix string, devices []resource.Device) Labeler {
+	var modes []resource.ComputeMode
+	for i, dev := range devices {
+		mode, err := dev.GetComputeMode()
+		if err != nil {
+			klog.Warningf("Failed to retrieve compute mode for device %d: %v", i, err)
+			continue
+		}
+		modes = append(modes, mode)
+	}
+
+	if len(modes) == 0 {
+		return empty{}
+	}
+
+	restrictive := slices.MaxFunc(modes, func(a, b resource.ComputeMode) int {
+		return cmp.Compare(slices.Index(computeModes, a), slices.Index(computeModes, b))
+	})
+	mixed := slices.ContainsFunc(modes, func(m resource.ComputeMode) bool { return m != restrictive })
+
+	return Labels{
+		prefix + "/gpu.compute-mode":       string(restrictive),
+		prefix + "/gpu.compute-mode.mixed": strconv.FormatBool(mixed),
+	}
+}
+
+// newNumaCountLabeler creates a labeler for the number of devices of any model attached to
+// each NUMA node. The labels are only generated if the NUMA node of every device is known,
+// so that the per-NUMA counts always add up to the total number of devices. On a node with
+// several device types this is the sum of the gpu.count.<index> labels, not gpu.count.
+func newNumaCountLabeler(prefix string, devices []resource.Device) Labeler {
+	counts := make(map[int]int)
+	for i, dev := range devices {
+		node, err := dev.GetNumaNode()
+		if err != nil {
+			klog.Warningf("Failed to retrieve NUMA node for device %d: %v", i, err)
+			return empty{}
+		}
+		if node < 0 {
+			klog.Infof("NUMA node of device %d is unknown, skipping NUMA labels", i)
+			return empty{}
+		}
+		counts[node]++
+	}
+
+	labels := make(Labels)
+	for node, count := range counts {
+		labels[fmt.Sprintf("%s/gpu.numa.%d.count", prefix, node)] = strconv.Itoa(count)
+	}
+
+	return labels
+}
+
+// newNumaNodeLabeler creates a labeler for the NUMA node of each device. If the NUMA node
+// of a device is unavailable it is reported as unknown.
+func newNumaNodeLabeler(prefix string, devices []resource.Device) Labeler {
+	nodes := make(map[int]string)
+	for i, dev := range devices {
+		nodes[i] = labelValueUnknown
+		node, err := dev.GetNumaNode()
+		if err != nil {
+			klog.Warningf("Failed to retrieve NUMA node for device %d: %v", i, err)
+			continue
+		}
+		if node >= 0 {
+			nodes[i] = strconv.Itoa(node)
+		}
+	}
+
+	return perDeviceLabels(prefix, "numa-node", len(devices), nodes)
+}
+
+// newDeviceMinorsLabeler creates a labeler listing the minor numbers of the device nodes
+// of all devices in ascending order. A warning is logged if the number of minors does not
+// match the number of devices. Devices whose minor number cannot be retrieved are skipped.
+func newDeviceMinorsLabeler(prefix string, devices []resource.Device) Labeler {
+	var minors []int
+	for i, dev := range devices {
+		minor, err := dev.GetMinorNumber()
+		if err != nil {
+			klog.Warningf("Failed to retrieve minor number for device %d: %v", i, err)
+			continue
+		}
+		minors = append(minors, minor)
+	}
+
+	if len(minors) != len(devices) {
+		klog.Warningf("Found %d device minor numbers for %d devices", len(minors), len(devices))
+	}
+	if len(minors) == 0 {
+		return empty{}
+	}
+
+	// Sort numerically, as joinLabelValues would order e.g. 10 before 2.
+	slices.Sort(minors)
+	values := make([]string, len(minors))
+	for i, minor := range minors {
+		values[i] = strconv.Itoa(minor)
+	}
+	joined := strings.Join(values, labelValueListSep)
+	if len(joined) > maxLabelValueLength {
+		klog.Warningf("Skipping label %s: joined label value must be %v characters or less: %v", "gpu.dev-minors", maxLabelValueLength, joined)
+		return empty{}
+	}
+
+	return Labels{prefix + "/gpu.dev-minors": joined}
+}
+
+// newSerialLabeler creates a labeler for the board serial numbers of the devices.
+// Devices that do not support the serial query are silently skipped.
+func newSerialLabeler(prefix string, devices []resource.Device) Labeler {
+	serials := make(map[int]string)
+	for i, dev := range devices {
+		serial, err := dev.GetSerial()
+		if errors.Is(err, resource.ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			klog.Warningf("Failed to retrieve serial for device %d: %v", i, err)
+			continue
+		}
+		serials[i] = sanitise(serial)
+	}
+
+	return perDeviceLabels(prefix, "serial", len(devices), serials)
+}
+
+// newVBIOSVersionLabeler creates a labeler for the VBIOS version of the devices. If the
+// devices report different versions, the lowest version is reported and the node is
+// flagged as mixed. An empty version is reported as unknown. Devices whose VBIOS version
+// cannot be retrieved are skipped.
+func newVBIOSVersionLabeler(prefix string, devices []resource.Device) Labeler {
+	var versions []string
+	for i, dev := range devices {
+		version, err := dev.GetVBIOSVersion()
+		if err != nil {
+			klog.Warningf("Failed to retrieve VBIOS version for device %d: %v", i, err)
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	if len(versions) == 0 {
+		return empty{}
+	}
+
+	lowest := slices.MinFunc(versions, compareVersions)
+	mixed := slices.ContainsFunc(versions, func(v string) bool { return v != lowest })
+	if mixed {
+		klog.Warningf("Multiple VBIOS versions detected, reporting lowest version %s", lowest)
+	}
+
+	labels := Labels{
+		prefix + "/gpu.vbios-version":       labelValueUnknown,
+		prefix + "/gpu.vbios-version.mixed": strconv.FormatBool(mixed),
+	}
+	if lowest != "" {
+		labels[prefix+"/gpu.vbios-version"] = sanitise(lowest)
+	}
+	// VBIOS versions are dot-separated, only publish the major version if it is numeric.
+	major, _, _ := strings.Cut(lowest, ".")
+	if _, err := strconv.Atoi(major); err == nil {
+		labels[prefix+"/gpu.vbios-version.major"] = major
+	}
+
+	return labels
+}
+
+// compareVersions compares two dot-separated version strings component by component.
+// Numeric components are compared as numbers, others lexically. It returns -1, 0 or 1
+// if a is lower than, equal to or higher than b.
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		if aErr == nil && bErr == nil {
+			if an != bn {
+				return cmp.Compare(an, bn)
+			}
+			continue
+		}
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(as), len(bs))
+}
+
+// newBoardPartNumberLabeler creates a labeler for the board part numbers of the devices.
+// If all devices report the same part number a single label is generated, otherwise one
+// label per device index. Devices whose part number cannot be retrieved are skipped.
+func newBoardPartNumberLabeler(prefix string, devices []resource.Device) Labeler {
+	partNumbers := make(map[int]string)
+	var first string
+	mixed := false
+	for i, dev := range devices {
+		partNumber, err := dev.GetBoardPartNumber()
+		if err != nil {
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to retrieve board part number for device %d: %v", i, err)
+			}
+			continue
+		}
+		partNumber = sanitise(partNumber)
+		if len(partNumbers) == 0 {
+			first = partNumber
+		}
+		mixed = mixed || partNumber != first
+		partNumbers[i] = partNumber
+	}
+
+	if len(partNumbers) == 0 {
+		return empty{}
+	}
+	if !mixed {
+		return Labels{prefix + "/gpu.board-part-number": first}
+	}
+
+	labels := make(Labels)
+	for i, partNumber := range partNumbers {
+		labels[deviceLabelKey(prefix, i, "board-part-number")] = partNumber
+	}
+	return labels
+}
+
+// newPerDeviceLabeler creates a labeler for the product and memory of every device, keyed
+// by the device index. The index is the position in the device list of the manager, which
+// follows the IXML device index and so is stable across runs.
+func newPerDeviceLabeler(prefix string, devices []resource.Device) Labeler {
+	labels := make(Labels)
+	for i, dev := range devices {
+		name, err := dev.GetName()
+		if err != nil {
+			klog.Warningf("Failed to retrieve name for device %d: %v", i, err)
+			continue
+		}
+		labels[deviceLabelKey(prefix, i, "product")] = name
+
+		memory, err := dev.GetTotalMemoryMB()
+		if err != nil {
+			klog.Warningf("Failed to retrieve memory for device %d: %v", i, err)
+			continue
+		}
+		labels[deviceLabelKey(prefix, i, "memory")] = strconv.FormatUint(memory, 10)
+	}
+
+	return labels
+}
+
+// perDeviceLabels creates labels for a per-device attribute. A node with a single device
+// gets the label gpu.<attr>, a node with multiple devices gets one gpu.<index>.<attr>
+// label per device. Devices without a value are skipped.
+func perDeviceLabels(prefix, attr string, numDevices int, values map[int]string) Labels {
+	labels := make(Labels)
+	for i, v := range values {
+		if numDevices == 1 {
+			labels[prefix+"/gpu."+attr] = v
+			continue
+		}
+		labels[deviceLabelKey(prefix, i, attr)] = v
+	}
+
+	return labels
+}
+
+// deviceLabelKey returns the label key of an attribute for the device at the given index.
+func deviceLabelKey(prefix string, index int, attr string) string {
+	return fmt.Sprintf("%s/gpu.%d.%s", prefix, index, attr)
+}
+
+// mostNumerousProduct returns the product with the most devices from the sorted product
+// names. Ties go to the first name in order. False is returned if there are no products.
+func mostNumerousProduct(names []string, counts map[string]int) (string, bool) {
+	if len(names) == 0 {
+		return "", false
+	}
+	most := names[0]
+	for _, name := range names[1:] {
+		if counts[name] > counts[most] {
+			most = name
+		}
+	}
+	return most, true
+}
+
+// memoryMBToGiB converts a memory size in MB to GiB, rounded to the nearest whole GiB, so
+// that boards reporting slightly different totals (e.g. 32510 and 32768) get the same value.
+func memoryMBToGiB(mb uint64) uint64 {
+	return (mb + 512) / 1024
+}
+
+// productSKU returns the SKU of a product, which is the product name followed by the memory
+// size in whole GiB, e.g. "BI-V150S-32GB", to tell apart memory configurations of a product.
+func productSKU(name string, memoryMB uint64) string {
+	return fmt.Sprintf("%s-%dGB", name, memoryMBToGiB(memoryMB))
+}
+
+// clampPercent limits a percentage value to the range [0,100].
+func clampPercent(p uint) uint {
+	if p > 100 {
+		return 100
+	}
+	return p
 }
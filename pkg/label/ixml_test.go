@@ -0,0 +1,1133 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// errTest is the error returned by the failing queries of mock devices.
+var errTest = errors.New("test error")
+
+// asDevices converts mock devices to a list of devices.
+func asDevices(devices ...*resource.MockDevice) []resource.Device {
+	var list []resource.Device
+	for _, d := range devices {
+		list = append(list, d)
+	}
+	return list
+}
+
+// generateLabels returns the labels of the labeler, failing the test on an error.
+func generateLabels(t *testing.T, l Labeler) Labels {
+	t.Helper()
+	labels, err := l.Labels(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return labels
+}
+
+// checkLabel checks the value of the label with the key, or that the label is absent if
+// the expected value is empty.
+func checkLabel(t *testing.T, labels Labels, key string, expected string) {
+	t.Helper()
+	value, ok := labels[DefaultLabelPrefix+"/"+key]
+	switch {
+	case expected == "" && ok:
+		t.Errorf("expected no label %s, got %q", key, value)
+	case expected != "" && value != expected:
+		t.Errorf("expected label %s=%q, got %q", key, expected, value)
+	}
+}
+
+// checkLabels checks that the labels are the expected labels.
+func checkLabels(t *testing.T, labels Labels, expected Labels) {
+	t.Helper()
+	if len(labels) == 0 && len(expected) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, labels)
+	}
+}
+
+func TestUtilizationLabel(t *testing.T) {
+	testCases := []struct {
+		description  string
+		utilizations []uint
+		names        []string
+		errors       []error
+		expected     string
+		perModel     []string
+	}{
+		{
+			description:  "single device",
+			utilizations: []uint{42},
+			expected:     "42",
+		},
+		{
+			description:  "maximum across devices of a model",
+			utilizations: []uint{20, 60, 40},
+			expected:     "60",
+		},
+		{
+			description:  "value above 100 is clamped",
+			utilizations: []uint{30, 150},
+			expected:     "100",
+		},
+		{
+			description:  "failed device is skipped",
+			utilizations: []uint{80, 10},
+			errors:       []error{errTest, nil},
+			expected:     "10",
+		},
+		{
+			description:  "label is absent if no device reports it",
+			utilizations: []uint{10},
+			errors:       []error{errTest},
+		},
+		{
+			description:  "maximum per model on a mixed node",
+			utilizations: []uint{20, 90, 60, 150},
+			names:        []string{"MR-V100", "BI-V150", "MR-V100", "BI-V150"},
+			expected:     "100",
+			perModel:     []string{"100", "60"},
+		},
+		{
+			description:  "per-model label is absent if no device of the model reports it",
+			utilizations: []uint{20, 90, 60},
+			names:        []string{"MR-V100", "BI-V150", "MR-V100"},
+			errors:       []error{nil, errTest, nil},
+			expected:     "60",
+			perModel:     []string{"", "60"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var devices []*resource.MockDevice
+			for i, u := range tc.utilizations {
+				d := &resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32768, UtilizationPercent: u}
+				if i < len(tc.names) {
+					d.Name = tc.names[i]
+				}
+				if i < len(tc.errors) && tc.errors[i] != nil {
+					d.Errors = map[string]error{"GetUtilizationPercent": tc.errors[i]}
+				}
+				devices = append(devices, d)
+			}
+			l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(devices...)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			labels := generateLabels(t, l)
+			checkLabel(t, labels, "gpu.utilization", tc.expected)
+			for i, expected := range tc.perModel {
+				checkLabel(t, labels, fmt.Sprintf("gpu.utilization.%d", i), expected)
+			}
+			if len(tc.perModel) == 0 {
+				checkLabel(t, labels, "gpu.utilization.0", "")
+			}
+		})
+	}
+}
+
+func TestComputeCapabilityLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    Labels
+	}{
+		{
+			description: "single device",
+			devices: []*resource.MockDevice{
+				{ComputeCapabilityMajor: 7, ComputeCapabilityMinor: 0},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/cuda.compute-capability.full":  "7.0",
+				DefaultLabelPrefix + "/cuda.compute-capability.major": "7",
+				DefaultLabelPrefix + "/cuda.compute-capability.minor": "0",
+			},
+		},
+		{
+			description: "mixed capabilities report the minimum",
+			devices: []*resource.MockDevice{
+				{ComputeCapabilityMajor: 8, ComputeCapabilityMinor: 0},
+				{ComputeCapabilityMajor: 7, ComputeCapabilityMinor: 5},
+				{ComputeCapabilityMajor: 7, ComputeCapabilityMinor: 2},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/cuda.compute-capability.full":  "7.2",
+				DefaultLabelPrefix + "/cuda.compute-capability.major": "7",
+				DefaultLabelPrefix + "/cuda.compute-capability.minor": "2",
+			},
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{ComputeCapabilityMajor: 6, ComputeCapabilityMinor: 1, Error: errTest},
+				{ComputeCapabilityMajor: 7, ComputeCapabilityMinor: 0},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/cuda.compute-capability.full":  "7.0",
+				DefaultLabelPrefix + "/cuda.compute-capability.major": "7",
+				DefaultLabelPrefix + "/cuda.compute-capability.minor": "0",
+			},
+		},
+		{
+			description: "no labels if no device reports its capability",
+			devices: []*resource.MockDevice{
+				{Error: errTest},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newComputeCapabilityLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
+
+func TestPersistenceModeLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "enabled",
+			devices:     []*resource.MockDevice{{PersistenceMode: true}, {PersistenceMode: true}},
+			expected:    "true",
+		},
+		{
+			description: "disabled",
+			devices:     []*resource.MockDevice{{PersistenceMode: false}},
+			expected:    "false",
+		},
+		{
+			description: "disabled on one device",
+			devices:     []*resource.MockDevice{{PersistenceMode: true}, {PersistenceMode: false}},
+			expected:    "false",
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{PersistenceMode: false, Errors: map[string]error{"GetPersistenceMode": errTest}},
+				{PersistenceMode: true},
+			},
+			expected: "true",
+		},
+		{
+			description: "label is absent if no device reports it",
+			devices:     []*resource.MockDevice{{Error: errTest}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newPersistenceModeLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.persistence-mode", tc.expected)
+		})
+	}
+}
+
+func TestNumaCountLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		numaNodes   []int
+		names       []string
+		errors      []error
+		expected    Labels
+	}{
+		{
+			description: "devices spread across NUMA nodes",
+			numaNodes:   []int{0, 0, 1, 1, 1},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.numa.0.count": "2",
+				DefaultLabelPrefix + "/gpu.numa.1.count": "3",
+			},
+		},
+		{
+			description: "devices on a single NUMA node",
+			numaNodes:   []int{0, 0},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.numa.0.count": "2",
+			},
+		},
+		{
+			description: "devices of different models are all counted",
+			numaNodes:   []int{0, 0, 1, 1, 1},
+			names:       []string{"MR-V100", "BI-V150", "MR-V100", "MR-V100", "BI-V150"},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.numa.0.count": "2",
+				DefaultLabelPrefix + "/gpu.numa.1.count": "3",
+			},
+		},
+		{
+			description: "all NUMA nodes unknown",
+			numaNodes:   []int{-1, -1},
+		},
+		{
+			description: "one NUMA node unknown",
+			numaNodes:   []int{0, -1},
+		},
+		{
+			description: "failed device",
+			numaNodes:   []int{0, 1},
+			errors:      []error{nil, errTest},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var devices []*resource.MockDevice
+			for i, node := range tc.numaNodes {
+				d := &resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32768, NumaNode: node}
+				if i < len(tc.names) {
+					d.Name = tc.names[i]
+				}
+				if i < len(tc.errors) && tc.errors[i] != nil {
+					d.Errors = map[string]error{"GetNumaNode": tc.errors[i]}
+				}
+				devices = append(devices, d)
+			}
+			labels := generateLabels(t, newNumaCountLabeler(DefaultLabelPrefix, asDevices(devices...)))
+			checkLabels(t, labels, tc.expected)
+			if len(labels) == 0 {
+				return
+			}
+
+			// The per-NUMA counts must add up to the number of devices of all models, which
+			// is gpu.count on a single-model node and the sum of gpu.count.<index> otherwise.
+			l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(devices...)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resourceLabels := generateLabels(t, l)
+			gpuCount := 0
+			for key, value := range resourceLabels {
+				if !strings.HasPrefix(key, DefaultLabelPrefix+"/gpu.count.") {
+					continue
+				}
+				count, err := strconv.Atoi(value)
+				if err != nil {
+					t.Fatalf("invalid %s: %v", key, err)
+				}
+				gpuCount += count
+			}
+			if gpuCount == 0 {
+				gpuCount, err = strconv.Atoi(resourceLabels[DefaultLabelPrefix+"/gpu.count"])
+				if err != nil {
+					t.Fatalf("invalid gpu.count: %v", err)
+				}
+			}
+			if gpuCount != len(devices) {
+				t.Fatalf("expected %d devices in the gpu.count labels, got %d", len(devices), gpuCount)
+			}
+
+			total := 0
+			for _, value := range labels {
+				count, err := strconv.Atoi(value)
+				if err != nil {
+					t.Fatalf("invalid NUMA count %q: %v", value, err)
+				}
+				total += count
+			}
+			if total != gpuCount {
+				t.Errorf("expected NUMA counts to add up to %d devices, got %d", gpuCount, total)
+			}
+		})
+	}
+}
+
+func TestMemoryUsedAndFreeLabels(t *testing.T) {
+	testCases := []struct {
+		description  string
+		devices      []*resource.MockDevice
+		expectedUsed string
+		expectedFree string
+	}{
+		{
+			description:  "single device",
+			devices:      []*resource.MockDevice{{Name: "MR-V100", TotalMemoryMB: 32768, UsedMemoryMB: 1024}},
+			expectedUsed: "1024",
+			expectedFree: "31744",
+		},
+		{
+			description: "most used device of a model",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768, UsedMemoryMB: 1024},
+				{Name: "MR-V100", TotalMemoryMB: 32768, UsedMemoryMB: 8192},
+			},
+			expectedUsed: "8192",
+			expectedFree: "24576",
+		},
+		{
+			description:  "used memory above the total",
+			devices:      []*resource.MockDevice{{Name: "MR-V100", TotalMemoryMB: 32768, UsedMemoryMB: 40000}},
+			expectedUsed: "40000",
+			expectedFree: "0",
+		},
+		{
+			description: "labels are absent if no device reports the used memory",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768, Errors: map[string]error{"GetUsedMemoryMB": errTest}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(tc.devices...)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			labels := generateLabels(t, l)
+			checkLabel(t, labels, "gpu.memory-used", tc.expectedUsed)
+			checkLabel(t, labels, "gpu.memory-free", tc.expectedFree)
+			if tc.expectedUsed == "" {
+				return
+			}
+
+			total, _ := strconv.ParseUint(labels[DefaultLabelPrefix+"/gpu.memory"], 10, 64)
+			used, _ := strconv.ParseUint(labels[DefaultLabelPrefix+"/gpu.memory-used"], 10, 64)
+			free, _ := strconv.ParseUint(labels[DefaultLabelPrefix+"/gpu.memory-free"], 10, 64)
+			if used <= total && free != total-used {
+				t.Errorf("expected gpu.memory-free = gpu.memory - gpu.memory-used, got %d != %d - %d", free, total, used)
+			}
+		})
+	}
+}
+
+func TestBoardPartNumberLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    Labels
+	}{
+		{
+			description: "devices agree",
+			devices:     []*resource.MockDevice{{BoardPartNumber: "900-2G500"}, {BoardPartNumber: "900-2G500"}},
+			expected:    Labels{DefaultLabelPrefix + "/gpu.board-part-number": "900-2G500"},
+		},
+		{
+			description: "devices differ",
+			devices:     []*resource.MockDevice{{BoardPartNumber: "900-2G500"}, {BoardPartNumber: "900-2G501"}},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.0.board-part-number": "900-2G500",
+				DefaultLabelPrefix + "/gpu.1.board-part-number": "900-2G501",
+			},
+		},
+		{
+			description: "unsupported device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetBoardPartNumber": resource.ErrNotSupported}},
+				{BoardPartNumber: "900-2G500"},
+			},
+			expected: Labels{DefaultLabelPrefix + "/gpu.board-part-number": "900-2G500"},
+		},
+		{
+			description: "label is absent if unsupported",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetBoardPartNumber": resource.ErrNotSupported}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newBoardPartNumberLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
+
+func TestDeviceMinorsLabeler(t *testing.T) {
+	testCases := []struct {
+		description     string
+		minors          []int
+		errors          []error
+		expected        string
+		expectedWarning bool
+	}{
+		{
+			description: "ordered minors",
+			minors:      []int{0, 1, 2, 3},
+			expected:    "0_1_2_3",
+		},
+		{
+			description: "unordered minors are sorted",
+			minors:      []int{3, 1, 0, 2},
+			expected:    "0_1_2_3",
+		},
+		{
+			description: "minors are sorted numerically",
+			minors:      []int{10, 2, 1},
+			expected:    "1_2_10",
+		},
+		{
+			description:     "failed device is skipped with a warning",
+			minors:          []int{1, 0},
+			errors:          []error{errTest, nil},
+			expected:        "0",
+			expectedWarning: true,
+		},
+		{
+			description:     "label is absent if no device reports it",
+			minors:          []int{0},
+			errors:          []error{errTest},
+			expectedWarning: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var devices []*resource.MockDevice
+			for i, minor := range tc.minors {
+				d := &resource.MockDevice{MinorNumber: minor}
+				if i < len(tc.errors) && tc.errors[i] != nil {
+					d.Errors = map[string]error{"GetMinorNumber": tc.errors[i]}
+				}
+				devices = append(devices, d)
+			}
+
+			// The value must not depend on the order of the devices.
+			for range 3 {
+				var labels Labels
+				logs := captureLogs(t, func() {
+					labels = generateLabels(t, newDeviceMinorsLabeler(DefaultLabelPrefix, asDevices(devices...)))
+				})
+				checkLabel(t, labels, "gpu.dev-minors", tc.expected)
+				if warned := strings.Contains(logs, "device minor numbers for"); warned != tc.expectedWarning {
+					t.Errorf("expected warning %v, got logs %q", tc.expectedWarning, logs)
+				}
+				slices.Reverse(devices)
+			}
+		})
+	}
+}
+
+func TestMemoryMBToGiB(t *testing.T) {
+	testCases := []struct {
+		mb       uint64
+		expected uint64
+	}{
+		{mb: 0, expected: 0},
+		{mb: 511, expected: 0},
+		{mb: 512, expected: 1},
+		{mb: 16384, expected: 16},
+		{mb: 32510, expected: 32},
+		{mb: 32768, expected: 32},
+		{mb: 33279, expected: 32},
+		{mb: 33280, expected: 33},
+	}
+
+	for _, tc := range testCases {
+		if gib := memoryMBToGiB(tc.mb); gib != tc.expected {
+			t.Errorf("memoryMBToGiB(%d): expected %d, got %d", tc.mb, tc.expected, gib)
+		}
+	}
+}
+
+func TestMemoryGiBLabel(t *testing.T) {
+	device := &resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32510}
+	l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(device)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	labels := generateLabels(t, l)
+	checkLabel(t, labels, "gpu.memory", "32510")
+	checkLabel(t, labels, "gpu.memory.gib", "32")
+}
+
+func TestComputeModeLabeler(t *testing.T) {
+	testCases := []struct {
+		description   string
+		modes         []resource.ComputeMode
+		errors        []error
+		expected      string
+		expectedMixed string
+	}{
+		{
+			description:   "default",
+			modes:         []resource.ComputeMode{resource.ComputeModeDefault, resource.ComputeModeDefault},
+			expected:      "default",
+			expectedMixed: "false",
+		},
+		{
+			description:   "exclusive-process",
+			modes:         []resource.ComputeMode{resource.ComputeModeExclusiveProcess},
+			expected:      "exclusive-process",
+			expectedMixed: "false",
+		},
+		{
+			description:   "prohibited",
+			modes:         []resource.ComputeMode{resource.ComputeModeProhibited},
+			expected:      "prohibited",
+			expectedMixed: "false",
+		},
+		{
+			description:   "mixed default and exclusive-process",
+			modes:         []resource.ComputeMode{resource.ComputeModeDefault, resource.ComputeModeExclusiveProcess},
+			expected:      "exclusive-process",
+			expectedMixed: "true",
+		},
+		{
+			description:   "mixed modes report the most restrictive",
+			modes:         []resource.ComputeMode{resource.ComputeModeProhibited, resource.ComputeModeDefault, resource.ComputeModeExclusiveProcess},
+			expected:      "prohibited",
+			expectedMixed: "true",
+		},
+		{
+			description:   "failed device is skipped",
+			modes:         []resource.ComputeMode{resource.ComputeModeProhibited, resource.ComputeModeDefault},
+			errors:        []error{errTest, nil},
+			expected:      "default",
+			expectedMixed: "false",
+		},
+		{
+			description: "labels are absent if no device reports them",
+			modes:       []resource.ComputeMode{resource.ComputeModeDefault},
+			errors:      []error{errTest},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var devices []*resource.MockDevice
+			for i, mode := range tc.modes {
+				d := &resource.MockDevice{ComputeMode: mode}
+				if i < len(tc.errors) && tc.errors[i] != nil {
+					d.Errors = map[string]error{"GetComputeMode": tc.errors[i]}
+				}
+				devices = append(devices, d)
+			}
+			labels := generateLabels(t, newComputeModeLabeler(DefaultLabelPrefix, asDevices(devices...)))
+			checkLabel(t, labels, "gpu.compute-mode", tc.expected)
+			checkLabel(t, labels, "gpu.compute-mode.mixed", tc.expectedMixed)
+		})
+	}
+}
+
+func TestTotalMemoryLabel(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "single device",
+			devices:     []*resource.MockDevice{{Name: "MR-V100", TotalMemoryMB: 32768}},
+			expected:    "32768",
+		},
+		{
+			description: "homogeneous devices",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+			},
+			expected: "98304",
+		},
+		{
+			description: "heterogeneous devices",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "BI-V150", TotalMemoryMB: 65536},
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+			},
+			expected: "131072",
+		},
+		{
+			description: "sum above the uint32 range",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 1 << 32},
+				{Name: "MR-V100", TotalMemoryMB: 1 << 32},
+			},
+			expected: "8589934592",
+		},
+		{
+			description: "label is absent without devices",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(tc.devices...)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkLabel(t, generateLabels(t, l), "gpu.memory.total", tc.expected)
+		})
+	}
+}
+
+func TestMixedProductLabels(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    map[string]string
+	}{
+		{
+			description: "two products",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "BI-V150", TotalMemoryMB: 65536},
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+			},
+			expected: map[string]string{
+				"gpu.product.0": "BI-V150",
+				"gpu.count.0":   "1",
+				"gpu.memory.0":  "65536",
+				"gpu.product.1": "MR-V100",
+				"gpu.count.1":   "2",
+				"gpu.memory.1":  "32768",
+				"gpu.product.2": "",
+				"gpu.product":   "MR-V100",
+				"gpu.count":     "2",
+				"gpu.memory":    "32768",
+			},
+		},
+		{
+			description: "three products",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "BI-V150", TotalMemoryMB: 65536},
+				{Name: "BI-V100", TotalMemoryMB: 32768},
+				{Name: "BI-V150", TotalMemoryMB: 65536},
+			},
+			expected: map[string]string{
+				"gpu.product.0": "BI-V100",
+				"gpu.count.0":   "1",
+				"gpu.product.1": "BI-V150",
+				"gpu.count.1":   "2",
+				"gpu.product.2": "MR-V100",
+				"gpu.count.2":   "1",
+				"gpu.product":   "BI-V150",
+				"gpu.count":     "2",
+				"gpu.memory":    "65536",
+			},
+		},
+		{
+			description: "ties go to the first product by name",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "BI-V150", TotalMemoryMB: 65536},
+			},
+			expected: map[string]string{
+				"gpu.product": "BI-V150",
+				"gpu.count":   "1",
+			},
+		},
+		{
+			description: "single product has no suffixed labels",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+			},
+			expected: map[string]string{
+				"gpu.product.0": "",
+				"gpu.count.0":   "",
+				"gpu.product":   "MR-V100",
+				"gpu.count":     "2",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			// The labels must not depend on the order of the devices.
+			for range 3 {
+				l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(tc.devices...)))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				labels := generateLabels(t, l)
+				for key, expected := range tc.expected {
+					checkLabel(t, labels, key, expected)
+				}
+				slices.Reverse(tc.devices)
+			}
+		})
+	}
+}
+
+// sysfsManager is a mock manager that reports sysfs as its discovery source.
+type sysfsManager struct {
+	*resource.MockManager
+}
+
+// DiscoverySource returns the sysfs discovery source
+func (m sysfsManager) DiscoverySource() string {
+	return resource.DiscoverySourceSysfs
+}
+
+func TestDiscoverySourceLabelAfterFallback(t *testing.T) {
+	primary := resource.NewMockManager(resource.WithError(errTest))
+	fallback := sysfsManager{resource.NewMockManager(resource.WithDevices(&resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32768}))}
+	manager := resource.NewFallbackManager(primary, fallback)
+
+	labels := generateLabels(t, NewIXDeviceLabeler(manager, newTestConfig()))
+	checkLabel(t, labels, "gpu.discovery-source", resource.DiscoverySourceSysfs)
+	checkLabel(t, labels, "gpu.product", "MR-V100")
+}
+
+func TestProductCountLabel(t *testing.T) {
+	testCases := []struct {
+		description     string
+		devices         []*resource.MockDevice
+		expected        string
+		expectedWarning bool
+	}{
+		{
+			description: "no devices",
+			expected:    "0",
+		},
+		{
+			description: "one product",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+			},
+			expected: "1",
+		},
+		{
+			description: "multiple products",
+			devices: []*resource.MockDevice{
+				{Name: "MR-V100", TotalMemoryMB: 32768},
+				{Name: "BI-V150", TotalMemoryMB: 65536},
+				{Name: "BI-V100", TotalMemoryMB: 32768},
+			},
+			expected:        "3",
+			expectedWarning: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var labels Labels
+			logs := captureLogs(t, func() {
+				l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(tc.devices...)))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				labels = generateLabels(t, l)
+			})
+			checkLabel(t, labels, "gpu.product-count", tc.expected)
+			if warned := strings.Contains(logs, "gpu.product-count"); warned != tc.expectedWarning {
+				t.Errorf("expected warning %v, got logs %q", tc.expectedWarning, logs)
+			}
+		})
+	}
+}
+
+func TestIXMLVersionLabeler(t *testing.T) {
+	testCases := []struct {
+		description   string
+		manager       *resource.MockManager
+		expectedFull  string
+		expectedMajor string
+	}{
+		{
+			description:   "library version",
+			manager:       resource.NewMockManager(resource.WithIXMLVersion("2.3.1")),
+			expectedFull:  "2.3.1",
+			expectedMajor: "2",
+		},
+		{
+			description:  "module version has no numeric major",
+			manager:      resource.NewMockManager(resource.WithIXMLVersion("v0.1.0")),
+			expectedFull: "v0.1.0",
+		},
+		{
+			description: "labels are absent if the version cannot be retrieved",
+			manager:     resource.NewMockManager(resource.WithError(errTest)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newIXMLVersionLabeler(DefaultLabelPrefix, tc.manager))
+			checkLabel(t, labels, "ix.ixml-version.full", tc.expectedFull)
+			checkLabel(t, labels, "ix.ixml-version.major", tc.expectedMajor)
+		})
+	}
+}
+
+func TestHealthLabeler(t *testing.T) {
+	testCases := []struct {
+		description       string
+		failing           []int
+		expectedHealthy   string
+		expectedUnhealthy string
+	}{
+		{
+			description:     "all devices healthy",
+			expectedHealthy: "true",
+		},
+		{
+			description:       "one of four devices failing",
+			failing:           []int{2},
+			expectedHealthy:   "false",
+			expectedUnhealthy: "1",
+		},
+		{
+			description:       "all devices failing",
+			failing:           []int{0, 1, 2, 3},
+			expectedHealthy:   "false",
+			expectedUnhealthy: "4",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var devices []*resource.MockDevice
+			for i := range 4 {
+				d := &resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32768}
+				if slices.Contains(tc.failing, i) {
+					d.Errors = map[string]error{"CheckHealth": errTest}
+				}
+				devices = append(devices, d)
+			}
+
+			// The failing devices do not prevent the labels of the other labelers.
+			l := NewIXDeviceLabeler(resource.NewMockManager(resource.WithDevices(devices...)), newTestConfig())
+			labels := generateLabels(t, l)
+			checkLabel(t, labels, "gpu.healthy", tc.expectedHealthy)
+			checkLabel(t, labels, "gpu.unhealthy-count", tc.expectedUnhealthy)
+			checkLabel(t, labels, "gpu.count", "4")
+		})
+	}
+}
+
+// cudaManager is a mock manager whose CUDA driver and runtime versions can fail separately.
+type cudaManager struct {
+	*resource.MockManager
+	driverErr  error
+	runtimeErr error
+}
+
+// GetCudaDriverVersion returns driverErr if set, and the version of the mock manager otherwise
+func (m cudaManager) GetCudaDriverVersion() (*uint, *uint, error) {
+	if m.driverErr != nil {
+		return nil, nil, m.driverErr
+	}
+	return m.MockManager.GetCudaDriverVersion()
+}
+
+// GetCudaRuntimeVersion returns runtimeErr if set, and the version of the mock manager otherwise
+func (m cudaManager) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	if m.runtimeErr != nil {
+		return nil, nil, m.runtimeErr
+	}
+	return m.MockManager.GetCudaRuntimeVersion()
+}
+
+func TestCudaVersionLabeler(t *testing.T) {
+	testCases := []struct {
+		description     string
+		driverErr       error
+		runtimeErr      error
+		expectedDriver  string
+		expectedRuntime string
+		expectError     bool
+	}{
+		{
+			description:     "both versions",
+			expectedDriver:  "11.4",
+			expectedRuntime: "10.2",
+		},
+		{
+			description:     "driver version not supported",
+			driverErr:       resource.ErrNotSupported,
+			expectedRuntime: "10.2",
+		},
+		{
+			description:    "runtime version fails",
+			runtimeErr:     errTest,
+			expectedDriver: "11.4",
+		},
+		{
+			description: "neither version supported",
+			driverErr:   resource.ErrNotSupported,
+			runtimeErr:  resource.ErrNotSupported,
+		},
+		{
+			description: "neither version retrieved",
+			driverErr:   errTest,
+			runtimeErr:  errTest,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := cudaManager{
+				MockManager: resource.NewMockManager(
+					resource.WithCudaDriverVersion(11, 4),
+					resource.WithCudaRuntimeVersion(10, 2),
+				),
+				driverErr:  tc.driverErr,
+				runtimeErr: tc.runtimeErr,
+			}
+			l, err := newCudaVersionLabeler(DefaultLabelPrefix, manager)
+			if tc.expectError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			labels := generateLabels(t, l)
+			checkLabel(t, labels, "cuda.driver-version.full", tc.expectedDriver)
+			checkLabel(t, labels, "cuda.runtime-version.full", tc.expectedRuntime)
+		})
+	}
+}
+
+func TestProductFullNameLabel(t *testing.T) {
+	testCases := []struct {
+		description string
+		device      *resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "vendor-qualified name",
+			device:      &resource.MockDevice{Name: "BI-V150S", FullName: "Iluvatar BI-V150S"},
+			expected:    "Iluvatar-BI-V150S",
+		},
+		{
+			description: "name without space",
+			device:      &resource.MockDevice{Name: "MR-V100X", FullName: "MR-V100X"},
+			expected:    "MR-V100X",
+		},
+		{
+			description: "name with multiple spaces",
+			device:      &resource.MockDevice{Name: "BI-V150S", FullName: "Iluvatar  BI-V150S   PCIe"},
+			expected:    "Iluvatar-BI-V150S-PCIe",
+		},
+		{
+			description: "name with unicode characters",
+			device:      &resource.MockDevice{Name: "BI-V150S", FullName: "天数智芯 BI-V150S"},
+			expected:    "BI-V150S",
+		},
+		{
+			description: "full name not supported",
+			device:      &resource.MockDevice{Name: "BI-V150S", Errors: map[string]error{"GetFullName": resource.ErrNotSupported}},
+			expected:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(tc.device)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			labels := generateLabels(t, l)
+			checkLabel(t, labels, "gpu.product.full", tc.expected)
+			checkLabel(t, labels, "gpu.product", tc.device.Name)
+		})
+	}
+}
+
+func TestProductSKU(t *testing.T) {
+	testCases := []struct {
+		name     string
+		memoryMB uint64
+		expected string
+	}{
+		{name: "BI-V150S", memoryMB: 32768, expected: "BI-V150S-32GB"},
+		{name: "BI-V150S", memoryMB: 32510, expected: "BI-V150S-32GB"},
+		{name: "BI-V150S", memoryMB: 65536, expected: "BI-V150S-64GB"},
+		{name: "MR-V100", memoryMB: 16384, expected: "MR-V100-16GB"},
+		{name: "MR-V100", memoryMB: 0, expected: "MR-V100-0GB"},
+	}
+
+	for _, tc := range testCases {
+		if sku := productSKU(tc.name, tc.memoryMB); sku != tc.expected {
+			t.Errorf("productSKU(%q, %d): expected %q, got %q", tc.name, tc.memoryMB, tc.expected, sku)
+		}
+	}
+}
+
+func TestProductSKULabel(t *testing.T) {
+	devices := []*resource.MockDevice{
+		{Name: "BI-V150S", TotalMemoryMB: 32768},
+		{Name: "BI-V150S", TotalMemoryMB: 32768},
+	}
+	l, err := newIXResourceLabeler(DefaultLabelPrefix, resource.NewMockManager(resource.WithDevices(devices...)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	labels := generateLabels(t, l)
+	checkLabel(t, labels, "gpu.product.sku", "BI-V150S-32GB")
+	if errs := ValidateLabels(labels); len(errs) != 0 {
+		t.Errorf("invalid labels: %v", errs)
+	}
+}
+
+func TestCircuitBreakerLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		failures    int
+		expected    string
+	}{
+		{
+			description: "closed circuit",
+			failures:    1,
+			expected:    "",
+		},
+		{
+			description: "open circuit",
+			failures:    2,
+			expected:    "true",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			breaker := resource.NewCircuitBreakerManager(resource.NewMockManager(resource.WithError(errTest)), 2, time.Hour)
+			for range tc.failures {
+				breaker.GetIXDriverVersion()
+			}
+			manager := resource.NewCachedManager(resource.NewFallbackManager(breaker, resource.NewMockManager()))
+
+			labels := generateLabels(t, newCircuitBreakerLabeler(DefaultLabelPrefix, manager))
+			checkLabel(t, labels, "gpu.circuit-open", tc.expected)
+		})
+	}
+}
@@ -17,12 +17,22 @@
 package label
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 
 	"gitee.com/deep-spark/ix-feature-discovery/pkg/config"
@@ -33,7 +43,7 @@ import (
 type Labels map[string]string
 
 // Labels method returns the labels as is, implementing the Labeler interface
-func (labels Labels) Labels() (Labels, error) {
+func (labels Labels) Labels(ctx context.Context) (Labels, error) {
 	return labels, nil
 }
 
@@ -41,13 +51,14 @@ func (labels Labels) Labels() (Labels, error) {
 type empty struct{}
 
 // Labels method returns an empty set of labels, implementing the Labeler interface
-func (manager empty) Labels() (Labels, error) {
+func (manager empty) Labels(ctx context.Context) (Labels, error) {
 	return nil, nil
 }
 
-// Labeler defines an interface for generating labels
+// Labeler defines an interface for generating labels. Labeling stops with the context's
+// error if the context is done.
 type Labeler interface {
-	Labels() (Labels, error)
+	Labels(ctx context.Context) (Labels, error)
 }
 
 // labelerList represents a list of labelers that itself implements the Labeler interface.
@@ -62,12 +73,15 @@ func Merge(labelers ...Labeler) Labeler {
 
 // Labels method returns the labels from a set of labelers. Labels later in the list
 // overwrite earlier labels.
-func (labelers labelerList) Labels() (Labels, error) {
+func (labelers labelerList) Labels(ctx context.Context) (Labels, error) {
 	allLabels := make(Labels)
 	for _, labeler := range labelers {
-		labels, err := labeler.Labels()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		labels, err := labeler.Labels(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("error generating labels: %v", err)
+			return nil, fmt.Errorf("error generating labels: %w", err)
 		}
 		for k, v := range labels {
 			allLabels[k] = v
@@ -77,14 +91,493 @@ func (labelers labelerList) Labels() (Labels, error) {
 	return allLabels, nil
 }
 
+// mergeOptions configures how the labels of a list of labelers are generated concurrently.
+type mergeOptions struct {
+	// maxConcurrency is the maximum number of labelers that generate labels concurrently.
+	maxConcurrency int
+	// bestEffort skips failed labelers rather than failing, and counts them in the label
+	// with the errorsKey key.
+	bestEffort bool
+	errorsKey  string
+}
+
+// newMergeOptions returns the merge options of the config.
+func newMergeOptions(config *config.Config) mergeOptions {
+	return mergeOptions{
+		maxConcurrency: *config.Flags.MaxLabelerConcurrency,
+		bestEffort:     *config.Flags.BestEffort,
+		errorsKey:      *config.Flags.LabelPrefix + "/gpu.discovery-errors",
+	}
+}
+
+// concurrentLabelerList represents a list of labelers that generate labels concurrently.
+type concurrentLabelerList struct {
+	labelers []Labeler
+	options  mergeOptions
+}
+
+// mergeConcurrently converts a set of labelers to a single composite labeler, whose labelers
+// generate labels concurrently as configured by the options.
+func mergeConcurrently(options mergeOptions, labelers ...Labeler) Labeler {
+	return &concurrentLabelerList{
+		labelers: labelers,
+		options:  options,
+	}
+}
+
+// Labels method returns the labels from a set of labelers. The labelers run concurrently,
+// up to maxConcurrency at a time, and labels later in the list overwrite earlier labels. The
+// first error cancels the labelers that have not completed, unless best-effort mode is
+// enabled, in which case failed labelers are skipped and counted in the errors label.
+func (l *concurrentLabelerList) Labels(ctx context.Context) (Labels, error) {
+	results := make([]Labels, len(l.labelers))
+	var failures atomic.Int64
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(l.options.maxConcurrency)
+	for i, labeler := range l.labelers {
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			labels, err := labeler.Labels(gctx)
+			if err != nil && l.options.bestEffort && gctx.Err() == nil {
+				klog.Warningf("Skipping labels of failed labeler: %v", err)
+				failures.Add(1)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("error generating labels: %w", err)
+			}
+			results[i] = labels
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	errorsKey := l.options.errorsKey
+	discoveryErrors := failures.Load()
+	allLabels := make(Labels)
+	for _, labels := range results {
+		for k, v := range labels {
+			allLabels[k] = v
+		}
+		// Failures of nested lists are added up rather than overwritten.
+		if n, err := strconv.ParseInt(labels[errorsKey], 10, 64); err == nil {
+			discoveryErrors += n
+		}
+	}
+	if l.options.bestEffort {
+		allLabels[errorsKey] = strconv.FormatInt(discoveryErrors, 10)
+	}
+
+	return allLabels, nil
+}
+
+// Diff compares two sets of labels. It returns the labels that are only in new, the labels
+// that are only in old, and the labels of new whose values differ from old.
+func Diff(old, new Labels) (added, removed, changed Labels) {
+	added, removed, changed = make(Labels), make(Labels), make(Labels)
+	for k, v := range new {
+		oldValue, ok := old[k]
+		switch {
+		case !ok:
+			added[k] = v
+		case oldValue != v:
+			changed[k] = v
+		}
+	}
+	for k, v := range old {
+		if _, ok := new[k]; !ok {
+			removed[k] = v
+		}
+	}
+	return added, removed, changed
+}
+
 // NewLabelers constructs the required labelers from the specified config
 func NewLabelers(manager resource.Manager, config *config.Config) (Labeler, error) {
-	deviceLabeler, err := NewIXDeviceLabeler(manager, config)
+	deviceLabeler := NewIXDeviceLabeler(manager, config)
+
+	include, err := compilePatterns(*config.Flags.LabelInclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label include pattern: %v", err)
+	}
+	exclude, err := compilePatterns(*config.Flags.LabelExclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label exclude pattern: %v", err)
+	}
+
+	transforms, err := newLabelTransforms(config.Transforms)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label transform: %v", err)
+	}
+
+	var labelers []Labeler
+
+	// The static labels come first, so that discovered labels take precedence.
+	if path := *config.Flags.StaticLabelsFile; path != "" {
+		staticLabeler, err := NewStaticLabeler(path)
+		if err != nil {
+			return nil, err
+		}
+		labelers = append(labelers, staticLabeler)
+	}
+
+	// The external labels come next, so that discovered labels take precedence.
+	if command := *config.Flags.ExternalLabelerCommand; command != "" {
+		execLabeler := NewExecLabeler(command, *config.Flags.ExternalLabelerArgs, time.Duration(*config.Flags.ExternalLabelerTimeout))
+		labelers = append(labelers, execLabeler)
+	}
+
+	labelers = append(labelers, NewFilterLabeler(NewTransformLabeler(deviceLabeler, transforms), include, exclude))
+
+	return mergeConcurrently(newMergeOptions(config), labelers...), nil
+}
+
+// LabelTransform changes the values of the labels whose keys match Key.
+type LabelTransform struct {
+	Key     *regexp.Regexp
+	Replace func(value string) string
+}
+
+// transformLabeler is a labeler that changes label values of an inner labeler.
+type transformLabeler struct {
+	inner      Labeler
+	transforms []LabelTransform
+}
+
+// NewTransformLabeler creates a labeler that applies the transforms to the labels of inner.
+// The transforms are applied in order, so that a transform sees the value changed by the
+// transforms before it.
+func NewTransformLabeler(inner Labeler, transforms []LabelTransform) Labeler {
+	if len(transforms) == 0 {
+		return inner
+	}
+	return &transformLabeler{
+		inner:      inner,
+		transforms: transforms,
+	}
+}
+
+// Labels method returns the labels of the inner labeler with the transformed values
+func (l *transformLabeler) Labels(ctx context.Context) (Labels, error) {
+	labels, err := l.inner.Labels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transformed := make(Labels, len(labels))
+	for k, v := range labels {
+		for _, t := range l.transforms {
+			if t.Key.MatchString(k) {
+				v = t.Replace(v)
+			}
+		}
+		transformed[k] = v
+	}
+	return transformed, nil
+}
+
+// newLabelTransforms creates the label transforms described by the config.
+func newLabelTransforms(configs []config.LabelTransform) ([]LabelTransform, error) {
+	var transforms []LabelTransform
+	for _, c := range configs {
+		key, err := regexp.Compile(c.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := regexp.Compile(c.Value)
+		if err != nil {
+			return nil, err
+		}
+		replacement := c.Replacement
+		transforms = append(transforms, LabelTransform{
+			Key: key,
+			Replace: func(v string) string {
+				return value.ReplaceAllString(v, replacement)
+			},
+		})
+	}
+	return transforms, nil
+}
+
+// filterLabeler is a labeler that removes labels of an inner labeler by their keys.
+type filterLabeler struct {
+	inner   Labeler
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewFilterLabeler creates a labeler that filters the labels of inner by their keys. If
+// include is not empty, only the keys matching at least one include pattern are kept. Keys
+// matching any exclude pattern are always removed.
+func NewFilterLabeler(inner Labeler, include, exclude []*regexp.Regexp) Labeler {
+	if len(include) == 0 && len(exclude) == 0 {
+		return inner
+	}
+	return &filterLabeler{
+		inner:   inner,
+		include: include,
+		exclude: exclude,
+	}
+}
+
+// Labels method returns the labels of the inner labeler that pass the filter
+func (l *filterLabeler) Labels(ctx context.Context) (Labels, error) {
+	labels, err := l.inner.Labels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make(Labels)
+	for k, v := range labels {
+		if len(l.include) > 0 && !matchesAny(l.include, k) {
+			continue
+		}
+		if matchesAny(l.exclude, k) {
+			continue
+		}
+		filtered[k] = v
+	}
+	return filtered, nil
+}
+
+// matchesAny returns whether s matches at least one of the patterns.
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePatterns compiles a list of regular expressions.
+func compilePatterns(exprs []string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, expr := range exprs {
+		p, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// staticLabeler is a labeler for the labels in a file.
+type staticLabeler struct {
+	path string
+
+	mu     sync.Mutex
+	labels Labels
+}
+
+// NewStaticLabeler creates a labeler for the labels in the file at path. The file holds a
+// key=value pair per line; blank lines and lines starting with '#' are ignored. An error is
+// returned if the file cannot be read or holds an invalid label. The file is read again
+// every time labels are generated, so that changes to it are applied.
+func NewStaticLabeler(path string) (Labeler, error) {
+	labels, err := readStaticLabels(path)
 	if err != nil {
-		return nil, fmt.Errorf("error creating labeler: %v", err)
+		return nil, err
+	}
+	return &staticLabeler{path: path, labels: labels}, nil
+}
+
+// Labels method reads the file again and returns its labels. If the file cannot be read or
+// holds an invalid label, e.g. while it is being edited, a warning is logged and the last
+// labels read are returned.
+func (l *staticLabeler) Labels(ctx context.Context) (Labels, error) {
+	labels, err := readStaticLabels(l.path)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		klog.Warningf("Failed to reload static labels, keeping the previous labels: %v", err)
+		return l.labels, nil
+	}
+	l.labels = labels
+	return labels, nil
+}
+
+// readStaticLabels reads the labels in the file at path.
+func readStaticLabels(path string) (Labels, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read static labels file: %v", err)
+	}
+
+	labels, err := parseLabels(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid static labels in %s: %w", path, err)
+	}
+
+	return labels, nil
+}
+
+// cachedLabeler is a labeler that reuses the labels of an inner labeler for a while.
+type cachedLabeler struct {
+	inner Labeler
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	labels    Labels
+	updatedAt time.Time
+}
+
+// NewCachedLabeler creates a labeler that returns the last labels of inner until ttl has
+// passed since they were generated, rather than calling inner every time. Errors are not
+// cached.
+func NewCachedLabeler(inner Labeler, ttl time.Duration) Labeler {
+	return &cachedLabeler{
+		inner: inner,
+		ttl:   ttl,
+	}
+}
+
+// Labels method returns the cached labels, or the labels of the inner labeler if they have
+// expired
+func (l *cachedLabeler) Labels(ctx context.Context) (Labels, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.labels != nil && time.Since(l.updatedAt) < l.ttl {
+		return l.labels, nil
 	}
 
-	return deviceLabeler, nil
+	labels, err := l.inner.Labels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if labels == nil {
+		labels = Labels{}
+	}
+	l.labels = labels
+	l.updatedAt = time.Now()
+
+	return labels, nil
+}
+
+// RetryConfig configures how a labeler is retried.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+	// Delay is the time to wait between attempts.
+	Delay time.Duration
+}
+
+// retryLabeler is a labeler that retries an inner labeler that fails.
+type retryLabeler struct {
+	inner  Labeler
+	config RetryConfig
+}
+
+// NewRetryLabeler creates a labeler that calls inner up to maxAttempts times, waiting delay
+// between attempts, until it succeeds. The error of the last attempt is returned if all
+// attempts fail. Attempts stop early if the context is done.
+func NewRetryLabeler(inner Labeler, maxAttempts int, delay time.Duration) Labeler {
+	return &retryLabeler{
+		inner: inner,
+		config: RetryConfig{
+			MaxAttempts: maxAttempts,
+			Delay:       delay,
+		},
+	}
+}
+
+// Labels method returns the labels of the first successful attempt of the inner labeler
+func (l *retryLabeler) Labels(ctx context.Context) (Labels, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		var labels Labels
+		labels, err = l.inner.Labels(ctx)
+		if err == nil || attempt >= l.config.MaxAttempts || ctx.Err() != nil {
+			return labels, err
+		}
+		klog.Warningf("Labeler failed (attempt %d/%d), retrying in %v: %v", attempt, l.config.MaxAttempts, l.config.Delay, err)
+		if serr := sleepContext(ctx, l.config.Delay); serr != nil {
+			return nil, err
+		}
+	}
+}
+
+// execLabeler is a labeler that runs an external command and parses its output.
+type execLabeler struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+// NewExecLabeler creates a labeler for the labels printed by an external command. The
+// command prints a key=value pair per line; blank lines and lines starting with '#' are
+// ignored. The command runs every time labels are generated, and is killed if it does not
+// exit within timeout. A timeout of zero or less only limits the command by the context.
+func NewExecLabeler(command string, args []string, timeout time.Duration) Labeler {
+	return &execLabeler{
+		command: command,
+		args:    args,
+		timeout: timeout,
+	}
+}
+
+// Labels method runs the command and returns the labels it prints. An error is returned if
+// the command fails, exits with a non-zero code or prints an invalid label.
+func (l *execLabeler) Labels(ctx context.Context) (Labels, error) {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, l.command, l.args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait for children of the command that keep its output open once it is killed.
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if stderr.Len() > 0 {
+		klog.V(4).Infof("Stderr of external labeler %s: %s", l.command, stderr.String())
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("external labeler %s did not complete in time: %w", l.command, ctx.Err())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("external labeler %s failed: %w", l.command, err)
+	}
+
+	labels, err := parseLabels(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid labels from external labeler %s: %w", l.command, err)
+	}
+
+	return labels, nil
+}
+
+// parseLabels parses a key=value pair per line, ignoring blank lines and lines starting
+// with '#'. An error is returned if a line or a label is invalid.
+func parseLabels(data []byte) (Labels, error) {
+	labels := make(Labels)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key=value: %q", i+1, line)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	if errs := ValidateLabels(labels); len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return labels, nil
 }
 
 // NewTimestampLabeler creates a new label manager for generating timestamp.
@@ -95,12 +588,12 @@ func NewTimestampLabeler(config *config.Config) Labeler {
 	}
 
 	return Labels{
-		nodeLabelPrefix + "/ix.timestamp": fmt.Sprintf("%d", time.Now().Unix()),
+		*config.Flags.LabelPrefix + "/ix.timestamp": fmt.Sprintf("%d", time.Now().Unix()),
 	}
 }
 
 // newMachineTypeLabeler creates a new labeler for machine type based on the provided path
-func newMachineTypeLabeler(machineTypePath string) (Labeler, error) {
+func newMachineTypeLabeler(prefix, machineTypePath string) (Labeler, error) {
 	machineType, err := getMachineType(machineTypePath)
 	if err != nil {
 		klog.Warningf("Error getting machine type from %v: %v", machineTypePath, err)
@@ -111,12 +604,30 @@ func newMachineTypeLabeler(machineTypePath string) (Labeler, error) {
 	klog.Infof("Successfully got machine type: %s", machineType)
 
 	l := Labels{
-		nodeLabelPrefix + "/gpu.machine": machineType,
+		prefix + "/gpu.machine": machineType,
 	}
 
 	return l, nil
 }
 
+// newKernelModuleVersionLabeler creates a labeler for the version of the loaded kernel
+// driver module, read from the specified path. The kernel module can differ from the
+// user-space driver reported by IXML, so both are labeled. The version is unknown if the
+// module is not loaded.
+func newKernelModuleVersionLabeler(prefix, versionPath string) Labeler {
+	version := labelValueUnknown
+	data, err := os.ReadFile(versionPath)
+	if err != nil {
+		klog.Warningf("Error getting kernel module version from %v, is the module loaded? %v", versionPath, err)
+	} else if v := sanitise(strings.TrimSpace(string(data))); v != "" {
+		version = v
+	}
+
+	return Labels{
+		prefix + "/ix.kernel-module-version": version,
+	}
+}
+
 // getMachineType reads the machine type from the specified path
 func getMachineType(path string) (string, error) {
 	if path == "" {
@@ -137,6 +648,84 @@ func sanitise(input string) string {
 	input = re.ReplaceAllString(input, "")
 	// Remove redundant blank spaces
 	sanitised = strings.Join(strings.Fields(input), "-")
+	// Label values must begin and end with an alphanumeric character
+	sanitised = strings.Trim(sanitised, "-_.")
 
 	return sanitised
 }
+
+// GPUPresent reports whether the labels record GPUs as present on the node.
+func GPUPresent(config *config.Config, labels Labels) bool {
+	return labels[*config.Flags.LabelPrefix+"/gpu.present"] == "true"
+}
+
+// ValidateLabels checks every label key and value against the Kubernetes label syntax. A
+// key must be a qualified name with an optional DNS subdomain prefix, and a value must be
+// at most 63 characters of alphanumerics, '-', '_' and '.'. An error is returned for each
+// invalid key or value.
+func ValidateLabels(labels Labels) []error {
+	var errs []error
+	for _, k := range sortedKeys(labels) {
+		if msgs := validation.IsQualifiedName(k); len(msgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid label key %q: %s", k, strings.Join(msgs, "; ")))
+		}
+		if msgs := validation.IsValidLabelValue(labels[k]); len(msgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid value %q for label %q: %s", labels[k], k, strings.Join(msgs, "; ")))
+		}
+	}
+	return errs
+}
+
+// sortedKeys returns the keys of the labels in sorted order.
+func sortedKeys(labels Labels) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// joinLabelValues sorts the given values and joins them into a single label value.
+// An error is returned if the result exceeds the maximum label value length.
+func joinLabelValues(values []string) (string, error) {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+
+	joined := strings.Join(sorted, labelValueListSep)
+	if len(joined) > maxLabelValueLength {
+		return "", fmt.Errorf("joined label value must be %v characters or less: %v", maxLabelValueLength, joined)
+	}
+
+	return joined, nil
+}
+
+// splitLabelValues sorts the given values and joins them into as few label values as
+// possible, each at most the maximum label value length. An error is returned if a single
+// value exceeds the maximum label value length.
+func splitLabelValues(values []string) ([]string, error) {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+
+	var joined []string
+	var current string
+	for _, v := range sorted {
+		if len(v) > maxLabelValueLength {
+			return nil, fmt.Errorf("label value must be %v characters or less: %v", maxLabelValueLength, v)
+		}
+		switch {
+		case current == "":
+			current = v
+		case len(current)+len(labelValueListSep)+len(v) <= maxLabelValueLength:
+			current += labelValueListSep + v
+		default:
+			joined = append(joined, current)
+			current = v
+		}
+	}
+	if current != "" {
+		joined = append(joined, current)
+	}
+
+	return joined, nil
+}
@@ -0,0 +1,1139 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/config"
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// ptr returns a reference to whatever value is passed into it
+func ptr[T any](x T) *T {
+	return &x
+}
+
+// newTestConfig returns a config with the default flags, changed by the options.
+func newTestConfig(opts ...func(*config.Flags)) *config.Config {
+	flags := &config.Flags{
+		NoTimestamp:             ptr(true),
+		SleepInterval:           ptr(config.Duration(60 * time.Second)),
+		SleepJitterPercent:      ptr(0),
+		CycleTimeout:            ptr(config.Duration(0)),
+		MaxLabelerConcurrency:   ptr(4),
+		IXMLTimeout:             ptr(config.Duration(0)),
+		CircuitBreakerThreshold: ptr(0),
+		CircuitBreakerCooldown:  ptr(config.Duration(0)),
+		HardwareLabelsTTL:       ptr(config.Duration(time.Hour)),
+		LabelerRetryAttempts:    ptr(1),
+		LabelerRetryDelay:       ptr(config.Duration(0)),
+		OutputFile:              ptr(""),
+		MachineTypeFile:         ptr(""),
+		DevicePluginConfigFile:  ptr(""),
+		VideoCapabilitiesFile:   ptr(""),
+		KernelModuleVersionFile: ptr(""),
+		StaticLabelsFile:        ptr(""),
+		ExternalLabelerCommand:  ptr(""),
+		ExternalLabelerArgs:     ptr([]string{}),
+		ExternalLabelerTimeout:  ptr(config.Duration(0)),
+		NoECCErrorLabels:        ptr(false),
+		DryRun:                  ptr(false),
+		BestEffort:              ptr(false),
+		LeaderElect:             ptr(false),
+		WebhookURL:              ptr(""),
+		WebhookTimeout:          ptr(config.Duration(0)),
+		MetricsPort:             ptr(0),
+		NoPartitioningLabels:    ptr(false),
+		LabelPrefix:             ptr(DefaultLabelPrefix),
+		LabelInclude:            ptr([]string{}),
+		LabelExclude:            ptr([]string{}),
+		NoDriverLabels:          ptr(false),
+		NoCudaLabels:            ptr(false),
+		NoGPULabels:             ptr(false),
+		NoMachineLabels:         ptr(false),
+		KubeRetryAttempts:       ptr(1),
+		KubeRetryBaseDelay:      ptr(config.Duration(0)),
+		UseSysfs:                ptr(false),
+		PerDeviceLabels:         ptr(false),
+	}
+	for _, opt := range opts {
+		opt(flags)
+	}
+	return &config.Config{Flags: flags}
+}
+
+// countingLabeler is a labeler that counts how often it generates labels, and fails with
+// err the first failures times.
+type countingLabeler struct {
+	labels   Labels
+	err      error
+	failures int
+	calls    int
+}
+
+// Labels method counts the call and returns the labels or error of the labeler
+func (l *countingLabeler) Labels(ctx context.Context) (Labels, error) {
+	l.calls++
+	if l.calls <= l.failures {
+		return nil, l.err
+	}
+	return l.labels, nil
+}
+
+func TestCachedLabeler(t *testing.T) {
+	testCases := []struct {
+		description   string
+		ttl           time.Duration
+		wait          time.Duration
+		err           error
+		expectedCalls int
+	}{
+		{
+			description:   "second call within the ttl is cached",
+			ttl:           time.Hour,
+			expectedCalls: 1,
+		},
+		{
+			description:   "second call after the ttl calls inner again",
+			ttl:           time.Millisecond,
+			wait:          10 * time.Millisecond,
+			expectedCalls: 2,
+		},
+		{
+			description:   "errors are not cached",
+			ttl:           time.Hour,
+			err:           errors.New("query failed"),
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			inner := &countingLabeler{labels: Labels{"a": "b"}, err: tc.err}
+			if tc.err != nil {
+				inner.failures = 2
+			}
+			l := NewCachedLabeler(inner, tc.ttl)
+
+			for i := 0; i < 2; i++ {
+				labels, err := l.Labels(context.Background())
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tc.err == nil && !reflect.DeepEqual(labels, inner.labels) {
+					t.Errorf("expected labels %v, got %v", inner.labels, labels)
+				}
+				time.Sleep(tc.wait)
+			}
+
+			if inner.calls != tc.expectedCalls {
+				t.Errorf("expected %d calls of inner, got %d", tc.expectedCalls, inner.calls)
+			}
+		})
+	}
+}
+
+func TestIXDeviceLabelerCachesStaticAttributes(t *testing.T) {
+	testCases := []struct {
+		description    string
+		ttl            time.Duration
+		expectedSerial string
+		expectedTemp   string
+	}{
+		{
+			description:    "static attributes are reused within the ttl",
+			ttl:            time.Hour,
+			expectedSerial: "serial-1",
+			expectedTemp:   "70",
+		},
+		{
+			description:    "static attributes are queried every cycle without a ttl",
+			ttl:            0,
+			expectedSerial: "serial-2",
+			expectedTemp:   "70",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			device := &resource.MockDevice{
+				Name:               "MR-V100",
+				TotalMemoryMB:      32768,
+				Serial:             "serial-1",
+				TemperatureCelsius: 50,
+			}
+			manager := resource.NewMockManager(resource.WithDevices(device))
+			l := NewIXDeviceLabeler(manager, newTestConfig(func(f *config.Flags) {
+				f.HardwareLabelsTTL = ptr(config.Duration(tc.ttl))
+			}))
+
+			if _, err := l.Labels(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			device.Serial = "serial-2"
+			device.TemperatureCelsius = 70
+			labels, err := l.Labels(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if serial := labels[DefaultLabelPrefix+"/gpu.serial"]; serial != tc.expectedSerial {
+				t.Errorf("expected serial %q, got %q", tc.expectedSerial, serial)
+			}
+			if temp := labels[DefaultLabelPrefix+"/gpu.temperature"]; temp != tc.expectedTemp {
+				t.Errorf("expected temperature %q, got %q", tc.expectedTemp, temp)
+			}
+		})
+	}
+}
+
+func TestRetryLabeler(t *testing.T) {
+	errQuery := errors.New("query failed")
+	testCases := []struct {
+		description    string
+		failures       int
+		maxAttempts    int
+		expectedCalls  int
+		expectedError  error
+		expectedLabels Labels
+	}{
+		{
+			description:    "success is not retried",
+			maxAttempts:    3,
+			expectedCalls:  1,
+			expectedLabels: Labels{"a": "b"},
+		},
+		{
+			description:    "transient failures are retried",
+			failures:       2,
+			maxAttempts:    3,
+			expectedCalls:  3,
+			expectedLabels: Labels{"a": "b"},
+		},
+		{
+			description:   "last error is returned once all attempts fail",
+			failures:      3,
+			maxAttempts:   3,
+			expectedCalls: 3,
+			expectedError: errQuery,
+		},
+		{
+			description:   "single attempt is not retried",
+			failures:      1,
+			maxAttempts:   1,
+			expectedCalls: 1,
+			expectedError: errQuery,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			inner := &countingLabeler{labels: Labels{"a": "b"}, err: errQuery, failures: tc.failures}
+			l := NewRetryLabeler(inner, tc.maxAttempts, time.Millisecond)
+
+			labels, err := l.Labels(context.Background())
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error %v, got %v", tc.expectedError, err)
+			}
+			if !reflect.DeepEqual(labels, tc.expectedLabels) {
+				t.Errorf("expected labels %v, got %v", tc.expectedLabels, labels)
+			}
+			if inner.calls != tc.expectedCalls {
+				t.Errorf("expected %d calls of inner, got %d", tc.expectedCalls, inner.calls)
+			}
+		})
+	}
+}
+
+func TestRetryLabelerStopsWhenContextIsDone(t *testing.T) {
+	inner := &countingLabeler{err: errors.New("query failed"), failures: 3}
+	l := NewRetryLabeler(inner, 3, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Labels(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected 1 call of inner, got %d", inner.calls)
+	}
+}
+
+// flakyManager is a manager whose driver version query fails the first failures times.
+type flakyManager struct {
+	resource.Manager
+	failures int
+	calls    int
+}
+
+// GetIXDriverVersion counts the call and fails the first failures calls
+func (m *flakyManager) GetIXDriverVersion() (string, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return "", errors.New("transient IXML error")
+	}
+	return m.Manager.GetIXDriverVersion()
+}
+
+func TestIXDeviceLabelerRetriesIXMLQueries(t *testing.T) {
+	testCases := []struct {
+		description   string
+		attempts      int
+		expectedError bool
+		expectedCalls int
+	}{
+		{
+			description:   "transient failure is retried",
+			attempts:      3,
+			expectedCalls: 2,
+		},
+		{
+			description:   "failure without retries fails the labeling",
+			attempts:      1,
+			expectedError: true,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			device := &resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32768}
+			flaky := &flakyManager{
+				Manager:  resource.NewMockManager(resource.WithDevices(device), resource.WithDriverVersion("4.1.0")),
+				failures: 1,
+			}
+			l := NewIXDeviceLabeler(resource.NewCachedManager(flaky), newTestConfig(func(f *config.Flags) {
+				f.LabelerRetryAttempts = ptr(tc.attempts)
+			}))
+
+			labels, err := l.Labels(context.Background())
+			if tc.expectedError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if v := labels[DefaultLabelPrefix+"/ix.driver-version.full"]; v != "4.1.0" {
+					t.Errorf("expected driver version 4.1.0, got %q", v)
+				}
+			}
+			if flaky.calls != tc.expectedCalls {
+				t.Errorf("expected %d driver version queries, got %d", tc.expectedCalls, flaky.calls)
+			}
+		})
+	}
+}
+
+func TestLabelCategoryFlags(t *testing.T) {
+	isDriverLabel := func(key string) bool { return strings.HasPrefix(key, "ix.") }
+	isCudaLabel := func(key string) bool { return strings.HasPrefix(key, "cuda.") }
+	isMachineLabel := func(key string) bool { return key == "gpu.machine" }
+	isGPULabel := func(key string) bool {
+		return strings.HasPrefix(key, "gpu.") && key != "gpu.present" && !isMachineLabel(key)
+	}
+
+	testCases := []struct {
+		description string
+		option      func(*config.Flags)
+		isExcluded  func(key string) bool
+	}{
+		{
+			description: "no driver labels",
+			option:      func(f *config.Flags) { f.NoDriverLabels = ptr(true) },
+			isExcluded:  isDriverLabel,
+		},
+		{
+			description: "no cuda labels",
+			option:      func(f *config.Flags) { f.NoCudaLabels = ptr(true) },
+			isExcluded:  isCudaLabel,
+		},
+		{
+			description: "no gpu labels",
+			option:      func(f *config.Flags) { f.NoGPULabels = ptr(true) },
+			isExcluded:  isGPULabel,
+		},
+		{
+			description: "no machine labels",
+			option:      func(f *config.Flags) { f.NoMachineLabels = ptr(true) },
+			isExcluded:  isMachineLabel,
+		},
+	}
+
+	generate := func(t *testing.T, opts ...func(*config.Flags)) Labels {
+		t.Helper()
+		manager := resource.NewMockManager(
+			resource.WithDevices(&resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32768}),
+			resource.WithDriverVersion("4.1.0"),
+			resource.WithCudaDriverVersion(10, 2),
+			resource.WithCudaRuntimeVersion(10, 2),
+		)
+		l, err := NewLabelers(manager, newTestConfig(opts...))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return generateLabels(t, l)
+	}
+	all := generate(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generate(t, tc.option)
+			excluded := 0
+			for key := range all {
+				_, present := labels[key]
+				if tc.isExcluded(strings.TrimPrefix(key, DefaultLabelPrefix+"/")) {
+					excluded++
+					if present {
+						t.Errorf("expected label %s to be excluded", key)
+					}
+				} else if !present {
+					t.Errorf("expected label %s to be present", key)
+				}
+			}
+			if excluded == 0 {
+				t.Error("expected some labels to be excluded")
+			}
+			if len(labels) != len(all)-excluded {
+				t.Errorf("expected %d labels, got %d", len(all)-excluded, len(labels))
+			}
+		})
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	testCases := []struct {
+		description    string
+		labels         Labels
+		expectedErrors int
+	}{
+		{
+			description: "valid labels",
+			labels: Labels{
+				"iluvatar.ai/gpu.product":         "MR-V100",
+				"iluvatar.ai/gpu.pci.device-id":   "0x0001",
+				"iluvatar.ai/gpu.product.full_id": "a.b_c-d",
+			},
+		},
+		{
+			description: "empty value",
+			labels:      Labels{"iluvatar.ai/gpu.product": ""},
+		},
+		{
+			description: "63 character value",
+			labels:      Labels{"iluvatar.ai/gpu.product": strings.Repeat("a", 63)},
+		},
+		{
+			description:    "64 character value",
+			labels:         Labels{"iluvatar.ai/gpu.product": strings.Repeat("a", 64)},
+			expectedErrors: 1,
+		},
+		{
+			description:    "value with illegal characters",
+			labels:         Labels{"iluvatar.ai/gpu.product": "MR V100"},
+			expectedErrors: 1,
+		},
+		{
+			description:    "value starting with a non-alphanumeric",
+			labels:         Labels{"iluvatar.ai/gpu.product": "-MR-V100"},
+			expectedErrors: 1,
+		},
+		{
+			description: "63 character key name",
+			labels:      Labels{"iluvatar.ai/" + strings.Repeat("a", 63): "true"},
+		},
+		{
+			description:    "64 character key name",
+			labels:         Labels{"iluvatar.ai/" + strings.Repeat("a", 64): "true"},
+			expectedErrors: 1,
+		},
+		{
+			description:    "key with illegal characters",
+			labels:         Labels{"iluvatar.ai/gpu product": "true"},
+			expectedErrors: 1,
+		},
+		{
+			description:    "key with an invalid prefix",
+			labels:         Labels{"Iluvatar_AI/gpu.product": "MR-V100"},
+			expectedErrors: 1,
+		},
+		{
+			description:    "key prefix longer than 253 characters",
+			labels:         Labels{strings.Repeat("a.", 127) + "ai/gpu.product": "MR-V100"},
+			expectedErrors: 1,
+		},
+		{
+			description: "all violations are reported",
+			labels: Labels{
+				"iluvatar.ai/gpu product": "MR V100",
+				"iluvatar.ai/gpu.count":   strings.Repeat("1", 64),
+			},
+			expectedErrors: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			if errs := ValidateLabels(tc.labels); len(errs) != tc.expectedErrors {
+				t.Errorf("expected %d errors, got %v", tc.expectedErrors, errs)
+			}
+		})
+	}
+}
+
+func TestKernelModuleVersionLabeler(t *testing.T) {
+	dir := t.TempDir()
+	loaded := filepath.Join(dir, "version")
+	if err := os.WriteFile(loaded, []byte("4.1.0\n"), 0644); err != nil {
+		t.Fatalf("failed to write version file: %v", err)
+	}
+
+	testCases := []struct {
+		description string
+		path        string
+		expected    string
+	}{
+		{
+			description: "loaded module",
+			path:        loaded,
+			expected:    "4.1.0",
+		},
+		{
+			description: "module not loaded",
+			path:        filepath.Join(dir, "missing", "version"),
+			expected:    labelValueUnknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newKernelModuleVersionLabeler(DefaultLabelPrefix, tc.path))
+			checkLabel(t, labels, "ix.kernel-module-version", tc.expected)
+		})
+	}
+}
+
+func TestMissingKernelModuleDoesNotBlockOtherLabels(t *testing.T) {
+	manager := resource.NewMockManager(
+		resource.WithDevices(&resource.MockDevice{Name: "MR-V100", TotalMemoryMB: 32768}),
+		resource.WithDriverVersion("4.1.0"),
+	)
+	l := NewIXDeviceLabeler(manager, newTestConfig(func(f *config.Flags) {
+		f.KernelModuleVersionFile = ptr(filepath.Join(t.TempDir(), "missing"))
+	}))
+
+	labels := generateLabels(t, l)
+	checkLabel(t, labels, "ix.kernel-module-version", labelValueUnknown)
+	checkLabel(t, labels, "ix.driver-version.full", "4.1.0")
+	checkLabel(t, labels, "gpu.product", "MR-V100")
+}
+
+// sleepingLabeler is a labeler that sleeps for delay before returning its labels, and
+// records the highest number of sleepingLabelers that ran at the same time.
+type sleepingLabeler struct {
+	labels  Labels
+	delay   time.Duration
+	running *atomic.Int32
+	peak    *atomic.Int32
+}
+
+// Labels method sleeps for the delay and returns the labels of the labeler
+func (l *sleepingLabeler) Labels(ctx context.Context) (Labels, error) {
+	if l.running != nil {
+		n := l.running.Add(1)
+		defer l.running.Add(-1)
+		for {
+			peak := l.peak.Load()
+			if n <= peak || l.peak.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+	}
+	time.Sleep(l.delay)
+	return l.labels, nil
+}
+
+// newSleepingLabelers creates n sleeping labelers that each return one label.
+func newSleepingLabelers(n int, delay time.Duration, running, peak *atomic.Int32) []Labeler {
+	var labelers []Labeler
+	for i := range n {
+		labelers = append(labelers, &sleepingLabeler{
+			labels:  Labels{fmt.Sprintf("label-%d", i): "true"},
+			delay:   delay,
+			running: running,
+			peak:    peak,
+		})
+	}
+	return labelers
+}
+
+func TestConcurrentLabelerList(t *testing.T) {
+	testCases := []struct {
+		description    string
+		maxConcurrency int
+	}{
+		{description: "sequential", maxConcurrency: 1},
+		{description: "limited", maxConcurrency: 3},
+		{description: "unlimited", maxConcurrency: 10},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var running, peak atomic.Int32
+			labelers := newSleepingLabelers(10, 10*time.Millisecond, &running, &peak)
+			l := mergeConcurrently(mergeOptions{maxConcurrency: tc.maxConcurrency}, labelers...)
+
+			labels := generateLabels(t, l)
+			if len(labels) != 10 {
+				t.Errorf("expected 10 labels, got %v", labels)
+			}
+			if int(peak.Load()) > tc.maxConcurrency {
+				t.Errorf("expected at most %d concurrent labelers, got %d", tc.maxConcurrency, peak.Load())
+			}
+			if tc.maxConcurrency > 1 && peak.Load() < 2 {
+				t.Errorf("expected labelers to run concurrently, got a peak of %d", peak.Load())
+			}
+		})
+	}
+}
+
+func TestConcurrentLabelerListOrder(t *testing.T) {
+	// The first labeler completes last, but the labels of later labelers still take precedence.
+	l := mergeConcurrently(mergeOptions{maxConcurrency: 2},
+		&sleepingLabeler{labels: Labels{"key": "first"}, delay: 20 * time.Millisecond},
+		Labels{"key": "second"},
+	)
+
+	labels := generateLabels(t, l)
+	if labels["key"] != "second" {
+		t.Errorf("expected the label of the later labeler, got %q", labels["key"])
+	}
+}
+
+func TestConcurrentLabelerListError(t *testing.T) {
+	l := mergeConcurrently(mergeOptions{maxConcurrency: 2},
+		Labels{"key": "value"},
+		&countingLabeler{err: errors.New("labeler error"), failures: 1},
+	)
+
+	if _, err := l.Labels(context.Background()); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// BenchmarkLabelerList generates labels from 10 labelers that each sleep for 10ms,
+// sequentially and concurrently.
+func BenchmarkLabelerList(b *testing.B) {
+	labelers := newSleepingLabelers(10, 10*time.Millisecond, nil, nil)
+	benchmarks := []struct {
+		description string
+		labeler     Labeler
+	}{
+		{description: "sequential", labeler: Merge(labelers...)},
+		{description: "concurrent", labeler: mergeConcurrently(mergeOptions{maxConcurrency: len(labelers)}, labelers...)},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.description, func(b *testing.B) {
+			for range b.N {
+				if _, err := bm.labeler.Labels(context.Background()); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterLabeler(t *testing.T) {
+	inner := Labels{
+		"iluvatar.ai/gpu.product":     "MR-V100",
+		"iluvatar.ai/gpu.count":       "2",
+		"iluvatar.ai/gpu.temperature": "45",
+		"iluvatar.ai/ix.driver":       "4.1.0",
+	}
+
+	testCases := []struct {
+		description string
+		include     []string
+		exclude     []string
+		expected    Labels
+	}{
+		{
+			description: "no patterns",
+			expected:    inner,
+		},
+		{
+			description: "include only",
+			include:     []string{`/gpu\.`},
+			expected: Labels{
+				"iluvatar.ai/gpu.product":     "MR-V100",
+				"iluvatar.ai/gpu.count":       "2",
+				"iluvatar.ai/gpu.temperature": "45",
+			},
+		},
+		{
+			description: "several include patterns",
+			include:     []string{`gpu\.product$`, `/ix\.`},
+			expected: Labels{
+				"iluvatar.ai/gpu.product": "MR-V100",
+				"iluvatar.ai/ix.driver":   "4.1.0",
+			},
+		},
+		{
+			description: "exclude only",
+			exclude:     []string{`temperature`},
+			expected: Labels{
+				"iluvatar.ai/gpu.product": "MR-V100",
+				"iluvatar.ai/gpu.count":   "2",
+				"iluvatar.ai/ix.driver":   "4.1.0",
+			},
+		},
+		{
+			description: "exclude takes precedence over include",
+			include:     []string{`/gpu\.`},
+			exclude:     []string{`temperature`, `count`},
+			expected: Labels{
+				"iluvatar.ai/gpu.product": "MR-V100",
+			},
+		},
+		{
+			description: "include matching nothing",
+			include:     []string{`^example\.com/`},
+			expected:    Labels{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			include, err := compilePatterns(tc.include)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			exclude, err := compilePatterns(tc.exclude)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			labels := generateLabels(t, NewFilterLabeler(inner, include, exclude))
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
+
+func TestNewLabelersRejectsInvalidPatterns(t *testing.T) {
+	_, err := NewLabelers(resource.NewMockManager(), newTestConfig(func(f *config.Flags) {
+		f.LabelExclude = ptr([]string{"gpu.("})
+	}))
+	if err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestExecLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		script      string
+		args        []string
+		timeout     time.Duration
+		expected    Labels
+		expectError bool
+	}{
+		{
+			description: "labels are parsed",
+			script:      "echo '# comment'\necho\necho 'example.com/rack=r1'\necho 'example.com/row = 4'\n",
+			expected: Labels{
+				"example.com/rack": "r1",
+				"example.com/row":  "4",
+			},
+		},
+		{
+			description: "arguments are passed",
+			script:      "echo \"example.com/$1=$2\"\n",
+			args:        []string{"rack", "r2"},
+			expected:    Labels{"example.com/rack": "r2"},
+		},
+		{
+			description: "stderr is ignored",
+			script:      "echo 'warning' >&2\necho 'example.com/rack=r1'\n",
+			expected:    Labels{"example.com/rack": "r1"},
+		},
+		{
+			description: "non-zero exit code",
+			script:      "echo 'example.com/rack=r1'\nexit 3\n",
+			expectError: true,
+		},
+		{
+			description: "invalid line",
+			script:      "echo 'rack'\n",
+			expectError: true,
+		},
+		{
+			description: "invalid label value",
+			script:      "echo 'example.com/rack=rack 1'\n",
+			expectError: true,
+		},
+		{
+			description: "command exceeding the timeout",
+			script:      "sleep 10\n",
+			timeout:     100 * time.Millisecond,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			script := filepath.Join(t.TempDir(), "labeler.sh")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\n"+tc.script), 0755); err != nil {
+				t.Fatalf("failed to write script: %v", err)
+			}
+
+			labels, err := NewExecLabeler(script, tc.args, tc.timeout).Labels(context.Background())
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got labels %v", labels)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
+
+func TestSanitise(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"BI-V150S", "BI-V150S"},
+		{"Iluvatar BI-V150S", "Iluvatar-BI-V150S"},
+		{"Iluvatar   BI-V150S  PCIe", "Iluvatar-BI-V150S-PCIe"},
+		{"天数智芯 BI-V150S", "BI-V150S"},
+		{"Iluvatar® BI-V150S™", "Iluvatar-BI-V150S"},
+		{" -BI-V150S. ", "BI-V150S"},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if sanitised := sanitise(tc.input); sanitised != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, sanitised)
+			}
+		})
+	}
+}
+
+func TestZeroDeviceLabels(t *testing.T) {
+	machineTypeFile := filepath.Join(t.TempDir(), "product_name")
+	if err := os.WriteFile(machineTypeFile, []byte("ServerX 1000\n"), 0644); err != nil {
+		t.Fatalf("failed to write machine type file: %v", err)
+	}
+
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    Labels
+	}{
+		{
+			description: "no devices",
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.present": "false",
+				DefaultLabelPrefix + "/gpu.machine": "ServerX-1000",
+			},
+		},
+		{
+			description: "devices",
+			devices:     []*resource.MockDevice{{Name: "MR-V100", TotalMemoryMB: 32768}},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.present": "true",
+				DefaultLabelPrefix + "/gpu.machine": "ServerX-1000",
+				DefaultLabelPrefix + "/gpu.product": "MR-V100",
+				DefaultLabelPrefix + "/gpu.count":   "1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			conf := newTestConfig(func(f *config.Flags) { f.MachineTypeFile = ptr(machineTypeFile) })
+			manager := resource.NewMockManager(resource.WithDevices(tc.devices...))
+			l, err := NewLabelers(manager, conf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			labels := generateLabels(t, l)
+			for key, expected := range tc.expected {
+				if labels[key] != expected {
+					t.Errorf("expected label %s=%q, got %q", key, expected, labels[key])
+				}
+			}
+			if len(tc.devices) == 0 && len(labels) != len(tc.expected) {
+				t.Errorf("expected only the labels %v, got %v", tc.expected, labels)
+			}
+			if GPUPresent(conf, labels) != (len(tc.devices) > 0) {
+				t.Errorf("expected GPUPresent to be %v", len(tc.devices) > 0)
+			}
+		})
+	}
+}
+
+// slowInitManager is a mock manager whose initialization sleeps for delay.
+type slowInitManager struct {
+	*resource.MockManager
+	delay time.Duration
+}
+
+// Init sleeps for the delay of the manager before initializing it
+func (m *slowInitManager) Init() error {
+	time.Sleep(m.delay)
+	return m.MockManager.Init()
+}
+
+func TestLabelersInitTimeout(t *testing.T) {
+	slow := &slowInitManager{
+		MockManager: resource.NewMockManager(resource.WithDevices(&resource.MockDevice{Name: "MR-V100"})),
+		delay:       time.Second,
+	}
+	l, err := NewLabelers(resource.NewTimeoutManager(slow, 10*time.Millisecond), newTestConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = l.Labels(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected an error wrapping context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	testCases := []struct {
+		description string
+		data        string
+		expected    Labels
+		expectError bool
+	}{
+		{
+			description: "labels",
+			data:        "example.com/rack=r1\nexample.com/region = eu-west \n",
+			expected: Labels{
+				"example.com/rack":   "r1",
+				"example.com/region": "eu-west",
+			},
+		},
+		{
+			description: "comments and blank lines are ignored",
+			data:        "# site labels\n\n  # indented comment\nexample.com/rack=r1\n",
+			expected:    Labels{"example.com/rack": "r1"},
+		},
+		{
+			description: "empty value",
+			data:        "example.com/rack=\n",
+			expected:    Labels{"example.com/rack": ""},
+		},
+		{
+			description: "empty file",
+			data:        "",
+			expected:    Labels{},
+		},
+		{
+			description: "line without separator",
+			data:        "example.com/rack\n",
+			expectError: true,
+		},
+		{
+			description: "invalid key",
+			data:        "example.com/rack id=r1\n",
+			expectError: true,
+		},
+		{
+			description: "invalid value",
+			data:        "example.com/rack=rack 1\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels, err := parseLabels([]byte(tc.data))
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got labels %v", labels)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(labels, tc.expected) {
+				t.Errorf("expected labels %v, got %v", tc.expected, labels)
+			}
+		})
+	}
+}
+
+func TestNewStaticLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		data        *string
+		expectError bool
+	}{
+		{
+			description: "valid file",
+			data:        ptr("example.com/rack=r1\n"),
+		},
+		{
+			description: "invalid label",
+			data:        ptr("example.com/rack=rack 1\n"),
+			expectError: true,
+		},
+		{
+			description: "missing file",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "labels")
+			if tc.data != nil {
+				if err := os.WriteFile(path, []byte(*tc.data), 0644); err != nil {
+					t.Fatalf("failed to write labels file: %v", err)
+				}
+			}
+
+			_, err := NewStaticLabeler(path)
+			if tc.expectError && err == nil {
+				t.Error("expected an error")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestStaticLabelerReload(t *testing.T) {
+	testCases := []struct {
+		description string
+		update      func(path string) error
+		expected    Labels
+	}{
+		{
+			description: "changed file is applied",
+			update: func(path string) error {
+				return os.WriteFile(path, []byte("example.com/rack=r2\n"), 0644)
+			},
+			expected: Labels{"example.com/rack": "r2"},
+		},
+		{
+			description: "invalid file keeps the previous labels",
+			update: func(path string) error {
+				return os.WriteFile(path, []byte("example.com/rack\n"), 0644)
+			},
+			expected: Labels{"example.com/rack": "r1"},
+		},
+		{
+			description: "removed file keeps the previous labels",
+			update:      os.Remove,
+			expected:    Labels{"example.com/rack": "r1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "labels")
+			if err := os.WriteFile(path, []byte("example.com/rack=r1\n"), 0644); err != nil {
+				t.Fatalf("failed to write labels file: %v", err)
+			}
+			l, err := NewStaticLabeler(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := tc.update(path); err != nil {
+				t.Fatalf("failed to update labels file: %v", err)
+			}
+			checkLabels(t, generateLabels(t, l), tc.expected)
+		})
+	}
+}
+
+func TestSplitLabelValues(t *testing.T) {
+	long := strings.Repeat("a", maxLabelValueLength)
+
+	testCases := []struct {
+		description string
+		values      []string
+		expected    []string
+		expectError bool
+	}{
+		{
+			description: "no values",
+		},
+		{
+			description: "values are sorted into a single value",
+			values:      []string{"b", "c", "a"},
+			expected:    []string{"a_b_c"},
+		},
+		{
+			description: "values are split at the maximum length",
+			values:      []string{"b", long[:31], long[:31]},
+			expected:    []string{long[:31] + "_" + long[:31], "b"},
+		},
+		{
+			description: "value of the maximum length",
+			values:      []string{long, "b"},
+			expected:    []string{long, "b"},
+		},
+		{
+			description: "value longer than the maximum length",
+			values:      []string{long + "a"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			values, err := splitLabelValues(tc.values)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %v", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(values, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, values)
+			}
+		})
+	}
+}
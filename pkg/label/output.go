@@ -17,13 +17,28 @@
 package label
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	nfdv1alpha1 "sigs.k8s.io/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	nfdclientset "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned"
@@ -31,52 +46,378 @@ import (
 	"gitee.com/deep-spark/ix-feature-discovery/pkg/config"
 )
 
+const (
+	// webhookMaxRetries is the number of times a failed webhook request is retried.
+	webhookMaxRetries = 3
+	// webhookInitialBackoff is the delay before the first webhook retry; it doubles on each retry.
+	webhookInitialBackoff = time.Second
+	// maxRetryDelay caps the delay between retries of failed Kubernetes API requests.
+	maxRetryDelay = 30 * time.Second
+)
+
 // Outputer defines a mechanism to output labels.
 type Outputer interface {
-	Output(Labels) error
+	Output(context.Context, Labels) error
+	// Cleanup removes the labels output by the outputer, when the process shuts down.
+	Cleanup() error
 }
 
 type NodeFeatureOutputer struct {
 	nodeConfig   config.NodeConfig
 	nfdClientSet nfdclientset.Interface
+	// eventRecorder, if set, records events about the NodeFeature object.
+	eventRecorder record.EventRecorder
+	// labelPrefix is the prefix of the generated label keys.
+	labelPrefix string
+
+	// retryAttempts and retryBaseDelay configure the retries of failed API requests.
+	retryAttempts  int
+	retryBaseDelay time.Duration
 }
 
-// NewOutputer creates a NodeFeatureOutputer.
+// NewOutputer creates an Outputer for the configured outputs. A FileOutputer is created if
+// an output file is set, a WebhookOutputer if a webhook URL is set, a PrometheusOutputer if a
+// metrics port is set, and a NodeFeatureOutputer if a Kubernetes client is available. If
+// several are configured, labels are written to all. In dry-run mode labels are only printed
+// to stdout.
 func NewOutputer(config *config.Config, nodeConfig config.NodeConfig, clientSets config.ClientSets) (Outputer, error) {
-	if nodeConfig.Name == "" {
-		return nil, fmt.Errorf("required flag node-name not set")
+	if config.Flags.DryRun != nil && *config.Flags.DryRun {
+		return &StdoutOutputer{}, nil
+	}
+
+	var outputers []Outputer
+	if config.Flags.OutputFile != nil && *config.Flags.OutputFile != "" {
+		outputers = append(outputers, &FileOutputer{path: *config.Flags.OutputFile})
+	}
+
+	if config.Flags.WebhookURL != nil && *config.Flags.WebhookURL != "" {
+		outputers = append(outputers, &WebhookOutputer{
+			url:    *config.Flags.WebhookURL,
+			client: &http.Client{Timeout: time.Duration(*config.Flags.WebhookTimeout)},
+		})
+	}
+
+	if config.Flags.MetricsPort != nil && *config.Flags.MetricsPort != 0 {
+		p, err := NewPrometheusOutputer(*config.Flags.MetricsPort)
+		if err != nil {
+			return nil, err
+		}
+		outputers = append(outputers, p)
+	}
+
+	if clientSets.NFD != nil {
+		if nodeConfig.Name == "" {
+			return nil, fmt.Errorf("required flag node-name not set")
+		}
+		if nodeConfig.Namespace == "" {
+			return nil, fmt.Errorf("required flag namespace not set")
+		}
+		outputers = append(outputers, &NodeFeatureOutputer{
+			nodeConfig:     nodeConfig,
+			nfdClientSet:   clientSets.NFD,
+			eventRecorder:  clientSets.EventRecorder,
+			labelPrefix:    *config.Flags.LabelPrefix,
+			retryAttempts:  *config.Flags.KubeRetryAttempts,
+			retryBaseDelay: time.Duration(*config.Flags.KubeRetryBaseDelay),
+		})
+	}
+
+	if len(outputers) == 0 {
+		return nil, fmt.Errorf("no output configured: set an output file, webhook URL or metrics port, or provide Kubernetes credentials")
+	}
+	return NewMultiOutputer(outputers...), nil
+}
+
+// MultiOutputer outputs labels to multiple outputers.
+type MultiOutputer []Outputer
+
+// NewMultiOutputer returns an Outputer that outputs labels to all of the specified outputers.
+// A single outputer is returned as is.
+func NewMultiOutputer(outputers ...Outputer) Outputer {
+	if len(outputers) == 1 {
+		return outputers[0]
+	}
+	return MultiOutputer(outputers)
+}
+
+// Output outputs the labels to each outputer. All outputers are attempted even if some
+// fail, and their errors are joined.
+func (m MultiOutputer) Output(ctx context.Context, labels Labels) error {
+	var errs []error
+	for _, o := range m {
+		if err := o.Output(ctx, labels); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Cleanup cleans up each outputer. All outputers are attempted even if some fail, and their
+// errors are joined.
+func (m MultiOutputer) Cleanup() error {
+	var errs []error
+	for _, o := range m {
+		if err := o.Cleanup(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	if nodeConfig.Namespace == "" {
-		return nil, fmt.Errorf("required flag namespace not set")
+	return errors.Join(errs...)
+}
+
+// FileOutputer writes labels to a file in the NFD features.d format.
+type FileOutputer struct {
+	path string
+}
+
+// Output writes the labels to the output file, one key=value pair per line. The file is
+// replaced atomically so that NFD never reads a partially written file.
+func (f *FileOutputer) Output(ctx context.Context, labels Labels) error {
+	var buf bytes.Buffer
+	if err := writeLabels(&buf, labels); err != nil {
+		return err
 	}
-	out := NodeFeatureOutputer{
-		nodeConfig:   nodeConfig,
-		nfdClientSet: clientSets.NFD,
+
+	klog.Infof("Writing labels to output file %s", f.path)
+	if err := writeFileAtomically(f.path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write output file %q: %w", f.path, err)
 	}
-	return &out, nil
+	return nil
 }
 
-// Output creates or updates the node-specific NodeFeature custom resource.
-func (n *NodeFeatureOutputer) Output(labels Labels) error {
+// Cleanup does nothing, as the output file is removed whenever the labeling loop exits.
+func (f *FileOutputer) Cleanup() error {
+	return nil
+}
+
+// StdoutOutputer prints labels to stdout without side effects, for testing label generation.
+type StdoutOutputer struct{}
+
+// Output prints the labels to stdout, one key=value pair per line.
+func (s *StdoutOutputer) Output(ctx context.Context, labels Labels) error {
+	return writeLabels(os.Stdout, labels)
+}
+
+// Cleanup does nothing, as labels printed to stdout cannot be removed.
+func (s *StdoutOutputer) Cleanup() error {
+	return nil
+}
+
+// WebhookOutputer posts labels as a JSON object to an HTTP endpoint.
+type WebhookOutputer struct {
+	url    string
+	client *http.Client
+}
+
+// Output posts the labels to the webhook. Network errors and 5xx responses are retried up
+// to webhookMaxRetries times with exponential backoff.
+func (w *WebhookOutputer) Output(ctx context.Context, labels Labels) error {
+	body, err := json.Marshal(labels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels: %w", err)
+	}
+
+	backoff := webhookInitialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == webhookMaxRetries {
+			return fmt.Errorf("failed to post labels to webhook %q: %w", w.url, err)
+		}
+		klog.Warningf("Failed to post labels to webhook, retrying in %s: %v", backoff, err)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// Cleanup does nothing, as the webhook has no way to remove labels.
+func (w *WebhookOutputer) Cleanup() error {
+	return nil
+}
+
+// post sends a single request to the webhook and reports whether a failure may be retried.
+func (w *WebhookOutputer) post(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	klog.Infof("Posted labels to webhook %s: %s", w.url, resp.Status)
+	return false, nil
+}
+
+// PrometheusOutputer exposes labels as Prometheus gauges on a /metrics endpoint. Each label
+// is a series of the ix_feature_discovery_label gauge with value 1.
+type PrometheusOutputer struct {
+	gauge *prometheus.GaugeVec
+}
+
+var (
+	prometheusOutputersMu sync.Mutex
+	// prometheusOutputers holds the outputer serving each port, so that the outputers
+	// recreated on a restart reuse the running server instead of binding the port again.
+	prometheusOutputers = make(map[int]*PrometheusOutputer)
+)
+
+// NewPrometheusOutputer returns a PrometheusOutputer serving metrics on the specified port.
+func NewPrometheusOutputer(port int) (*PrometheusOutputer, error) {
+	prometheusOutputersMu.Lock()
+	defer prometheusOutputersMu.Unlock()
+
+	if p, ok := prometheusOutputers[port]; ok {
+		return p, nil
+	}
+
+	p := &PrometheusOutputer{
+		gauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ix_feature_discovery_label",
+			Help: "Labels generated by ix-feature-discovery, with value 1 for each label.",
+		}, []string{"key", "value"}),
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(p.gauge)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on metrics port %d: %w", port, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	go func() {
+		klog.Infof("Serving metrics on port %d", port)
+		if err := http.Serve(listener, mux); err != nil {
+			klog.Errorf("Metrics server stopped: %v", err)
+		}
+	}()
+
+	prometheusOutputers[port] = p
+	return p, nil
+}
+
+// Output replaces the exposed labels, so that removed labels no longer appear as metrics.
+func (p *PrometheusOutputer) Output(ctx context.Context, labels Labels) error {
+	p.gauge.Reset()
+	for k, v := range labels {
+		p.gauge.WithLabelValues(k, v).Set(1)
+	}
+	return nil
+}
+
+// Cleanup removes the exposed labels.
+func (p *PrometheusOutputer) Cleanup() error {
+	p.gauge.Reset()
+	return nil
+}
+
+// writeLabels writes the labels to w in the NFD features.d format, one key=value pair per
+// line, sorted by key.
+func writeLabels(w io.Writer, labels Labels) error {
+	for _, k := range sortedKeys(labels) {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", k, labels[k]); err != nil {
+			return fmt.Errorf("failed to write labels: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomically writes data to a temporary file in the directory of path and
+// renames it to path.
+func writeFileAtomically(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions of temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Output creates or updates the node-specific NodeFeature custom resource. A warning event
+// is recorded if the labels cannot be output, or if some labels could not be generated.
+func (n *NodeFeatureOutputer) Output(ctx context.Context, labels Labels) error {
+	if count := labels[n.labelPrefix+"/gpu.discovery-errors"]; count != "" && count != "0" {
+		n.event(corev1.EventTypeWarning, "DiscoveryFailed", "%s labelers failed, their labels are missing", count)
+	}
+
+	err := withRetry(ctx, func() error { return n.output(ctx, labels) }, n.retryAttempts, n.retryBaseDelay)
+	if err != nil {
+		n.event(corev1.EventTypeWarning, "OutputFailed", "Failed to output labels: %v", err)
+	}
+	return err
+}
+
+// event records an event about the NodeFeature object, if an event recorder is set.
+func (n *NodeFeatureOutputer) event(eventType, reason, messageFmt string, args ...interface{}) {
+	if n.eventRecorder == nil {
+		return
+	}
+	nodeFeature := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{Name: n.nodeFeatureName(), Namespace: n.nodeConfig.Namespace},
+	}
+	n.eventRecorder.Eventf(nodeFeature, eventType, reason, messageFmt, args...)
+}
+
+// nodeFeatureName returns the name of the node-specific NodeFeature object.
+func (n *NodeFeatureOutputer) nodeFeatureName() string {
+	return strings.Join([]string{nodeFeaturePrefix, n.nodeConfig.Name}, "-")
+}
+
+// output creates or updates the NodeFeature object once.
+func (n *NodeFeatureOutputer) output(ctx context.Context, labels Labels) error {
 	nodename := n.nodeConfig.Name
 	if nodename == "" {
 		return fmt.Errorf("required flag %q not set", "node-name")
 	}
 	namespace := n.nodeConfig.Namespace
-	nodeFeatureName := strings.Join([]string{nodeFeaturePrefix, nodename}, "-")
+	nodeFeatureName := n.nodeFeatureName()
 
-	if nfr, err := n.nfdClientSet.NfdV1alpha1().NodeFeatures(namespace).Get(context.TODO(), nodeFeatureName, metav1.GetOptions{}); errors.IsNotFound(err) {
+	if nfr, err := n.nfdClientSet.NfdV1alpha1().NodeFeatures(namespace).Get(ctx, nodeFeatureName, metav1.GetOptions{}); apierrors.IsNotFound(err) {
 		klog.Infof("Creating NodeFeature object %s in namespace %s", nodeFeatureName, namespace)
 		nfr = &nfdv1alpha1.NodeFeature{
 			TypeMeta:   metav1.TypeMeta{},
 			ObjectMeta: metav1.ObjectMeta{Name: nodeFeatureName, Labels: map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodename}},
 			Spec:       nfdv1alpha1.NodeFeatureSpec{Features: *nfdv1alpha1.NewFeatures(), Labels: labels},
 		}
-		nfrCreated, err := n.nfdClientSet.NfdV1alpha1().NodeFeatures(namespace).Create(context.TODO(), nfr, metav1.CreateOptions{})
+		nfrCreated, err := n.nfdClientSet.NfdV1alpha1().NodeFeatures(namespace).Create(ctx, nfr, metav1.CreateOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to create NodeFeature object %q: %w", nfr.Name, err)
 		}
 		klog.Infof("NodeFeature object %s created successfully: %v", nfrCreated.Name, nfrCreated)
+		n.event(corev1.EventTypeNormal, "LabelsUpdated", "Created NodeFeature object with %d labels", len(labels))
 	} else if err != nil {
 		return fmt.Errorf("failed to get NodeFeature object %s: %w", nodeFeatureName, err)
 	} else {
@@ -85,15 +426,119 @@ func (n *NodeFeatureOutputer) Output(labels Labels) error {
 		nfrUpdated.Spec = nfdv1alpha1.NodeFeatureSpec{Features: *nfdv1alpha1.NewFeatures(), Labels: labels}
 
 		if !equality.Semantic.DeepEqual(nfr, nfrUpdated) {
-			klog.Infof("Updating NodeFeature object %s in namespace %s", nodeFeatureName, namespace)
-			nfrUpdated, err = n.nfdClientSet.NfdV1alpha1().NodeFeatures(namespace).Update(context.TODO(), nfrUpdated, metav1.UpdateOptions{})
+			added, removed, changed := Diff(nfr.Spec.Labels, labels)
+			klog.Infof("Updating NodeFeature object %s in namespace %s: %d labels added, %d removed, %d changed",
+				nodeFeatureName, namespace, len(added), len(removed), len(changed))
+			logLabelDiff(nfr.Spec.Labels, added, removed, changed)
+			nfrUpdated, err = n.nfdClientSet.NfdV1alpha1().NodeFeatures(namespace).Update(ctx, nfrUpdated, metav1.UpdateOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to update NodeFeature object %q: %w", nfr.Name, err)
 			}
-			klog.Infof("NodeFeature object %s updated successfully: %v", nfrUpdated.Name, nfrUpdated)
+			klog.Infof("NodeFeature object %s updated successfully", nfrUpdated.Name)
+			n.event(corev1.EventTypeNormal, "LabelsUpdated", "Updated labels: %d added, %d removed, %d changed",
+				len(added), len(removed), len(changed))
 		} else {
 			klog.Infof("No changes detected in NodeFeature object %s, skipping update", nodeFeatureName)
 		}
 	}
 	return nil
 }
+
+// Cleanup deletes the node-specific NodeFeature custom resource, so that the labels of a
+// node that no longer runs ix-feature-discovery do not persist. A missing object is ignored.
+func (n *NodeFeatureOutputer) Cleanup() error {
+	ctx := context.Background()
+	return withRetry(ctx, func() error { return n.cleanup(ctx) }, n.retryAttempts, n.retryBaseDelay)
+}
+
+// cleanup deletes the NodeFeature object once.
+func (n *NodeFeatureOutputer) cleanup(ctx context.Context) error {
+	namespace := n.nodeConfig.Namespace
+	nodeFeatureName := n.nodeFeatureName()
+
+	klog.Infof("Deleting NodeFeature object %s in namespace %s", nodeFeatureName, namespace)
+	err := n.nfdClientSet.NfdV1alpha1().NodeFeatures(namespace).Delete(ctx, nodeFeatureName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		klog.Infof("NodeFeature object %s not found, nothing to delete", nodeFeatureName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete NodeFeature object %q: %w", nodeFeatureName, err)
+	}
+	klog.Infof("NodeFeature object %s deleted successfully", nodeFeatureName)
+	return nil
+}
+
+// logLabelDiff logs the added, removed and changed labels, sorted by key. The previous
+// values of changed labels are looked up in old.
+func logLabelDiff(old, added, removed, changed Labels) {
+	for _, k := range sortedKeys(added) {
+		klog.Infof("Label added: %s=%s", k, added[k])
+	}
+	for _, k := range sortedKeys(removed) {
+		klog.Infof("Label removed: %s=%s", k, removed[k])
+	}
+	for _, k := range sortedKeys(changed) {
+		klog.Infof("Label changed: %s=%s (was %s)", k, changed[k], old[k])
+	}
+}
+
+// withRetry calls fn until it succeeds or fails with an error that is not transient, at
+// most maxAttempts times. The delay between attempts starts at baseDelay and doubles after
+// each attempt, with up to 50% random jitter added so that many nodes do not retry in
+// lockstep, and never exceeds maxRetryDelay. The last error is returned if all attempts
+// fail, and the context's error if it is done while waiting.
+func withRetry(ctx context.Context, fn func() error, maxAttempts int, baseDelay time.Duration) error {
+	delay := baseDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= maxAttempts || !isTransient(err) {
+			return err
+		}
+		wait := jitteredRetryDelay(delay)
+		klog.Warningf("Attempt %d of %d failed, retrying in %s: %v", attempt, maxAttempts, wait, err)
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// jitteredRetryDelay returns delay with up to 50% random jitter added, capped at
+// maxRetryDelay.
+func jitteredRetryDelay(delay time.Duration) time.Duration {
+	wait := delay
+	// rand.N panics unless its argument is positive, which delay / 2 is not for 1ns.
+	if half := delay / 2; half > 0 {
+		wait += rand.N(half)
+	}
+	return min(wait, maxRetryDelay)
+}
+
+// isTransient returns true if err is a Kubernetes API or network error that may go away
+// when the request is retried.
+func isTransient(err error) bool {
+	if apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// sleepContext waits for the specified duration, or returns the context's error if it is
+// done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
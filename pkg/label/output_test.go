@@ -0,0 +1,514 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+	nfdfake "sigs.k8s.io/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/config"
+)
+
+// captureStdout returns the output written to stdout by f.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read stdout: %v", err)
+	}
+	return string(out)
+}
+
+func TestDryRunOutputer(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "ix-features")
+	conf := newTestConfig(func(f *config.Flags) {
+		f.DryRun = ptr(true)
+		f.OutputFile = ptr(outputFile)
+	})
+	o, err := NewOutputer(conf, config.NodeConfig{}, config.ClientSets{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := o.(*StdoutOutputer); !ok {
+		t.Fatalf("expected a StdoutOutputer in dry-run mode, got %T", o)
+	}
+
+	labels := Labels{
+		"iluvatar.ai/gpu.product": "MR-V100",
+		"iluvatar.ai/gpu.count":   "2",
+		"iluvatar.ai/gpu.memory":  "32768",
+	}
+	out := captureStdout(t, func() {
+		if err := o.Output(context.Background(), labels); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	expected := "iluvatar.ai/gpu.count=2\n" +
+		"iluvatar.ai/gpu.memory=32768\n" +
+		"iluvatar.ai/gpu.product=MR-V100\n"
+	if out != expected {
+		t.Errorf("expected output %q, got %q", expected, out)
+	}
+	if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+		t.Errorf("expected no output file in dry-run mode, got %v", err)
+	}
+}
+
+func TestWebhookOutputer(t *testing.T) {
+	labels := Labels{"iluvatar.ai/gpu.product": "MR-V100"}
+
+	testCases := []struct {
+		description   string
+		statuses      []int
+		timeout       time.Duration
+		expectedCalls int
+		expectError   bool
+	}{
+		{
+			description:   "success",
+			statuses:      []int{http.StatusOK},
+			expectedCalls: 1,
+		},
+		{
+			description:   "server error is retried",
+			statuses:      []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedCalls: 2,
+		},
+		{
+			description:   "client error is not retried",
+			statuses:      []int{http.StatusBadRequest},
+			expectedCalls: 1,
+			expectError:   true,
+		},
+		{
+			description:   "retries stop when the context is done",
+			statuses:      []int{http.StatusInternalServerError},
+			timeout:       100 * time.Millisecond,
+			expectedCalls: 1,
+			expectError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := int(calls.Add(1))
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+				}
+				var received Labels
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("failed to decode body: %v", err)
+				}
+				if !reflect.DeepEqual(received, labels) {
+					t.Errorf("expected labels %v, got %v", labels, received)
+				}
+				w.WriteHeader(tc.statuses[min(call, len(tc.statuses))-1])
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			if tc.timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			o := &WebhookOutputer{url: server.URL, client: server.Client()}
+			err := o.Output(ctx, labels)
+			if tc.expectError && err == nil {
+				t.Error("expected an error")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if int(calls.Load()) != tc.expectedCalls {
+				t.Errorf("expected %d requests, got %d", tc.expectedCalls, calls.Load())
+			}
+		})
+	}
+}
+
+// freePort returns a TCP port that is free to listen on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// getMetrics returns the body of the /metrics endpoint on the port.
+func getMetrics(t *testing.T, port int) string {
+	t.Helper()
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/metrics", port))
+	if err != nil {
+		t.Fatalf("failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestPrometheusOutputer(t *testing.T) {
+	port := freePort(t)
+	o, err := NewPrometheusOutputer(port)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	product := `ix_feature_discovery_label{key="iluvatar.ai/gpu.product",value="MR-V100"} 1`
+	count := `ix_feature_discovery_label{key="iluvatar.ai/gpu.count",value="2"} 1`
+
+	if err := o.Output(context.Background(), Labels{"iluvatar.ai/gpu.product": "MR-V100", "iluvatar.ai/gpu.count": "2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metrics := getMetrics(t, port)
+	if !strings.Contains(metrics, product) || !strings.Contains(metrics, count) {
+		t.Errorf("expected metrics to contain %q and %q, got %q", product, count, metrics)
+	}
+
+	// Outputting again, as on every discovery cycle, replaces the previous labels.
+	if err := o.Output(context.Background(), Labels{"iluvatar.ai/gpu.product": "MR-V100"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metrics = getMetrics(t, port)
+	if !strings.Contains(metrics, product) || strings.Contains(metrics, count) {
+		t.Errorf("expected metrics to contain only %q, got %q", product, metrics)
+	}
+
+	// Recreating the outputer on a restart reuses the server on the port.
+	again, err := NewPrometheusOutputer(port)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again != o {
+		t.Error("expected the outputer of the port to be reused")
+	}
+
+	if err := o.Cleanup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metrics = getMetrics(t, port); strings.Contains(metrics, product) {
+		t.Errorf("expected no labels after cleanup, got %q", metrics)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	testCases := []struct {
+		description   string
+		failures      int
+		failure       func(call int) error
+		maxAttempts   int
+		expectedCalls int
+		expectError   bool
+	}{
+		{
+			description:   "success on the first attempt",
+			maxAttempts:   3,
+			expectedCalls: 1,
+		},
+		{
+			description:   "success after failures",
+			failures:      2,
+			maxAttempts:   3,
+			expectedCalls: 3,
+		},
+		{
+			description:   "last error after all attempts fail",
+			failures:      3,
+			maxAttempts:   3,
+			expectedCalls: 3,
+			expectError:   true,
+		},
+		{
+			description:   "single attempt",
+			failures:      1,
+			maxAttempts:   1,
+			expectedCalls: 1,
+			expectError:   true,
+		},
+		{
+			description: "too many requests are retried",
+			failures:    1,
+			failure: func(call int) error {
+				return apierrors.NewTooManyRequests(fmt.Sprintf("failure %d", call), 0)
+			},
+			maxAttempts:   3,
+			expectedCalls: 2,
+		},
+		{
+			description: "network errors are retried",
+			failures:    1,
+			failure: func(call int) error {
+				return fmt.Errorf("failure %d: %w", call, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+			},
+			maxAttempts:   3,
+			expectedCalls: 2,
+		},
+		{
+			description: "errors that are not transient are not retried",
+			failures:    3,
+			failure: func(call int) error {
+				return apierrors.NewForbidden(schema.GroupResource{Resource: "nodefeatures"}, fmt.Sprintf("failure %d", call), nil)
+			},
+			maxAttempts:   3,
+			expectedCalls: 1,
+			expectError:   true,
+		},
+		{
+			description: "errors without a type are not retried",
+			failures:    3,
+			failure: func(call int) error {
+				return fmt.Errorf("failure %d", call)
+			},
+			maxAttempts:   3,
+			expectedCalls: 1,
+			expectError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			failure := tc.failure
+			if failure == nil {
+				failure = func(call int) error {
+					return apierrors.NewServiceUnavailable(fmt.Sprintf("failure %d", call))
+				}
+			}
+			calls := 0
+			var lastErr error
+			err := withRetry(context.Background(), func() error {
+				calls++
+				if calls <= tc.failures {
+					lastErr = failure(calls)
+					return lastErr
+				}
+				return nil
+			}, tc.maxAttempts, time.Millisecond)
+			if tc.expectError && (err == nil || err != lastErr) {
+				t.Errorf("expected the last error %v, got %v", lastErr, err)
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestJitteredRetryDelay(t *testing.T) {
+	testCases := []struct {
+		description string
+		delay       time.Duration
+		minimum     time.Duration
+		maximum     time.Duration
+	}{
+		{
+			description: "up to 50% jitter is added",
+			delay:       time.Second,
+			minimum:     time.Second,
+			maximum:     1500 * time.Millisecond,
+		},
+		{
+			description: "delay of 1ns",
+			delay:       time.Nanosecond,
+			minimum:     time.Nanosecond,
+			maximum:     time.Nanosecond,
+		},
+		{
+			description: "jitter is capped",
+			delay:       maxRetryDelay - time.Second,
+			minimum:     maxRetryDelay - time.Second,
+			maximum:     maxRetryDelay,
+		},
+		{
+			description: "delay above the cap",
+			delay:       4 * maxRetryDelay,
+			minimum:     maxRetryDelay,
+			maximum:     maxRetryDelay,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			for range 100 {
+				if wait := jitteredRetryDelay(tc.delay); wait < tc.minimum || wait > tc.maximum {
+					t.Fatalf("expected a delay between %s and %s, got %s", tc.minimum, tc.maximum, wait)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeFeatureOutputerRetries(t *testing.T) {
+	labels := Labels{DefaultLabelPrefix + "/gpu.product": "MR-V100"}
+
+	testCases := []struct {
+		description   string
+		failures      int
+		expectedCalls int
+		expectError   bool
+	}{
+		{
+			description:   "transient failures are retried",
+			failures:      2,
+			expectedCalls: 3,
+		},
+		{
+			description:   "error after all attempts fail",
+			failures:      3,
+			expectedCalls: 3,
+			expectError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			client := nfdfake.NewSimpleClientset()
+			calls := 0
+			client.PrependReactor("create", "nodefeatures", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				calls++
+				if calls <= tc.failures {
+					return true, nil, apierrors.NewServiceUnavailable("leader election in progress")
+				}
+				return false, nil, nil
+			})
+
+			o := &NodeFeatureOutputer{
+				nodeConfig:     config.NodeConfig{Name: "node", Namespace: "ix-feature-discovery"},
+				nfdClientSet:   client,
+				labelPrefix:    DefaultLabelPrefix,
+				retryAttempts:  3,
+				retryBaseDelay: time.Millisecond,
+			}
+			err := o.Output(context.Background(), labels)
+			if tc.expectError && err == nil {
+				t.Error("expected an error")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d create calls, got %d", tc.expectedCalls, calls)
+			}
+			if tc.expectError {
+				return
+			}
+
+			nodeFeature, err := client.NfdV1alpha1().NodeFeatures("ix-feature-discovery").Get(context.Background(), o.nodeFeatureName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get NodeFeature object: %v", err)
+			}
+			if !reflect.DeepEqual(Labels(nodeFeature.Spec.Labels), labels) {
+				t.Errorf("expected labels %v, got %v", labels, nodeFeature.Spec.Labels)
+			}
+		})
+	}
+}
+
+func TestNodeFeatureOutputerCleanup(t *testing.T) {
+	testCases := []struct {
+		description string
+		exists      bool
+	}{
+		{
+			description: "existing object is deleted",
+			exists:      true,
+		},
+		{
+			description: "missing object is ignored",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			client := nfdfake.NewSimpleClientset()
+			o := &NodeFeatureOutputer{
+				nodeConfig:     config.NodeConfig{Name: "node", Namespace: "ix-feature-discovery"},
+				nfdClientSet:   client,
+				labelPrefix:    DefaultLabelPrefix,
+				retryAttempts:  3,
+				retryBaseDelay: time.Millisecond,
+			}
+			if tc.exists {
+				if err := o.Output(context.Background(), Labels{DefaultLabelPrefix + "/gpu.present": "true"}); err != nil {
+					t.Fatalf("failed to output labels: %v", err)
+				}
+			}
+
+			deletes := 0
+			client.PrependReactor("delete", "nodefeatures", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				deletes++
+				if name := action.(k8stesting.DeleteAction).GetName(); name != o.nodeFeatureName() {
+					t.Errorf("expected delete of %q, got %q", o.nodeFeatureName(), name)
+				}
+				return false, nil, nil
+			})
+
+			if err := o.Cleanup(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if deletes != 1 {
+				t.Errorf("expected 1 delete call, got %d", deletes)
+			}
+
+			_, err := client.NfdV1alpha1().NodeFeatures("ix-feature-discovery").Get(context.Background(), o.nodeFeatureName(), metav1.GetOptions{})
+			if !apierrors.IsNotFound(err) {
+				t.Errorf("expected NodeFeature object to be deleted, got error %v", err)
+			}
+		})
+	}
+}
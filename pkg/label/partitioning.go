@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"errors"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// newPartitioningLabeler creates a labeler for the hardware partitioning capability of the
+// devices. The node is reported as capable only if every device supports partitioning, so
+// boards without the capability are labeled as not capable. Partitioning is reported as
+// enabled only if it is enabled on every device, and the label is omitted if the mode of
+// any capable device cannot be retrieved.
+func newPartitioningLabeler(prefix string, devices []resource.Device) Labeler {
+	capable := len(devices) > 0
+	enabled := true
+	modeKnown := true
+	for i, dev := range devices {
+		mode, err := dev.GetPartitioningMode()
+		if errors.Is(err, resource.ErrNotSupported) {
+			capable = false
+			continue
+		}
+		if err != nil {
+			klog.Warningf("Failed to retrieve partitioning mode for device %d: %v", i, err)
+			modeKnown = false
+			continue
+		}
+		enabled = enabled && mode
+	}
+
+	labels := Labels{
+		prefix + "/gpu.partitioning.capable": strconv.FormatBool(capable),
+	}
+	if capable && modeKnown {
+		labels[prefix+"/gpu.partitioning.enabled"] = strconv.FormatBool(enabled)
+	}
+
+	return labels
+}
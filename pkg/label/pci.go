@@ -0,0 +1,391 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// newPCIBusIDLabeler creates a labeler for the PCI bus IDs of the devices. It generates a
+// gpu.<index>.pci-bus-id label per device, and lists the compact bus IDs of all devices in
+// gpu.pci-bus-ids, continued in gpu.pci-bus-ids.1, gpu.pci-bus-ids.2 and so on if they do
+// not fit in a single label value. Devices whose bus ID cannot be retrieved are skipped.
+func newPCIBusIDLabeler(prefix string, devices []resource.Device) Labeler {
+	labels := make(Labels)
+	var busIDs []string
+	for i, dev := range devices {
+		busID, err := dev.GetPCIBusID()
+		if err != nil {
+			klog.Warningf("Failed to retrieve PCI bus ID for device %d: %v", i, err)
+			continue
+		}
+		labels[deviceLabelKey(prefix, i, "pci-bus-id")] = encodePCIBusID(busID)
+		busIDs = append(busIDs, compactPCIBusID(busID))
+	}
+
+	values, err := splitLabelValues(busIDs)
+	if err != nil {
+		klog.Warningf("Skipping label %s: %v", "gpu.pci-bus-ids", err)
+		return labels
+	}
+	for i, value := range values {
+		key := prefix + "/gpu.pci-bus-ids"
+		if i > 0 {
+			key += "." + strconv.Itoa(i)
+		}
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// newPCIVendorIDLabeler creates a labeler for the PCI vendor ID of the devices, which is
+// stable across product name changes. If the devices have different vendor IDs, a warning
+// is logged and a per-device label is generated instead. Devices whose vendor ID cannot be
+// retrieved are skipped.
+func newPCIVendorIDLabeler(prefix string, devices []resource.Device) Labeler {
+	vendorIDs := make(map[int]string)
+	var first string
+	mixed := false
+	for i, dev := range devices {
+		vendorID, err := dev.GetPCIVendorID()
+		if err != nil {
+			klog.Warningf("Failed to retrieve PCI vendor ID for device %d: %v", i, err)
+			continue
+		}
+		if len(vendorIDs) == 0 {
+			first = vendorID
+		}
+		mixed = mixed || vendorID != first
+		vendorIDs[i] = vendorID
+	}
+
+	if len(vendorIDs) == 0 {
+		return empty{}
+	}
+	if !mixed {
+		return Labels{prefix + "/gpu.pci.vendor-id": first}
+	}
+
+	klog.Warningf("Devices have different PCI vendor IDs, labeling each device")
+	labels := make(Labels)
+	for i, vendorID := range vendorIDs {
+		labels[deviceLabelKey(prefix, i, "pci.vendor-id")] = vendorID
+	}
+	return labels
+}
+
+// newPCIIDsLabeler creates a labeler for the PCI device and subsystem IDs of the devices,
+// for targeting specific SKUs. The distinct IDs of all devices are listed in each label.
+// Devices whose IDs cannot be retrieved are skipped.
+func newPCIIDsLabeler(prefix string, devices []resource.Device) Labeler {
+	var deviceIDs, subsystemIDs []string
+	for i, dev := range devices {
+		if id, err := dev.GetPCIDeviceID(); err == nil {
+			deviceIDs = append(deviceIDs, id)
+		} else {
+			klog.Warningf("Failed to retrieve PCI device ID for device %d: %v", i, err)
+		}
+
+		if id, err := dev.GetPCISubsystemID(); err == nil {
+			subsystemIDs = append(subsystemIDs, id)
+		} else {
+			klog.Warningf("Failed to retrieve PCI subsystem ID for device %d: %v", i, err)
+		}
+	}
+
+	labels := make(Labels)
+	for name, ids := range map[string][]string{
+		"gpu.pci.device-id":    deviceIDs,
+		"gpu.pci.subsystem-id": subsystemIDs,
+	} {
+		if len(ids) == 0 {
+			continue
+		}
+		slices.Sort(ids)
+		joined, err := joinLabelValues(slices.Compact(ids))
+		if err != nil {
+			klog.Warningf("Skipping label %s: %v", name, err)
+			continue
+		}
+		labels[prefix+"/"+name] = joined
+	}
+
+	return labels
+}
+
+// newSRIOVLabeler creates a labeler for the SR-IOV virtual functions of the devices. The
+// node is reported as capable if any device supports SR-IOV, and the number of enabled
+// virtual functions is summed across those devices. Devices whose virtual functions cannot
+// be retrieved are skipped.
+func newSRIOVLabeler(prefix string, devices []resource.Device) Labeler {
+	capable := false
+	total := 0
+	for i, dev := range devices {
+		numVFs, err := dev.GetSRIOVNumVFs()
+		if errors.Is(err, resource.ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			klog.Warningf("Failed to retrieve SR-IOV VFs for device %d: %v", i, err)
+			continue
+		}
+		capable = true
+		total += numVFs
+	}
+
+	labels := Labels{
+		prefix + "/gpu.sriov.capable": strconv.FormatBool(capable),
+	}
+	if capable {
+		labels[prefix+"/gpu.sriov.vfs"] = strconv.Itoa(total)
+	}
+
+	return labels
+}
+
+// newBoardLabeler creates a labeler for the physical boards of the devices: whether any
+// device is on a board with multiple GPUs, and the number of distinct boards. Devices whose
+// board ID cannot be retrieved count as a board of their own.
+func newBoardLabeler(prefix string, devices []resource.Device) Labeler {
+	multiGPU := false
+	boardIDs := make(map[int]uint)
+	for i, dev := range devices {
+		if isMulti, err := dev.IsMultiGPUBoard(); err == nil {
+			multiGPU = multiGPU || isMulti
+		} else if !errors.Is(err, resource.ErrNotSupported) {
+			klog.Warningf("Failed to retrieve multi-GPU board for device %d: %v", i, err)
+		}
+
+		id, err := dev.GetBoardID()
+		if errors.Is(err, resource.ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			klog.Warningf("Failed to retrieve board ID for device %d: %v", i, err)
+			continue
+		}
+		boardIDs[i] = id
+	}
+
+	boards := countBoards(len(devices), boardIDs)
+	return Labels{
+		prefix + "/gpu.multi-gpu-board": strconv.FormatBool(multiGPU || boards < len(devices)),
+		prefix + "/gpu.board-count":     strconv.Itoa(boards),
+	}
+}
+
+// countBoards returns the number of distinct boards of a number of devices, given the board
+// IDs of the devices by index. Devices without a board ID count as a board of their own.
+func countBoards(numDevices int, boardIDs map[int]uint) int {
+	distinct := make(map[uint]bool)
+	for _, id := range boardIDs {
+		distinct[id] = true
+	}
+	return len(distinct) + numDevices - len(boardIDs)
+}
+
+// newIOMMUGroupsLabeler creates a labeler listing the IOMMU groups of the devices in
+// ascending order, for planning VFIO passthrough. The groups are reported as disabled if
+// IOMMU is disabled on the host. Devices whose IOMMU group cannot be retrieved are skipped.
+func newIOMMUGroupsLabeler(prefix string, devices []resource.Device) Labeler {
+	var groups []int
+	for i, dev := range devices {
+		group, err := dev.GetIOMMUGroup()
+		if errors.Is(err, resource.ErrNotSupported) {
+			return Labels{prefix + "/gpu.iommu-groups": "disabled"}
+		}
+		if err != nil {
+			klog.Warningf("Failed to retrieve IOMMU group for device %d: %v", i, err)
+			continue
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) == 0 {
+		return empty{}
+	}
+
+	// Sort numerically, as joinLabelValues would order e.g. 10 before 2.
+	slices.Sort(groups)
+	groups = slices.Compact(groups)
+	values := make([]string, len(groups))
+	for i, group := range groups {
+		values[i] = strconv.Itoa(group)
+	}
+	joined := strings.Join(values, labelValueListSep)
+	if len(joined) > maxLabelValueLength {
+		klog.Warningf("Skipping label %s: joined label value must be %v characters or less: %v", "gpu.iommu-groups", maxLabelValueLength, joined)
+		return empty{}
+	}
+
+	return Labels{prefix + "/gpu.iommu-groups": joined}
+}
+
+// newVirtualizationModeLabeler creates a labeler for the virtualization mode of the devices:
+// none on bare metal, passthrough for physical devices in a virtual machine, or vf for SR-IOV
+// virtual functions. If the devices are in different modes, the mode is reported as mixed.
+// Devices whose mode cannot be retrieved are skipped.
+func newVirtualizationModeLabeler(prefix string, devices []resource.Device) Labeler {
+	var modes []resource.VirtualizationMode
+	for i, dev := range devices {
+		mode, err := dev.GetVirtualizationMode()
+		if err != nil {
+			klog.Warningf("Failed to retrieve virtualization mode for device %d: %v", i, err)
+			continue
+		}
+		modes = append(modes, mode)
+	}
+
+	if len(modes) == 0 {
+		return empty{}
+	}
+
+	mode := string(modes[0])
+	if slices.ContainsFunc(modes, func(m resource.VirtualizationMode) bool { return m != modes[0] }) {
+		klog.Warningf("Devices have different virtualization modes: %v", modes)
+		mode = "mixed"
+	}
+
+	return Labels{
+		prefix + "/gpu.virtualization.mode": mode,
+	}
+}
+
+// newInterconnectLabeler creates a labeler for the number of active interconnect links per
+// device. The lowest count across all devices is reported, and 0 means that some device has
+// no interconnect. Devices whose links cannot be retrieved are skipped.
+func newInterconnectLabeler(prefix string, devices []resource.Device) Labeler {
+	var links []int
+	for i, dev := range devices {
+		count, err := dev.GetInterconnectLinks()
+		if err != nil {
+			klog.Warningf("Failed to retrieve interconnect links for device %d: %v", i, err)
+			continue
+		}
+		links = append(links, count)
+	}
+
+	if len(links) == 0 {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.interconnect.links": strconv.Itoa(slices.Min(links)),
+	}
+}
+
+// pcieLink describes the PCIe link generation and width of a device.
+type pcieLink struct {
+	gen   uint
+	width uint
+}
+
+// newPCIeLinkLabeler creates a labeler for the current and maximum PCIe link generation
+// and width. The lowest values across all devices are reported, as they bound the
+// host-to-device bandwidth. For devices behind a PCIe switch the reported current link is
+// the one to the switch, which may be narrower than the physical slot. The node is flagged
+// as downgraded if the current link of any device is below its maximum. Devices whose link
+// cannot be retrieved are skipped.
+func newPCIeLinkLabeler(prefix string, devices []resource.Device) Labeler {
+	var current, maximum []pcieLink
+	downgraded := false
+	for i, dev := range devices {
+		gen, width, err := dev.GetPCIeLinkInfo()
+		if err != nil {
+			klog.Warningf("Failed to retrieve PCIe link info for device %d: %v", i, err)
+			continue
+		}
+		link := pcieLink{gen: gen, width: width}
+		current = append(current, link)
+
+		maxGen, maxWidth, err := dev.GetPCIeMaxLinkInfo()
+		if err != nil {
+			klog.Warningf("Failed to retrieve max PCIe link info for device %d: %v", i, err)
+			continue
+		}
+		maxLink := pcieLink{gen: maxGen, width: maxWidth}
+		maximum = append(maximum, maxLink)
+
+		if isPCIeLinkDowngraded(link, maxLink) {
+			klog.Warningf("PCIe link of device %d is downgraded: gen %d x%d, max gen %d x%d", i, gen, width, maxGen, maxWidth)
+			downgraded = true
+		}
+	}
+
+	labels := make(Labels)
+	if len(current) != 0 {
+		link := minPCIeLink(current)
+		labels[prefix+"/gpu.pcie.gen"] = strconv.FormatUint(uint64(link.gen), 10)
+		labels[prefix+"/gpu.pcie.width"] = strconv.FormatUint(uint64(link.width), 10)
+	}
+	if len(maximum) != 0 {
+		link := minPCIeLink(maximum)
+		labels[prefix+"/gpu.pcie.max-gen"] = strconv.FormatUint(uint64(link.gen), 10)
+		labels[prefix+"/gpu.pcie.max-width"] = strconv.FormatUint(uint64(link.width), 10)
+		labels[prefix+"/gpu.pcie.downgraded"] = strconv.FormatBool(downgraded)
+	}
+
+	return labels
+}
+
+// isPCIeLinkDowngraded returns whether the current link runs below the maximum generation or width.
+func isPCIeLinkDowngraded(current, maximum pcieLink) bool {
+	return current.gen < maximum.gen || current.width < maximum.width
+}
+
+// minPCIeLink returns the lowest generation and the lowest width of the given links.
+func minPCIeLink(links []pcieLink) pcieLink {
+	result := links[0]
+	for _, link := range links[1:] {
+		result.gen = min(result.gen, link.gen)
+		result.width = min(result.width, link.width)
+	}
+	return result
+}
+
+// compactPCIBusID converts a PCI bus ID into a short label value holding the bus and device
+// numbers, prefixed with the domain if it is not 0, e.g. "0000:3b:00.0" becomes "3b-00" and
+// "0001:3b:00.0" becomes "1-3b-00". The function number is dropped, as each GPU is a
+// separate PCI device. Bus IDs that cannot be parsed are encoded in full.
+func compactPCIBusID(busID string) string {
+	parts := strings.Split(strings.ToLower(busID), ":")
+	if len(parts) != 3 {
+		return encodePCIBusID(busID)
+	}
+	domain := strings.TrimLeft(parts[0], "0")
+	bus := parts[1]
+	device, _, _ := strings.Cut(parts[2], ".")
+
+	fields := []string{bus, device}
+	if domain != "" {
+		fields = append([]string{domain}, fields...)
+	}
+	return sanitise(strings.Join(fields, "-"))
+}
+
+// encodePCIBusID converts a PCI bus ID into a valid label value. Colons are not
+// valid in label values and are replaced with '-', e.g. "0000:3b:00.0" becomes "0000-3b-00.0".
+func encodePCIBusID(busID string) string {
+	return sanitise(strings.ReplaceAll(busID, ":", "-"))
+}
@@ -0,0 +1,665 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"fmt"
+	"testing"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+func TestEncodePCIBusID(t *testing.T) {
+	testCases := []struct {
+		busID    string
+		expected string
+	}{
+		{"0000:3b:00.0", "0000-3b-00.0"},
+		{"00000000:AF:00.0", "00000000-AF-00.0"},
+		{"3b:00.0", "3b-00.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.busID, func(t *testing.T) {
+			if encoded := encodePCIBusID(tc.busID); encoded != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, encoded)
+			}
+		})
+	}
+}
+
+func TestCompactPCIBusID(t *testing.T) {
+	testCases := []struct {
+		busID    string
+		expected string
+	}{
+		{"0000:3b:00.0", "3b-00"},
+		{"00000000:AF:00.0", "af-00"},
+		{"0001:3b:00.0", "1-3b-00"},
+		{"0000:3b:01.1", "3b-01"},
+		{"3b:00.0", "3b-00.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.busID, func(t *testing.T) {
+			if compact := compactPCIBusID(tc.busID); compact != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, compact)
+			}
+		})
+	}
+}
+
+// busIDDevices returns devices with the PCI bus IDs 0000:<bus>:00.0 for the given buses.
+func busIDDevices(buses ...string) []*resource.MockDevice {
+	var devices []*resource.MockDevice
+	for _, bus := range buses {
+		devices = append(devices, &resource.MockDevice{PCIBusID: "0000:" + bus + ":00.0"})
+	}
+	return devices
+}
+
+func TestPCIBusIDLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    Labels
+	}{
+		{
+			description: "single device",
+			devices:     busIDDevices("3b"),
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.0.pci-bus-id": "0000-3b-00.0",
+				DefaultLabelPrefix + "/gpu.pci-bus-ids":  "3b-00",
+			},
+		},
+		{
+			description: "multiple devices are listed in sorted order",
+			devices:     busIDDevices("af", "3b"),
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.0.pci-bus-id": "0000-af-00.0",
+				DefaultLabelPrefix + "/gpu.1.pci-bus-id": "0000-3b-00.0",
+				DefaultLabelPrefix + "/gpu.pci-bus-ids":  "3b-00_af-00",
+			},
+		},
+		{
+			description: "eight devices fit in a single label",
+			devices:     busIDDevices("1a", "1b", "3d", "3e", "88", "89", "b1", "b2"),
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.0.pci-bus-id": "0000-1a-00.0",
+				DefaultLabelPrefix + "/gpu.1.pci-bus-id": "0000-1b-00.0",
+				DefaultLabelPrefix + "/gpu.2.pci-bus-id": "0000-3d-00.0",
+				DefaultLabelPrefix + "/gpu.3.pci-bus-id": "0000-3e-00.0",
+				DefaultLabelPrefix + "/gpu.4.pci-bus-id": "0000-88-00.0",
+				DefaultLabelPrefix + "/gpu.5.pci-bus-id": "0000-89-00.0",
+				DefaultLabelPrefix + "/gpu.6.pci-bus-id": "0000-b1-00.0",
+				DefaultLabelPrefix + "/gpu.7.pci-bus-id": "0000-b2-00.0",
+				DefaultLabelPrefix + "/gpu.pci-bus-ids":  "1a-00_1b-00_3d-00_3e-00_88-00_89-00_b1-00_b2-00",
+			},
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{PCIBusID: "0000:3b:00.0", Error: errTest},
+				{PCIBusID: "0000:af:00.0"},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.1.pci-bus-id": "0000-af-00.0",
+				DefaultLabelPrefix + "/gpu.pci-bus-ids":  "af-00",
+			},
+		},
+		{
+			description: "no labels if no device reports its bus ID",
+			devices: []*resource.MockDevice{
+				{Error: errTest},
+			},
+			expected: Labels{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newPCIBusIDLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabels(t, labels, tc.expected)
+			if errs := ValidateLabels(labels); len(errs) != 0 {
+				t.Errorf("invalid labels: %v", errs)
+			}
+		})
+	}
+}
+
+func TestPCIBusIDLabelerSplitsLongList(t *testing.T) {
+	var buses []string
+	for i := range 16 {
+		buses = append(buses, fmt.Sprintf("%02x", 0x10+i))
+	}
+	labels := generateLabels(t, newPCIBusIDLabeler(DefaultLabelPrefix, asDevices(busIDDevices(buses...)...)))
+
+	checkLabel(t, labels, "gpu.pci-bus-ids", "10-00_11-00_12-00_13-00_14-00_15-00_16-00_17-00_18-00_19-00")
+	checkLabel(t, labels, "gpu.pci-bus-ids.1", "1a-00_1b-00_1c-00_1d-00_1e-00_1f-00")
+	checkLabel(t, labels, "gpu.pci-bus-ids.2", "")
+	if errs := ValidateLabels(labels); len(errs) != 0 {
+		t.Errorf("invalid labels: %v", errs)
+	}
+}
+
+func TestPCIeLinkLabeler(t *testing.T) {
+	testCases := []struct {
+		description   string
+		devices       []*resource.MockDevice
+		expectedGen   string
+		expectedWidth string
+	}{
+		{
+			description: "PCIe 4.0 x16",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 4, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+			},
+			expectedGen:   "4",
+			expectedWidth: "16",
+		},
+		{
+			description: "PCIe 3.0 x8",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 3, PCIeWidth: 8, PCIeMaxGen: 3, PCIeMaxWidth: 8},
+			},
+			expectedGen:   "3",
+			expectedWidth: "8",
+		},
+		{
+			description: "device behind a switch reports the link to the switch",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 4, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+				{PCIeGen: 4, PCIeWidth: 8, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+			},
+			expectedGen:   "4",
+			expectedWidth: "8",
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 3, PCIeWidth: 8, Errors: map[string]error{"GetPCIeLinkInfo": errTest}},
+				{PCIeGen: 4, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+			},
+			expectedGen:   "4",
+			expectedWidth: "16",
+		},
+		{
+			description: "no labels if no device reports its link",
+			devices: []*resource.MockDevice{
+				{Error: errTest},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newPCIeLinkLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.pcie.gen", tc.expectedGen)
+			checkLabel(t, labels, "gpu.pcie.width", tc.expectedWidth)
+		})
+	}
+}
+
+func TestPCIeLinkLabelerReportsMinimum(t *testing.T) {
+	var devices []*resource.MockDevice
+	for _, width := range []uint{16, 16, 8, 16} {
+		devices = append(devices, &resource.MockDevice{PCIeGen: 4, PCIeWidth: width, PCIeMaxGen: 4, PCIeMaxWidth: 16})
+	}
+
+	labels := generateLabels(t, newPCIeLinkLabeler(DefaultLabelPrefix, asDevices(devices...)))
+	checkLabel(t, labels, "gpu.pcie.gen", "4")
+	checkLabel(t, labels, "gpu.pcie.width", "8")
+}
+
+func TestIsPCIeLinkDowngraded(t *testing.T) {
+	testCases := []struct {
+		description string
+		current     pcieLink
+		maximum     pcieLink
+		expected    bool
+	}{
+		{"at maximum", pcieLink{4, 16}, pcieLink{4, 16}, false},
+		{"lower generation", pcieLink{3, 16}, pcieLink{4, 16}, true},
+		{"narrower width", pcieLink{4, 8}, pcieLink{4, 16}, true},
+		{"lower generation and width", pcieLink{1, 1}, pcieLink{4, 16}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			if downgraded := isPCIeLinkDowngraded(tc.current, tc.maximum); downgraded != tc.expected {
+				t.Errorf("expected downgraded %v, got %v", tc.expected, downgraded)
+			}
+		})
+	}
+}
+
+func TestPCIeMaxLinkLabels(t *testing.T) {
+	testCases := []struct {
+		description        string
+		devices            []*resource.MockDevice
+		expectedMaxGen     string
+		expectedMaxWidth   string
+		expectedDowngraded string
+	}{
+		{
+			description: "links at maximum",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 4, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+				{PCIeGen: 4, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+			},
+			expectedMaxGen:     "4",
+			expectedMaxWidth:   "16",
+			expectedDowngraded: "false",
+		},
+		{
+			description: "any downgraded device flags the node",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 4, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+				{PCIeGen: 3, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+			},
+			expectedMaxGen:     "4",
+			expectedMaxWidth:   "16",
+			expectedDowngraded: "true",
+		},
+		{
+			description: "minimum of the maximums is reported",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 3, PCIeWidth: 8, PCIeMaxGen: 3, PCIeMaxWidth: 8},
+				{PCIeGen: 4, PCIeWidth: 16, PCIeMaxGen: 4, PCIeMaxWidth: 16},
+			},
+			expectedMaxGen:     "3",
+			expectedMaxWidth:   "8",
+			expectedDowngraded: "false",
+		},
+		{
+			description: "max labels are absent if the maximum is unsupported",
+			devices: []*resource.MockDevice{
+				{PCIeGen: 4, PCIeWidth: 16, Errors: map[string]error{"GetPCIeMaxLinkInfo": resource.ErrNotSupported}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newPCIeLinkLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.pcie.max-gen", tc.expectedMaxGen)
+			checkLabel(t, labels, "gpu.pcie.max-width", tc.expectedMaxWidth)
+			checkLabel(t, labels, "gpu.pcie.downgraded", tc.expectedDowngraded)
+		})
+	}
+}
+
+func TestSRIOVLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    Labels
+	}{
+		{
+			description: "virtual functions are summed",
+			devices:     []*resource.MockDevice{{SRIOVNumVFs: 4}, {SRIOVNumVFs: 2}},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.sriov.capable": "true",
+				DefaultLabelPrefix + "/gpu.sriov.vfs":     "6",
+			},
+		},
+		{
+			description: "capable without enabled virtual functions",
+			devices:     []*resource.MockDevice{{SRIOVNumVFs: 0}},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.sriov.capable": "true",
+				DefaultLabelPrefix + "/gpu.sriov.vfs":     "0",
+			},
+		},
+		{
+			description: "unsupported device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetSRIOVNumVFs": resource.ErrNotSupported}},
+				{SRIOVNumVFs: 4},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.sriov.capable": "true",
+				DefaultLabelPrefix + "/gpu.sriov.vfs":     "4",
+			},
+		},
+		{
+			description: "node without SR-IOV",
+			devices:     []*resource.MockDevice{{Errors: map[string]error{"GetSRIOVNumVFs": resource.ErrNotSupported}}},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.sriov.capable": "false",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newSRIOVLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
+
+func TestVirtualizationModeLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "none",
+			devices:     []*resource.MockDevice{{VirtualizationMode: resource.VirtualizationNone}},
+			expected:    "none",
+		},
+		{
+			description: "passthrough",
+			devices:     []*resource.MockDevice{{VirtualizationMode: resource.VirtualizationPassthrough}},
+			expected:    "passthrough",
+		},
+		{
+			description: "vf",
+			devices:     []*resource.MockDevice{{VirtualizationMode: resource.VirtualizationVF}},
+			expected:    "vf",
+		},
+		{
+			description: "mixed",
+			devices: []*resource.MockDevice{
+				{VirtualizationMode: resource.VirtualizationPassthrough},
+				{VirtualizationMode: resource.VirtualizationVF},
+			},
+			expected: "mixed",
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetVirtualizationMode": errTest}},
+				{VirtualizationMode: resource.VirtualizationVF},
+			},
+			expected: "vf",
+		},
+		{
+			description: "label is absent if no device reports it",
+			devices:     []*resource.MockDevice{{Error: errTest}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newVirtualizationModeLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.virtualization.mode", tc.expected)
+		})
+	}
+}
+
+func TestInterconnectLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "devices with links",
+			devices:     []*resource.MockDevice{{InterconnectLinks: 4}, {InterconnectLinks: 4}},
+			expected:    "4",
+		},
+		{
+			description: "minimum across devices",
+			devices:     []*resource.MockDevice{{InterconnectLinks: 4}, {InterconnectLinks: 2}},
+			expected:    "2",
+		},
+		{
+			description: "no interconnect",
+			devices:     []*resource.MockDevice{{InterconnectLinks: 0}},
+			expected:    "0",
+		},
+		{
+			description: "device without interconnect",
+			devices:     []*resource.MockDevice{{InterconnectLinks: 4}, {InterconnectLinks: 0}},
+			expected:    "0",
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetInterconnectLinks": errTest}},
+				{InterconnectLinks: 4},
+			},
+			expected: "4",
+		},
+		{
+			description: "label is absent if no device reports it",
+			devices:     []*resource.MockDevice{{Error: errTest}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newInterconnectLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.interconnect.links", tc.expected)
+		})
+	}
+}
+
+func TestIOMMUGroupsLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "groups are sorted numerically",
+			devices:     []*resource.MockDevice{{IOMMUGroup: 12}, {IOMMUGroup: 2}, {IOMMUGroup: 5}},
+			expected:    "2_5_12",
+		},
+		{
+			description: "shared groups are listed once",
+			devices:     []*resource.MockDevice{{IOMMUGroup: 5}, {IOMMUGroup: 5}},
+			expected:    "5",
+		},
+		{
+			description: "IOMMU disabled",
+			devices:     []*resource.MockDevice{{Errors: map[string]error{"GetIOMMUGroup": resource.ErrNotSupported}}},
+			expected:    "disabled",
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetIOMMUGroup": errTest}},
+				{IOMMUGroup: 7},
+			},
+			expected: "7",
+		},
+		{
+			description: "label is absent if no device reports it",
+			devices:     []*resource.MockDevice{{Error: errTest}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newIOMMUGroupsLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.iommu-groups", tc.expected)
+		})
+	}
+}
+
+func TestCountBoards(t *testing.T) {
+	testCases := []struct {
+		description string
+		numDevices  int
+		boardIDs    map[int]uint
+		expected    int
+	}{
+		{
+			description: "no devices",
+			expected:    0,
+		},
+		{
+			description: "one device per board",
+			numDevices:  4,
+			boardIDs:    map[int]uint{0: 10, 1: 11, 2: 12, 3: 13},
+			expected:    4,
+		},
+		{
+			description: "two devices per board",
+			numDevices:  4,
+			boardIDs:    map[int]uint{0: 10, 1: 10, 2: 11, 3: 11},
+			expected:    2,
+		},
+		{
+			description: "board IDs unavailable",
+			numDevices:  4,
+			boardIDs:    map[int]uint{},
+			expected:    4,
+		},
+		{
+			description: "board ID unavailable for some devices",
+			numDevices:  3,
+			boardIDs:    map[int]uint{0: 10, 1: 10},
+			expected:    2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			if boards := countBoards(tc.numDevices, tc.boardIDs); boards != tc.expected {
+				t.Errorf("expected %d boards, got %d", tc.expected, boards)
+			}
+		})
+	}
+}
+
+func TestBoardLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    Labels
+	}{
+		{
+			description: "single-GPU boards",
+			devices:     []*resource.MockDevice{{BoardID: 10}, {BoardID: 11}},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.multi-gpu-board": "false",
+				DefaultLabelPrefix + "/gpu.board-count":     "2",
+			},
+		},
+		{
+			description: "dual-GPU board",
+			devices: []*resource.MockDevice{
+				{BoardID: 10, MultiGPUBoard: true},
+				{BoardID: 10, MultiGPUBoard: true},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.multi-gpu-board": "true",
+				DefaultLabelPrefix + "/gpu.board-count":     "1",
+			},
+		},
+		{
+			description: "shared board ID without multi-GPU flag",
+			devices:     []*resource.MockDevice{{BoardID: 10}, {BoardID: 10}},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.multi-gpu-board": "true",
+				DefaultLabelPrefix + "/gpu.board-count":     "1",
+			},
+		},
+		{
+			description: "board queries not supported",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetBoardID": resource.ErrNotSupported, "IsMultiGPUBoard": resource.ErrNotSupported}},
+				{Errors: map[string]error{"GetBoardID": resource.ErrNotSupported, "IsMultiGPUBoard": resource.ErrNotSupported}},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.multi-gpu-board": "false",
+				DefaultLabelPrefix + "/gpu.board-count":     "2",
+			},
+		},
+		{
+			description: "board ID fails on one device",
+			devices: []*resource.MockDevice{
+				{BoardID: 10},
+				{Errors: map[string]error{"GetBoardID": errTest}},
+				{BoardID: 10},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.multi-gpu-board": "true",
+				DefaultLabelPrefix + "/gpu.board-count":     "2",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newBoardLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
+
+func TestPCIIDsLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    Labels
+	}{
+		{
+			description: "homogeneous devices",
+			devices: []*resource.MockDevice{
+				{PCIDeviceID: "0x0001", PCISubsystemID: "0x00011e3e"},
+				{PCIDeviceID: "0x0001", PCISubsystemID: "0x00011e3e"},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.pci.device-id":    "0x0001",
+				DefaultLabelPrefix + "/gpu.pci.subsystem-id": "0x00011e3e",
+			},
+		},
+		{
+			description: "heterogeneous devices are sorted and deduplicated",
+			devices: []*resource.MockDevice{
+				{PCIDeviceID: "0x0002", PCISubsystemID: "0x00021e3e"},
+				{PCIDeviceID: "0x0001", PCISubsystemID: "0x00011e3e"},
+				{PCIDeviceID: "0x0002", PCISubsystemID: "0x00011e3e"},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.pci.device-id":    "0x0001_0x0002",
+				DefaultLabelPrefix + "/gpu.pci.subsystem-id": "0x00011e3e_0x00021e3e",
+			},
+		},
+		{
+			description: "failing device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetPCIDeviceID": errTest}, PCISubsystemID: "0x00021e3e"},
+				{PCIDeviceID: "0x0001", Errors: map[string]error{"GetPCISubsystemID": errTest}},
+			},
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.pci.device-id":    "0x0001",
+				DefaultLabelPrefix + "/gpu.pci.subsystem-id": "0x00021e3e",
+			},
+		},
+		{
+			description: "all devices failing",
+			devices: []*resource.MockDevice{
+				{Error: errTest},
+			},
+			expected: Labels{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newPCIIDsLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"errors"
+	"strconv"
+
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// newPowerLabeler creates a labeler for the power draw and power limit of the devices.
+// If a value cannot be retrieved for a device it is reported as unknown. In addition,
+// the highest power limit across all devices is reported as the node power limit.
+func newPowerLabeler(prefix string, devices []resource.Device) Labeler {
+	draws := make(map[int]string)
+	limits := make(map[int]string)
+	limitWatts := make(map[int]uint)
+	for i, dev := range devices {
+		draws[i] = labelValueUnknown
+		if draw, err := dev.GetPowerDrawWatts(); err == nil {
+			draws[i] = strconv.FormatUint(uint64(draw), 10)
+		} else if !errors.Is(err, resource.ErrNotSupported) {
+			klog.Warningf("Failed to retrieve power draw for device %d: %v", i, err)
+		}
+
+		limits[i] = labelValueUnknown
+		if limit, err := dev.GetPowerLimitWatts(); err == nil {
+			limits[i] = strconv.FormatUint(uint64(limit), 10)
+			limitWatts[i] = limit
+		} else if !errors.Is(err, resource.ErrNotSupported) {
+			klog.Warningf("Failed to retrieve power limit for device %d: %v", i, err)
+		}
+	}
+
+	return Merge(
+		perDeviceLabels(prefix, "power-draw", len(devices), draws),
+		perDeviceLabels(prefix, "power-limit", len(devices), limits),
+		newPowerLimitLabeler(prefix, limitWatts),
+	)
+}
+
+// newPowerLimitLabeler creates a labeler for the power limit of the node, given the power
+// limits of the devices keyed by device index. If the devices report different limits, the
+// highest limit is reported and the per-device limits are logged.
+func newPowerLimitLabeler(prefix string, limits map[int]uint) Labeler {
+	if len(limits) == 0 {
+		return empty{}
+	}
+
+	var first, highest uint
+	seen, mixed := false, false
+	for _, limit := range limits {
+		if !seen {
+			first, seen = limit, true
+		} else if limit != first {
+			mixed = true
+		}
+		highest = max(highest, limit)
+	}
+	if mixed {
+		klog.Warningf("Devices report different power limits (W) by device index: %v, reporting %d", limits, highest)
+	}
+
+	return Labels{
+		prefix + "/gpu.power.limit": strconv.FormatUint(uint64(highest), 10),
+	}
+}
+
+// newMaxPowerLimitLabeler creates a labeler for the highest maximum power limit supported
+// by the devices. Devices that do not support the query or report a maximum of 0 are
+// skipped, and the label is omitted if no device reports a maximum.
+func newMaxPowerLimitLabeler(prefix string, devices []resource.Device) Labeler {
+	var highest uint
+	for i, dev := range devices {
+		limit, err := dev.GetMaxPowerLimitWatts()
+		if err != nil {
+			if !errors.Is(err, resource.ErrNotSupported) {
+				klog.Warningf("Failed to retrieve max power limit for device %d: %v", i, err)
+			}
+			continue
+		}
+		highest = max(highest, limit)
+	}
+
+	if highest == 0 {
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.power.max-limit": strconv.FormatUint(uint64(highest), 10),
+	}
+}
+
+// newCoolingLabeler creates a labeler for the cooling type of the devices. A device is
+// actively cooled if it reports at least one fan, and passively cooled if it reports no
+// fans or fan queries are not supported. The node is reported as mixed if it has devices
+// of both types. Devices whose fans cannot be retrieved are skipped.
+func newCoolingLabeler(prefix string, devices []resource.Device) Labeler {
+	active, passive := false, false
+	for i, dev := range devices {
+		numFans, err := dev.GetNumFans()
+		if err != nil && !errors.Is(err, resource.ErrNotSupported) {
+			klog.Warningf("Failed to retrieve number of fans for device %d: %v", i, err)
+			continue
+		}
+		if numFans > 0 {
+			active = true
+		} else {
+			passive = true
+		}
+	}
+
+	var cooling string
+	switch {
+	case active && passive:
+		cooling = "mixed"
+	case active:
+		cooling = "active"
+	case passive:
+		cooling = "passive"
+	default:
+		return empty{}
+	}
+
+	return Labels{
+		prefix + "/gpu.cooling": cooling,
+	}
+}
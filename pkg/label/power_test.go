@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// captureLogs returns the log output written by f.
+func captureLogs(t *testing.T, f func()) string {
+	t.Helper()
+	var buf bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&buf)
+	defer func() {
+		klog.Flush()
+		klog.LogToStderr(true)
+	}()
+	f()
+	klog.Flush()
+	return buf.String()
+}
+
+func TestPowerLimitLabel(t *testing.T) {
+	testCases := []struct {
+		description     string
+		devices         []*resource.MockDevice
+		expected        string
+		expectedWarning bool
+	}{
+		{
+			description: "identical limits",
+			devices:     []*resource.MockDevice{{PowerLimitWatts: 250}, {PowerLimitWatts: 250}},
+			expected:    "250",
+		},
+		{
+			description:     "different limits report the maximum",
+			devices:         []*resource.MockDevice{{PowerLimitWatts: 250}, {PowerLimitWatts: 300}, {PowerLimitWatts: 200}},
+			expected:        "300",
+			expectedWarning: true,
+		},
+		{
+			description:     "device reporting 0 W is a different limit",
+			devices:         []*resource.MockDevice{{PowerLimitWatts: 0}, {PowerLimitWatts: 250}, {PowerLimitWatts: 250}},
+			expected:        "250",
+			expectedWarning: true,
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{PowerLimitWatts: 300, Errors: map[string]error{"GetPowerLimitWatts": errTest}},
+				{PowerLimitWatts: 250},
+			},
+			expected: "250",
+		},
+		{
+			description: "label is absent if not supported",
+			devices:     []*resource.MockDevice{{Errors: map[string]error{"GetPowerLimitWatts": resource.ErrNotSupported}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			// The limits are kept in a map, so repeat to cover different iteration orders.
+			for range 10 {
+				var labels Labels
+				logs := captureLogs(t, func() {
+					labels = generateLabels(t, newPowerLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+				})
+				checkLabel(t, labels, "gpu.power.limit", tc.expected)
+				if warned := strings.Contains(logs, "different power limits"); warned != tc.expectedWarning {
+					t.Fatalf("expected warning %v, got logs %q", tc.expectedWarning, logs)
+				}
+			}
+		})
+	}
+}
+
+func TestCoolingLabeler(t *testing.T) {
+	testCases := []struct {
+		description string
+		devices     []*resource.MockDevice
+		expected    string
+	}{
+		{
+			description: "devices with fans",
+			devices:     []*resource.MockDevice{{NumFans: 1}, {NumFans: 2}},
+			expected:    "active",
+		},
+		{
+			description: "devices without fans",
+			devices:     []*resource.MockDevice{{NumFans: 0}, {NumFans: 0}},
+			expected:    "passive",
+		},
+		{
+			description: "fan queries not supported",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetNumFans": resource.ErrNotSupported}},
+			},
+			expected: "passive",
+		},
+		{
+			description: "mixed node",
+			devices:     []*resource.MockDevice{{NumFans: 1}, {NumFans: 0}},
+			expected:    "mixed",
+		},
+		{
+			description: "failing device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetNumFans": errTest}},
+				{NumFans: 1},
+			},
+			expected: "active",
+		},
+		{
+			description: "all devices failing",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetNumFans": errTest}},
+			},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newCoolingLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.cooling", tc.expected)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+// Sharing strategies of the device plugin
+const (
+	sharingStrategyNone        = "none"
+	sharingStrategyTimeSlicing = "time-slicing"
+)
+
+// devicePluginConfig is the part of the device plugin config that describes GPU sharing.
+//
+//	sharing:
+//	  timeSlicing:
+//	    replicas: 4
+//	    resources:
+//	    - name: iluvatar.com/gpu
+//	      replicas: 4
+type devicePluginConfig struct {
+	Sharing struct {
+		TimeSlicing struct {
+			Replicas  int `yaml:"replicas"`
+			Resources []struct {
+				Name     string `yaml:"name"`
+				Replicas int    `yaml:"replicas"`
+			} `yaml:"resources"`
+		} `yaml:"timeSlicing"`
+	} `yaml:"sharing"`
+}
+
+// parseDevicePluginSharing reads the device plugin config file in YAML or JSON format and
+// returns the sharing strategy and the number of replicas of each GPU. The replicas of a
+// resource override the default replicas; the highest count is used if there are several.
+func parseDevicePluginSharing(path string) (strategy string, replicas int, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("read file: %v", err)
+	}
+
+	var config devicePluginConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", 0, fmt.Errorf("unmarshal config: %v", err)
+	}
+
+	timeSlicing := config.Sharing.TimeSlicing
+	replicas = timeSlicing.Replicas
+	for _, r := range timeSlicing.Resources {
+		replicas = max(replicas, r.Replicas)
+	}
+	if replicas < 0 {
+		return "", 0, fmt.Errorf("invalid replicas %d: must not be negative", replicas)
+	}
+	if replicas <= 1 {
+		return sharingStrategyNone, 1, nil
+	}
+
+	return sharingStrategyTimeSlicing, replicas, nil
+}
+
+// newSharingLabeler creates a labeler for the GPU sharing settings of the device plugin
+// config file. No labels are generated if no file is configured, and the sharing strategy
+// is reported as none if the file cannot be read or parsed.
+func newSharingLabeler(prefix, path string) Labeler {
+	if path == "" {
+		return empty{}
+	}
+
+	strategy, replicas, err := parseDevicePluginSharing(path)
+	if err != nil {
+		klog.Warningf("Failed to parse device plugin config file %s, assuming no sharing: %v", path, err)
+		return Labels{
+			prefix + "/gpu.sharing-strategy": sharingStrategyNone,
+		}
+	}
+
+	return Labels{
+		prefix + "/gpu.sharing-strategy": strategy,
+		prefix + "/gpu.replicas":         strconv.Itoa(replicas),
+	}
+}
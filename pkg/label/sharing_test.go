@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDevicePluginSharing(t *testing.T) {
+	testCases := []struct {
+		description      string
+		config           string
+		expectedStrategy string
+		expectedReplicas int
+		expectError      bool
+	}{
+		{
+			description: "time-slicing replicas",
+			config: `
+sharing:
+  timeSlicing:
+    replicas: 4
+`,
+			expectedStrategy: "time-slicing",
+			expectedReplicas: 4,
+		},
+		{
+			description: "highest resource replicas",
+			config: `
+sharing:
+  timeSlicing:
+    replicas: 2
+    resources:
+    - name: iluvatar.com/gpu
+      replicas: 8
+    - name: iluvatar.com/gpu-small
+      replicas: 4
+`,
+			expectedStrategy: "time-slicing",
+			expectedReplicas: 8,
+		},
+		{
+			description:      "JSON config",
+			config:           `{"sharing": {"timeSlicing": {"replicas": 2}}}`,
+			expectedStrategy: "time-slicing",
+			expectedReplicas: 2,
+		},
+		{
+			description: "single replica",
+			config: `
+sharing:
+  timeSlicing:
+    replicas: 1
+`,
+			expectedStrategy: "none",
+			expectedReplicas: 1,
+		},
+		{
+			description:      "no sharing settings",
+			config:           "flags:\n  migStrategy: none\n",
+			expectedStrategy: "none",
+			expectedReplicas: 1,
+		},
+		{
+			description: "negative replicas",
+			config: `
+sharing:
+  timeSlicing:
+    replicas: -1
+`,
+			expectError: true,
+		},
+		{
+			description: "unparsable config",
+			config:      "sharing: [",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tc.config), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			strategy, replicas, err := parseDevicePluginSharing(path)
+			if tc.expectError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strategy != tc.expectedStrategy || replicas != tc.expectedReplicas {
+				t.Errorf("expected %s with %d replicas, got %s with %d replicas", tc.expectedStrategy, tc.expectedReplicas, strategy, replicas)
+			}
+		})
+	}
+}
+
+func TestSharingLabeler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("sharing:\n  timeSlicing:\n    replicas: 4\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	testCases := []struct {
+		description string
+		path        string
+		expected    Labels
+	}{
+		{
+			description: "no config file configured",
+		},
+		{
+			description: "config file",
+			path:        path,
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.sharing-strategy": "time-slicing",
+				DefaultLabelPrefix + "/gpu.replicas":         "4",
+			},
+		},
+		{
+			description: "missing config file",
+			path:        filepath.Join(t.TempDir(), "missing.yaml"),
+			expected: Labels{
+				DefaultLabelPrefix + "/gpu.sharing-strategy": "none",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newSharingLabeler(DefaultLabelPrefix, tc.path))
+			checkLabels(t, labels, tc.expected)
+		})
+	}
+}
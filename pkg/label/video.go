@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+// newVideoEngineLabeler creates a labeler for the number of hardware video encoder and
+// decoder engines per device. The lowest count across all devices is reported, so every
+// device of the node has at least that many engines. A count of 0 means there is no
+// hardware engine. Devices whose engine counts cannot be retrieved are skipped.
+func newVideoEngineLabeler(prefix string, devices []resource.Device) Labeler {
+	labels := make(Labels)
+
+	var encoders []uint
+	var decoders []uint
+	for i, dev := range devices {
+		if count, err := dev.GetVideoEncoderCount(); err != nil {
+			klog.Warningf("Failed to retrieve video encoder count for device %d: %v", i, err)
+		} else {
+			encoders = append(encoders, count)
+		}
+		if count, err := dev.GetVideoDecoderCount(); err != nil {
+			klog.Warningf("Failed to retrieve video decoder count for device %d: %v", i, err)
+		} else {
+			decoders = append(decoders, count)
+		}
+	}
+
+	if len(encoders) != 0 {
+		count := slices.Min(encoders)
+		labels[prefix+"/gpu.video-encoder-count"] = strconv.FormatUint(uint64(count), 10)
+		labels[prefix+"/gpu.has-video-encoder"] = strconv.FormatBool(count > 0)
+	}
+	if len(decoders) != 0 {
+		labels[prefix+"/gpu.video-decoder-count"] = strconv.FormatUint(uint64(slices.Min(decoders)), 10)
+	}
+
+	return labels
+}
+
+// videoCapability describes the media engines of a GPU product.
+type videoCapability struct {
+	Encoder bool     `yaml:"encoder"`
+	Decoder bool     `yaml:"decoder"`
+	Codecs  []string `yaml:"codecs"`
+}
+
+// videoCapabilities maps GPU product names, as reported by resource.Device.GetName, to
+// their media engines. It can be overridden per product by a video capabilities file.
+var videoCapabilities = map[string]videoCapability{
+	"BI-V100":  {},
+	"BI-V150":  {Decoder: true, Codecs: []string{"h264", "hevc"}},
+	"BI-V150S": {Decoder: true, Codecs: []string{"h264", "hevc"}},
+	"MR-V100":  {Decoder: true, Codecs: []string{"h264", "hevc", "jpeg"}},
+}
+
+// readVideoCapabilities reads a video capabilities file in YAML or JSON format, mapping
+// product names to capabilities, and returns the built-in capabilities overridden by it.
+func readVideoCapabilities(path string) (map[string]videoCapability, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %v", err)
+	}
+	var overrides map[string]videoCapability
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("unmarshal capabilities: %v", err)
+	}
+
+	capabilities := maps.Clone(videoCapabilities)
+	for product, capability := range overrides {
+		capabilities[strings.ToUpper(product)] = capability
+	}
+	return capabilities, nil
+}
+
+// newVideoCapabilityLabeler creates a labeler for the video encoding and decoding support of
+// the devices. A device supports encoding or decoding if it has the engine, as reported by
+// the device or, if the device cannot tell, by the capabilities of its product. The node
+// supports it if all devices do. The codecs supported by all devices are listed if the
+// capabilities of every product are known.
+func newVideoCapabilityLabeler(prefix string, devices []resource.Device, capabilitiesFile string) Labeler {
+	capabilities := videoCapabilities
+	if capabilitiesFile != "" {
+		c, err := readVideoCapabilities(capabilitiesFile)
+		if err != nil {
+			klog.Warningf("Failed to read video capabilities file %s, using the built-in capabilities: %v", capabilitiesFile, err)
+		} else {
+			capabilities = c
+		}
+	}
+
+	encoder, decoder := len(devices) > 0, len(devices) > 0
+	var codecs []string
+	codecsKnown := len(devices) > 0
+	for i, dev := range devices {
+		var capability videoCapability
+		known := false
+		if name, err := dev.GetName(); err != nil {
+			klog.Warningf("Failed to retrieve name for device %d: %v", i, err)
+		} else {
+			capability, known = capabilities[strings.ToUpper(name)]
+		}
+
+		if count, err := dev.GetVideoEncoderCount(); err == nil {
+			encoder = encoder && count > 0
+		} else {
+			encoder = encoder && capability.Encoder
+		}
+		if count, err := dev.GetVideoDecoderCount(); err == nil {
+			decoder = decoder && count > 0
+		} else {
+			decoder = decoder && capability.Decoder
+		}
+
+		switch {
+		case !known:
+			codecsKnown = false
+		case i == 0:
+			codecs = slices.Clone(capability.Codecs)
+		default:
+			codecs = slices.DeleteFunc(codecs, func(c string) bool {
+				return !slices.Contains(capability.Codecs, c)
+			})
+		}
+	}
+
+	labels := Labels{
+		prefix + "/gpu.video.encoder": strconv.FormatBool(encoder),
+		prefix + "/gpu.video.decoder": strconv.FormatBool(decoder),
+	}
+	if codecsKnown && len(codecs) > 0 {
+		joined, err := joinLabelValues(codecs)
+		if err != nil {
+			klog.Warningf("Skipping label %s: %v", "gpu.video.codecs", err)
+		} else {
+			labels[prefix+"/gpu.video.codecs"] = joined
+		}
+	}
+
+	return labels
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package label
+
+import (
+	"testing"
+
+	"gitee.com/deep-spark/ix-feature-discovery/pkg/resource"
+)
+
+func TestVideoEngineLabeler(t *testing.T) {
+	testCases := []struct {
+		description      string
+		devices          []*resource.MockDevice
+		expectedEncoders string
+		expectedDecoders string
+		expectedHas      string
+	}{
+		{
+			description:      "devices with engines",
+			devices:          []*resource.MockDevice{{VideoEncoderCount: 2, VideoDecoderCount: 4}},
+			expectedEncoders: "2",
+			expectedDecoders: "4",
+			expectedHas:      "true",
+		},
+		{
+			description:      "devices without engines",
+			devices:          []*resource.MockDevice{{}},
+			expectedEncoders: "0",
+			expectedDecoders: "0",
+			expectedHas:      "false",
+		},
+		{
+			description: "lowest count across devices",
+			devices: []*resource.MockDevice{
+				{VideoEncoderCount: 2, VideoDecoderCount: 1},
+				{VideoEncoderCount: 1, VideoDecoderCount: 4},
+			},
+			expectedEncoders: "1",
+			expectedDecoders: "1",
+			expectedHas:      "true",
+		},
+		{
+			description: "device without an encoder",
+			devices: []*resource.MockDevice{
+				{VideoEncoderCount: 2, VideoDecoderCount: 2},
+				{VideoEncoderCount: 0, VideoDecoderCount: 2},
+			},
+			expectedEncoders: "0",
+			expectedDecoders: "2",
+			expectedHas:      "false",
+		},
+		{
+			description: "failed device is skipped",
+			devices: []*resource.MockDevice{
+				{Errors: map[string]error{"GetVideoEncoderCount": errTest}},
+				{VideoEncoderCount: 1, VideoDecoderCount: 1},
+			},
+			expectedEncoders: "1",
+			expectedDecoders: "0",
+			expectedHas:      "true",
+		},
+		{
+			description: "labels are absent if no device reports them",
+			devices:     []*resource.MockDevice{{Error: errTest}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			labels := generateLabels(t, newVideoEngineLabeler(DefaultLabelPrefix, asDevices(tc.devices...)))
+			checkLabel(t, labels, "gpu.video-encoder-count", tc.expectedEncoders)
+			checkLabel(t, labels, "gpu.video-decoder-count", tc.expectedDecoders)
+			checkLabel(t, labels, "gpu.has-video-encoder", tc.expectedHas)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import "sync"
+
+// CachedManager is a manager that memoises the results of the wrapped manager between Init
+// and Shutdown, so that each query reaches the wrapped manager at most once per cycle.
+// Errors are not cached, so that a failed query can be retried.
+type CachedManager struct {
+	Manager
+
+	mu    sync.Mutex
+	cache *managerCache
+}
+
+// managerCache holds the results of one Init/Shutdown cycle.
+type managerCache struct {
+	devices       *cachedResult[[]Device]
+	driverVersion *cachedResult[string]
+	cudaDriver    *cachedResult[[2]*uint]
+	cudaVersion   *cachedResult[[2]*uint]
+	ixmlVersion   *cachedResult[string]
+}
+
+// cachedResult holds the value returned by a successful query.
+type cachedResult[T any] struct {
+	value T
+}
+
+var _ Manager = (*CachedManager)(nil)
+
+// NewCachedManager creates a new manager that caches the results of the given manager
+func NewCachedManager(manager Manager) *CachedManager {
+	return &CachedManager{
+		Manager: manager,
+		cache:   &managerCache{},
+	}
+}
+
+// Init initializes the wrapped manager and starts with an empty cache
+func (m *CachedManager) Init() error {
+	m.invalidate()
+	return m.Manager.Init()
+}
+
+// Shutdown shuts down the wrapped manager and drops the cached results
+func (m *CachedManager) Shutdown() error {
+	m.invalidate()
+	return m.Manager.Shutdown()
+}
+
+// GetDevices returns the cached devices of the wrapped manager
+func (m *CachedManager) GetDevices() ([]Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cache.devices == nil {
+		devices, err := m.Manager.GetDevices()
+		if err != nil {
+			return nil, err
+		}
+		m.cache.devices = &cachedResult[[]Device]{devices}
+	}
+	return m.cache.devices.value, nil
+}
+
+// GetIXDriverVersion returns the cached ix driver version of the wrapped manager
+func (m *CachedManager) GetIXDriverVersion() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cache.driverVersion == nil {
+		version, err := m.Manager.GetIXDriverVersion()
+		if err != nil {
+			return "", err
+		}
+		m.cache.driverVersion = &cachedResult[string]{version}
+	}
+	return m.cache.driverVersion.value, nil
+}
+
+// GetCudaDriverVersion returns the cached cuda driver version of the wrapped manager
+func (m *CachedManager) GetCudaDriverVersion() (*uint, *uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cache.cudaDriver == nil {
+		major, minor, err := m.Manager.GetCudaDriverVersion()
+		if err != nil {
+			return nil, nil, err
+		}
+		m.cache.cudaDriver = &cachedResult[[2]*uint]{[2]*uint{major, minor}}
+	}
+	return m.cache.cudaDriver.value[0], m.cache.cudaDriver.value[1], nil
+}
+
+// GetCudaRuntimeVersion returns the cached cuda runtime version of the wrapped manager
+func (m *CachedManager) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cache.cudaVersion == nil {
+		major, minor, err := m.Manager.GetCudaRuntimeVersion()
+		if err != nil {
+			return nil, nil, err
+		}
+		m.cache.cudaVersion = &cachedResult[[2]*uint]{[2]*uint{major, minor}}
+	}
+	return m.cache.cudaVersion.value[0], m.cache.cudaVersion.value[1], nil
+}
+
+// GetIXMLVersion returns the cached IXML library version of the wrapped manager
+func (m *CachedManager) GetIXMLVersion() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cache.ixmlVersion == nil {
+		version, err := m.Manager.GetIXMLVersion()
+		if err != nil {
+			return "", err
+		}
+		m.cache.ixmlVersion = &cachedResult[string]{version}
+	}
+	return m.cache.ixmlVersion.value, nil
+}
+
+// DiscoverySource returns the discovery source of the wrapped manager, or an empty string
+// if the wrapped manager does not report one
+func (m *CachedManager) DiscoverySource() string {
+	if s, ok := m.Manager.(DiscoverySourcer); ok {
+		return s.DiscoverySource()
+	}
+	return ""
+}
+
+// CircuitOpen returns whether the circuit breaker of the wrapped manager, if any, is open
+func (m *CachedManager) CircuitOpen() bool {
+	if s, ok := m.Manager.(CircuitStater); ok {
+		return s.CircuitOpen()
+	}
+	return false
+}
+
+// invalidate drops all cached results
+func (m *CachedManager) invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = &managerCache{}
+}
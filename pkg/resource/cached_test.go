@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"sync"
+	"testing"
+)
+
+// callCountingManager is a mock manager that counts the calls of each query method.
+type callCountingManager struct {
+	*MockManager
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// newCallCountingManager creates a call counting mock manager with two devices.
+func newCallCountingManager() *callCountingManager {
+	device := &MockDevice{Name: "MR-V100", TotalMemoryMB: 32768}
+	return &callCountingManager{
+		MockManager: NewMockManager(WithDevices(device, device), WithDriverVersion("4.1.0")),
+		calls:       make(map[string]int),
+	}
+}
+
+// count records a call of the method.
+func (m *callCountingManager) count(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[method]++
+}
+
+// GetDevices counts the call and returns the devices of the mock manager
+func (m *callCountingManager) GetDevices() ([]Device, error) {
+	m.count("GetDevices")
+	return m.MockManager.GetDevices()
+}
+
+// GetIXDriverVersion counts the call and returns the driver version of the mock manager
+func (m *callCountingManager) GetIXDriverVersion() (string, error) {
+	m.count("GetIXDriverVersion")
+	return m.MockManager.GetIXDriverVersion()
+}
+
+// GetCudaDriverVersion counts the call and returns the CUDA driver version of the mock manager
+func (m *callCountingManager) GetCudaDriverVersion() (*uint, *uint, error) {
+	m.count("GetCudaDriverVersion")
+	return m.MockManager.GetCudaDriverVersion()
+}
+
+// GetCudaRuntimeVersion counts the call and returns the CUDA runtime version of the mock manager
+func (m *callCountingManager) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	m.count("GetCudaRuntimeVersion")
+	return m.MockManager.GetCudaRuntimeVersion()
+}
+
+// GetIXMLVersion counts the call and returns the IXML version of the mock manager
+func (m *callCountingManager) GetIXMLVersion() (string, error) {
+	m.count("GetIXMLVersion")
+	return m.MockManager.GetIXMLVersion()
+}
+
+// queryMethods are the query methods of a manager that are cached by a CachedManager.
+var queryMethods = []string{"GetDevices", "GetIXDriverVersion", "GetCudaDriverVersion", "GetCudaRuntimeVersion", "GetIXMLVersion"}
+
+// queryManager calls every query method of the manager and returns the first error.
+func queryManager(m Manager) error {
+	if _, err := m.GetDevices(); err != nil {
+		return err
+	}
+	if _, err := m.GetIXDriverVersion(); err != nil {
+		return err
+	}
+	if _, _, err := m.GetCudaDriverVersion(); err != nil {
+		return err
+	}
+	if _, _, err := m.GetCudaRuntimeVersion(); err != nil {
+		return err
+	}
+	_, err := m.GetIXMLVersion()
+	return err
+}
+
+// checkCalls checks that every query method of the manager was called the expected number of times.
+func checkCalls(t *testing.T, m *callCountingManager, expected int) {
+	t.Helper()
+	for _, method := range queryMethods {
+		if calls := m.calls[method]; calls != expected {
+			t.Errorf("expected %d calls of %s, got %d", expected, method, calls)
+		}
+	}
+}
+
+func TestCachedManagerCallCounts(t *testing.T) {
+	counting := newCallCountingManager()
+	m := NewCachedManager(counting)
+
+	if err := m.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range 3 {
+		if err := queryManager(m); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	checkCalls(t, counting, 1)
+}
+
+func TestCachedManagerInvalidation(t *testing.T) {
+	testCases := []struct {
+		description string
+		between     func(*CachedManager) error
+	}{
+		{
+			description: "Shutdown drops the cached results",
+			between:     (*CachedManager).Shutdown,
+		},
+		{
+			description: "Init drops the cached results",
+			between:     (*CachedManager).Init,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			counting := newCallCountingManager()
+			m := NewCachedManager(counting)
+
+			if err := m.Init(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := queryManager(m); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := tc.between(m); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := queryManager(m); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkCalls(t, counting, 2)
+		})
+	}
+}
+
+func TestCachedManagerErrorsAreNotCached(t *testing.T) {
+	counting := newCallCountingManager()
+	m := NewCachedManager(counting)
+
+	counting.Error = errTest
+	if err := queryManager(m); err == nil {
+		t.Fatal("expected an error")
+	}
+	counting.Error = nil
+	for range 2 {
+		if err := queryManager(m); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The failed query of GetDevices returned before the other methods were called.
+	if calls := counting.calls["GetDevices"]; calls != 2 {
+		t.Errorf("expected 2 calls of GetDevices, got %d", calls)
+	}
+	if calls := counting.calls["GetIXMLVersion"]; calls != 1 {
+		t.Errorf("expected 1 call of GetIXMLVersion, got %d", calls)
+	}
+}
+
+// BenchmarkCachedManagerCalls runs labeling cycles that each query the manager once per
+// labeler, with and without a CachedManager, and reports the number of calls of the most
+// called method of the wrapped manager per cycle.
+func BenchmarkCachedManagerCalls(b *testing.B) {
+	// labelers is roughly the number of labelers that query the manager in a cycle.
+	const labelers = 10
+
+	benchmarks := []struct {
+		description string
+		cached      bool
+	}{
+		{description: "uncached"},
+		{description: "cached", cached: true},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.description, func(b *testing.B) {
+			counting := newCallCountingManager()
+			var manager Manager = counting
+			if bm.cached {
+				manager = NewCachedManager(counting)
+			}
+
+			b.ResetTimer()
+			for range b.N {
+				if err := manager.Init(); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				for range labelers {
+					if err := queryManager(manager); err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
+				if err := manager.Shutdown(); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			most := 0
+			for _, calls := range counting.calls {
+				most = max(most, calls)
+			}
+			b.ReportMetric(float64(most)/float64(b.N), "max-calls/cycle")
+		})
+	}
+}
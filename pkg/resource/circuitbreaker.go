@@ -0,0 +1,487 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreakerManager while its circuit is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// circuitState is the state of a circuit breaker
+type circuitState int
+
+// States of a circuit breaker
+const (
+	// circuitClosed passes all calls to the wrapped manager.
+	circuitClosed circuitState = iota
+	// circuitOpen fails all calls until the cooldown has passed.
+	circuitOpen
+	// circuitHalfOpen passes a single probe call, which closes the circuit if it succeeds
+	// and opens it again if it fails.
+	circuitHalfOpen
+)
+
+// CircuitBreakerManager is a manager that stops calling the wrapped manager after a number
+// of consecutive failures. Calls then fail immediately with ErrCircuitOpen until the
+// cooldown has passed, after which a single probe call decides whether to resume. Errors
+// wrapping ErrNotSupported do not count as failures.
+type CircuitBreakerManager struct {
+	Manager
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var _ Manager = (*CircuitBreakerManager)(nil)
+
+// NewCircuitBreakerManager creates a new manager that opens the circuit to the given manager
+// after threshold consecutive failures, for the duration of cooldown.
+func NewCircuitBreakerManager(manager Manager, threshold int, cooldown time.Duration) *CircuitBreakerManager {
+	return &CircuitBreakerManager{
+		Manager:   manager,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Init initializes the wrapped manager
+func (m *CircuitBreakerManager) Init() error {
+	_, err := callWithBreaker(m, func() (struct{}, error) {
+		return struct{}{}, m.Manager.Init()
+	})
+	return err
+}
+
+// Shutdown shuts down the wrapped manager. It is not subject to the circuit breaker, so
+// that a successfully initialized manager is always shut down.
+func (m *CircuitBreakerManager) Shutdown() error {
+	return m.Manager.Shutdown()
+}
+
+// GetDevices returns the devices of the wrapped manager, whose calls are subject to the
+// circuit breaker as well
+func (m *CircuitBreakerManager) GetDevices() ([]Device, error) {
+	devices, err := callWithBreaker(m, m.Manager.GetDevices)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := make([]Device, len(devices))
+	for i, device := range devices {
+		wrapped[i] = &circuitBreakerDevice{
+			device:  device,
+			manager: m,
+		}
+	}
+	return wrapped, nil
+}
+
+// GetIXDriverVersion returns the ix driver version of the wrapped manager
+func (m *CircuitBreakerManager) GetIXDriverVersion() (string, error) {
+	return callWithBreaker(m, m.Manager.GetIXDriverVersion)
+}
+
+// GetCudaDriverVersion returns the cuda driver version of the wrapped manager
+func (m *CircuitBreakerManager) GetCudaDriverVersion() (*uint, *uint, error) {
+	version, err := callWithBreaker(m, func() ([2]*uint, error) {
+		major, minor, err := m.Manager.GetCudaDriverVersion()
+		return [2]*uint{major, minor}, err
+	})
+	return version[0], version[1], err
+}
+
+// GetCudaRuntimeVersion returns the cuda runtime version of the wrapped manager
+func (m *CircuitBreakerManager) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	version, err := callWithBreaker(m, func() ([2]*uint, error) {
+		major, minor, err := m.Manager.GetCudaRuntimeVersion()
+		return [2]*uint{major, minor}, err
+	})
+	return version[0], version[1], err
+}
+
+// GetIXMLVersion returns the IXML library version of the wrapped manager
+func (m *CircuitBreakerManager) GetIXMLVersion() (string, error) {
+	return callWithBreaker(m, m.Manager.GetIXMLVersion)
+}
+
+// DiscoverySource returns the discovery source of the wrapped manager, or an empty string
+// if the wrapped manager does not report one
+func (m *CircuitBreakerManager) DiscoverySource() string {
+	if s, ok := m.Manager.(DiscoverySourcer); ok {
+		return s.DiscoverySource()
+	}
+	return ""
+}
+
+// CircuitOpen returns whether the circuit is open, including while a probe call decides
+// whether to close it again
+func (m *CircuitBreakerManager) CircuitOpen() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state != circuitClosed
+}
+
+// allow returns whether a call may be passed to the wrapped manager, moving an open circuit
+// whose cooldown has passed to half-open for a single probe call.
+func (m *CircuitBreakerManager) allow() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.state {
+	case circuitOpen:
+		if time.Since(m.openedAt) < m.cooldown {
+			return false
+		}
+		klog.Info("Circuit breaker cooldown has passed, probing the resource manager")
+		m.state = circuitHalfOpen
+		m.probing = true
+		return true
+	case circuitHalfOpen:
+		if m.probing {
+			return false
+		}
+		m.probing = true
+		return true
+	}
+	return true
+}
+
+// record updates the state of the circuit with the result of a call.
+func (m *CircuitBreakerManager) record(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil || errors.Is(err, ErrNotSupported) {
+		if m.state != circuitClosed {
+			klog.Info("Resource manager probe succeeded, closing the circuit breaker")
+		}
+		m.state = circuitClosed
+		m.failures = 0
+		m.probing = false
+		return
+	}
+
+	m.failures++
+	if m.state == circuitHalfOpen || m.failures >= m.threshold {
+		if m.state != circuitOpen {
+			klog.Warningf("Opening the circuit breaker for %v after %d consecutive failures: %v", m.cooldown, m.failures, err)
+		}
+		m.state = circuitOpen
+		m.openedAt = time.Now()
+		m.probing = false
+	}
+}
+
+// circuitBreakerDevice is a device whose calls are subject to the circuit breaker of its
+// manager, so that failing device queries open the circuit as failing manager calls do.
+type circuitBreakerDevice struct {
+	device  Device
+	manager *CircuitBreakerManager
+}
+
+var _ Device = (*circuitBreakerDevice)(nil)
+
+// GetName implements Device
+func (d *circuitBreakerDevice) GetName() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetName)
+}
+
+// GetBrand implements Device
+func (d *circuitBreakerDevice) GetBrand() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetBrand)
+}
+
+// GetFullName implements Device
+func (d *circuitBreakerDevice) GetFullName() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetFullName)
+}
+
+// GetUUID implements Device
+func (d *circuitBreakerDevice) GetUUID() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetUUID)
+}
+
+// GetTotalMemoryMB implements Device
+func (d *circuitBreakerDevice) GetTotalMemoryMB() (uint64, error) {
+	return callWithBreaker(d.manager, d.device.GetTotalMemoryMB)
+}
+
+// GetUsedMemoryMB implements Device
+func (d *circuitBreakerDevice) GetUsedMemoryMB() (uint64, error) {
+	return callWithBreaker(d.manager, d.device.GetUsedMemoryMB)
+}
+
+// GetTemperatureCelsius implements Device
+func (d *circuitBreakerDevice) GetTemperatureCelsius() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetTemperatureCelsius)
+}
+
+// GetUtilizationPercent implements Device
+func (d *circuitBreakerDevice) GetUtilizationPercent() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetUtilizationPercent)
+}
+
+// GetPCIBusID implements Device
+func (d *circuitBreakerDevice) GetPCIBusID() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetPCIBusID)
+}
+
+// GetPCIVendorID implements Device
+func (d *circuitBreakerDevice) GetPCIVendorID() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetPCIVendorID)
+}
+
+// GetPCIDeviceID implements Device
+func (d *circuitBreakerDevice) GetPCIDeviceID() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetPCIDeviceID)
+}
+
+// GetPCISubsystemID implements Device
+func (d *circuitBreakerDevice) GetPCISubsystemID() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetPCISubsystemID)
+}
+
+// GetSerial implements Device
+func (d *circuitBreakerDevice) GetSerial() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetSerial)
+}
+
+// GetBoardPartNumber implements Device
+func (d *circuitBreakerDevice) GetBoardPartNumber() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetBoardPartNumber)
+}
+
+// GetPowerDrawWatts implements Device
+func (d *circuitBreakerDevice) GetPowerDrawWatts() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetPowerDrawWatts)
+}
+
+// GetPowerLimitWatts implements Device
+func (d *circuitBreakerDevice) GetPowerLimitWatts() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetPowerLimitWatts)
+}
+
+// GetMaxPowerLimitWatts implements Device
+func (d *circuitBreakerDevice) GetMaxPowerLimitWatts() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetMaxPowerLimitWatts)
+}
+
+// GetPCIeLinkInfo implements Device
+func (d *circuitBreakerDevice) GetPCIeLinkInfo() (uint, uint, error) {
+	p, err := callWithBreaker(d.manager, func() (pair[uint, uint], error) {
+		a, b, err := d.device.GetPCIeLinkInfo()
+		return pair[uint, uint]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetPCIeMaxLinkInfo implements Device
+func (d *circuitBreakerDevice) GetPCIeMaxLinkInfo() (uint, uint, error) {
+	p, err := callWithBreaker(d.manager, func() (pair[uint, uint], error) {
+		a, b, err := d.device.GetPCIeMaxLinkInfo()
+		return pair[uint, uint]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetVBIOSVersion implements Device
+func (d *circuitBreakerDevice) GetVBIOSVersion() (string, error) {
+	return callWithBreaker(d.manager, d.device.GetVBIOSVersion)
+}
+
+// GetCudaComputeCapability implements Device
+func (d *circuitBreakerDevice) GetCudaComputeCapability() (int, int, error) {
+	p, err := callWithBreaker(d.manager, func() (pair[int, int], error) {
+		a, b, err := d.device.GetCudaComputeCapability()
+		return pair[int, int]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// HasTensorCores implements Device
+func (d *circuitBreakerDevice) HasTensorCores() (bool, error) {
+	return callWithBreaker(d.manager, d.device.HasTensorCores)
+}
+
+// GetFP16Supported implements Device
+func (d *circuitBreakerDevice) GetFP16Supported() (bool, error) {
+	return callWithBreaker(d.manager, d.device.GetFP16Supported)
+}
+
+// GetINT8Supported implements Device
+func (d *circuitBreakerDevice) GetINT8Supported() (bool, error) {
+	return callWithBreaker(d.manager, d.device.GetINT8Supported)
+}
+
+// GetECCMode implements Device
+func (d *circuitBreakerDevice) GetECCMode() (bool, bool, error) {
+	p, err := callWithBreaker(d.manager, func() (pair[bool, bool], error) {
+		a, b, err := d.device.GetECCMode()
+		return pair[bool, bool]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetECCErrors implements Device
+func (d *circuitBreakerDevice) GetECCErrors() (uint64, uint64, error) {
+	p, err := callWithBreaker(d.manager, func() (pair[uint64, uint64], error) {
+		a, b, err := d.device.GetECCErrors()
+		return pair[uint64, uint64]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetRetiredPages implements Device
+func (d *circuitBreakerDevice) GetRetiredPages() (uint, bool, error) {
+	p, err := callWithBreaker(d.manager, func() (pair[uint, bool], error) {
+		a, b, err := d.device.GetRetiredPages()
+		return pair[uint, bool]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetMultiprocessorCount implements Device
+func (d *circuitBreakerDevice) GetMultiprocessorCount() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetMultiprocessorCount)
+}
+
+// GetVideoEncoderCount implements Device
+func (d *circuitBreakerDevice) GetVideoEncoderCount() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetVideoEncoderCount)
+}
+
+// GetVideoDecoderCount implements Device
+func (d *circuitBreakerDevice) GetVideoDecoderCount() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetVideoDecoderCount)
+}
+
+// GetClocksMHz implements Device
+func (d *circuitBreakerDevice) GetClocksMHz() (uint, uint, error) {
+	p, err := callWithBreaker(d.manager, func() (pair[uint, uint], error) {
+		a, b, err := d.device.GetClocksMHz()
+		return pair[uint, uint]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetMaxClock implements Device
+func (d *circuitBreakerDevice) GetMaxClock(clockType ClockType) (uint, error) {
+	return callWithBreaker(d.manager, func() (uint, error) {
+		return d.device.GetMaxClock(clockType)
+	})
+}
+
+// GetMemoryBusWidth implements Device
+func (d *circuitBreakerDevice) GetMemoryBusWidth() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetMemoryBusWidth)
+}
+
+// GetPersistenceMode implements Device
+func (d *circuitBreakerDevice) GetPersistenceMode() (bool, error) {
+	return callWithBreaker(d.manager, d.device.GetPersistenceMode)
+}
+
+// GetNumaNode implements Device
+func (d *circuitBreakerDevice) GetNumaNode() (int, error) {
+	return callWithBreaker(d.manager, d.device.GetNumaNode)
+}
+
+// GetMinorNumber implements Device
+func (d *circuitBreakerDevice) GetMinorNumber() (int, error) {
+	return callWithBreaker(d.manager, d.device.GetMinorNumber)
+}
+
+// GetPartitioningMode implements Device
+func (d *circuitBreakerDevice) GetPartitioningMode() (bool, error) {
+	return callWithBreaker(d.manager, d.device.GetPartitioningMode)
+}
+
+// GetSRIOVNumVFs implements Device
+func (d *circuitBreakerDevice) GetSRIOVNumVFs() (int, error) {
+	return callWithBreaker(d.manager, d.device.GetSRIOVNumVFs)
+}
+
+// GetVirtualizationMode implements Device
+func (d *circuitBreakerDevice) GetVirtualizationMode() (VirtualizationMode, error) {
+	return callWithBreaker(d.manager, d.device.GetVirtualizationMode)
+}
+
+// GetComputeMode implements Device
+func (d *circuitBreakerDevice) GetComputeMode() (ComputeMode, error) {
+	return callWithBreaker(d.manager, d.device.GetComputeMode)
+}
+
+// GetDisplayActive implements Device
+func (d *circuitBreakerDevice) GetDisplayActive() (bool, error) {
+	return callWithBreaker(d.manager, d.device.GetDisplayActive)
+}
+
+// GetInterconnectLinks implements Device
+func (d *circuitBreakerDevice) GetInterconnectLinks() (int, error) {
+	return callWithBreaker(d.manager, d.device.GetInterconnectLinks)
+}
+
+// GetIOMMUGroup implements Device
+func (d *circuitBreakerDevice) GetIOMMUGroup() (int, error) {
+	return callWithBreaker(d.manager, d.device.GetIOMMUGroup)
+}
+
+// GetBoardID implements Device
+func (d *circuitBreakerDevice) GetBoardID() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetBoardID)
+}
+
+// IsMultiGPUBoard implements Device
+func (d *circuitBreakerDevice) IsMultiGPUBoard() (bool, error) {
+	return callWithBreaker(d.manager, d.device.IsMultiGPUBoard)
+}
+
+// GetNumFans implements Device
+func (d *circuitBreakerDevice) GetNumFans() (uint, error) {
+	return callWithBreaker(d.manager, d.device.GetNumFans)
+}
+
+// CheckHealth implements Device
+func (d *circuitBreakerDevice) CheckHealth() error {
+	_, err := callWithBreaker(d.manager, func() (struct{}, error) {
+		return struct{}{}, d.device.CheckHealth()
+	})
+	return err
+}
+
+// callWithBreaker passes f to the wrapped manager of m if the circuit allows it and records
+// its result, or fails with ErrCircuitOpen.
+func callWithBreaker[T any](m *CircuitBreakerManager, f func() (T, error)) (T, error) {
+	if !m.allow() {
+		var zero T
+		return zero, ErrCircuitOpen
+	}
+	value, err := f()
+	m.record(err)
+	return value, err
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingManager is a mock manager that counts the calls to its driver version query.
+type countingManager struct {
+	*MockManager
+	calls int
+}
+
+// GetIXDriverVersion counts the call before returning the driver version
+func (m *countingManager) GetIXDriverVersion() (string, error) {
+	m.calls++
+	return m.MockManager.GetIXDriverVersion()
+}
+
+// expireCooldown makes the cooldown of an open circuit pass.
+func expireCooldown(m *CircuitBreakerManager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.openedAt = time.Now().Add(-m.cooldown)
+}
+
+func TestCircuitBreakerThreshold(t *testing.T) {
+	testCases := []struct {
+		description   string
+		errs          []error
+		expectedOpen  bool
+		expectedCalls int
+	}{
+		{
+			description:   "failures below the threshold",
+			errs:          []error{errTest, errTest},
+			expectedCalls: 2,
+		},
+		{
+			description:   "failures reaching the threshold",
+			errs:          []error{errTest, errTest, errTest, errTest},
+			expectedOpen:  true,
+			expectedCalls: 3,
+		},
+		{
+			description:   "success resets the failures",
+			errs:          []error{errTest, errTest, nil, errTest, errTest},
+			expectedCalls: 5,
+		},
+		{
+			description:   "unsupported queries are not failures",
+			errs:          []error{ErrNotSupported, ErrNotSupported, ErrNotSupported, ErrNotSupported},
+			expectedCalls: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			inner := &countingManager{MockManager: NewMockManager()}
+			m := NewCircuitBreakerManager(inner, 3, time.Hour)
+
+			for i, err := range tc.errs {
+				inner.Error = err
+				_, got := m.GetIXDriverVersion()
+				if inner.calls > i && !errors.Is(got, err) {
+					t.Errorf("call %d: expected error %v, got %v", i, err, got)
+				}
+			}
+			if inner.calls != tc.expectedCalls {
+				t.Errorf("expected %d calls to the wrapped manager, got %d", tc.expectedCalls, inner.calls)
+			}
+			if m.CircuitOpen() != tc.expectedOpen {
+				t.Errorf("expected circuit open %v, got %v", tc.expectedOpen, m.CircuitOpen())
+			}
+			if _, err := m.GetIXDriverVersion(); tc.expectedOpen != errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("expected ErrCircuitOpen %v, got %v", tc.expectedOpen, err)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerCooldown(t *testing.T) {
+	testCases := []struct {
+		description  string
+		probeErr     error
+		expectedOpen bool
+	}{
+		{
+			description: "successful probe closes the circuit",
+		},
+		{
+			description:  "failed probe opens the circuit again",
+			probeErr:     errTest,
+			expectedOpen: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			inner := &countingManager{MockManager: NewMockManager(WithError(errTest))}
+			m := NewCircuitBreakerManager(inner, 1, time.Hour)
+			m.GetIXDriverVersion()
+
+			if _, err := m.GetIXDriverVersion(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("expected ErrCircuitOpen during the cooldown, got %v", err)
+			}
+
+			expireCooldown(m)
+			inner.Error = tc.probeErr
+			if _, err := m.GetIXDriverVersion(); !errors.Is(err, tc.probeErr) {
+				t.Errorf("expected the probe to return %v, got %v", tc.probeErr, err)
+			}
+			if inner.calls != 2 {
+				t.Errorf("expected 2 calls to the wrapped manager, got %d", inner.calls)
+			}
+			if m.CircuitOpen() != tc.expectedOpen {
+				t.Errorf("expected circuit open %v, got %v", tc.expectedOpen, m.CircuitOpen())
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	m := NewCircuitBreakerManager(NewMockManager(), 1, time.Hour)
+	m.record(errTest)
+	if m.allow() {
+		t.Fatal("expected calls to be refused during the cooldown")
+	}
+
+	expireCooldown(m)
+	if !m.allow() {
+		t.Fatal("expected a probe call after the cooldown")
+	}
+	if m.allow() {
+		t.Error("expected a second call to be refused while probing")
+	}
+	if !m.CircuitOpen() {
+		t.Error("expected the circuit to be reported open while probing")
+	}
+
+	m.record(nil)
+	if !m.allow() || m.CircuitOpen() {
+		t.Error("expected the circuit to be closed after a successful probe")
+	}
+}
+
+func TestCircuitBreakerDevices(t *testing.T) {
+	inner := NewMockManager(WithDevices(&MockDevice{Errors: map[string]error{"GetSerial": errTest}}))
+	m := NewCircuitBreakerManager(inner, 2, time.Hour)
+
+	devices, err := m.GetDevices()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for range 2 {
+		if _, err := devices[0].GetSerial(); !errors.Is(err, errTest) {
+			t.Fatalf("expected error %v, got %v", errTest, err)
+		}
+	}
+	if !m.CircuitOpen() {
+		t.Fatal("expected failing device queries to open the circuit")
+	}
+
+	for method, err := range callDeviceMethods(t, devices[0]) {
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("expected %s to return ErrCircuitOpen, got %v", method, err)
+		}
+	}
+}
+
+func TestCircuitBreakerCountsTimeouts(t *testing.T) {
+	slow := &sleepingManager{MockManager: NewMockManager(), delay: time.Second}
+	m := NewCircuitBreakerManager(NewTimeoutManager(slow, 10*time.Millisecond), 1, time.Hour)
+
+	m.GetIXDriverVersion()
+	if !m.CircuitOpen() {
+		t.Fatal("expected a timed-out call to open the circuit")
+	}
+	if _, err := m.GetIXDriverVersion(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+}
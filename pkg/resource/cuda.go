@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cudaRuntimeLibrary is the file name of the unversioned CUDA runtime library.
+const cudaRuntimeLibrary = "libcudart.so"
+
+// cudaRuntimeLibraryDirs are the directories searched for the CUDA runtime library of the
+// CoreX SDK. It is a variable so that it can point to a fake tree in tests.
+var cudaRuntimeLibraryDirs = []string{"/usr/local/corex/lib64", "/usr/local/corex/lib"}
+
+// decodeCudaVersion decodes a CUDA version integer, e.g. 11040 for version 11.4.
+func decodeCudaVersion(version int) (major uint, minor uint) {
+	return uint(version) / 1000, uint(version) % 1000 / 10
+}
+
+// readCudaRuntimeLibraryVersion returns the version of the CUDA runtime library, taken from
+// the names of the versioned library files, e.g. libcudart.so.10.2. If several versions are
+// installed the highest is returned. ErrNotSupported is returned if no library is found.
+func readCudaRuntimeLibraryVersion() (major uint, minor uint, err error) {
+	found := false
+	for _, dir := range cudaRuntimeLibraryDirs {
+		paths, err := filepath.Glob(filepath.Join(dir, cudaRuntimeLibrary+".*"))
+		if err != nil {
+			return 0, 0, fmt.Errorf("could not search for cuda runtime library: %v", err)
+		}
+		for _, path := range paths {
+			libMajor, libMinor, ok := parseCudaRuntimeLibraryVersion(filepath.Base(path))
+			if !ok {
+				continue
+			}
+			if !found || libMajor > major || libMajor == major && libMinor > minor {
+				major, minor = libMajor, libMinor
+			}
+			found = true
+		}
+	}
+	if !found {
+		return 0, 0, ErrNotSupported
+	}
+
+	return major, minor, nil
+}
+
+// parseCudaRuntimeLibraryVersion parses the major and minor version from the name of a
+// versioned CUDA runtime library file. ok is false if the name holds no minor version.
+func parseCudaRuntimeLibraryVersion(name string) (major uint, minor uint, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(name, cudaRuntimeLibrary+"."), ".")
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	libMajor, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	libMinor, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return uint(libMajor), uint(libMinor), true
+}
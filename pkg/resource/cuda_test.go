@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeCudaVersion(t *testing.T) {
+	testCases := []struct {
+		version       int
+		expectedMajor uint
+		expectedMinor uint
+	}{
+		{10020, 10, 2},
+		{11040, 11, 4},
+		{12000, 12, 0},
+		{12080, 12, 8},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.version), func(t *testing.T) {
+			major, minor := decodeCudaVersion(tc.version)
+			if major != tc.expectedMajor || minor != tc.expectedMinor {
+				t.Errorf("expected %d.%d, got %d.%d", tc.expectedMajor, tc.expectedMinor, major, minor)
+			}
+		})
+	}
+}
+
+func TestReadCudaRuntimeLibraryVersion(t *testing.T) {
+	testCases := []struct {
+		description   string
+		files         []string
+		expectedMajor uint
+		expectedMinor uint
+		expectedErr   error
+	}{
+		{
+			description:   "single version",
+			files:         []string{"libcudart.so", "libcudart.so.10.2", "libcudart.so.10.2.89"},
+			expectedMajor: 10,
+			expectedMinor: 2,
+		},
+		{
+			description:   "highest version",
+			files:         []string{"libcudart.so.10.2", "libcudart.so.11.4", "libcudart.so.9.10"},
+			expectedMajor: 11,
+			expectedMinor: 4,
+		},
+		{
+			description: "names without minor version are ignored",
+			files:       []string{"libcudart.so.10", "libcudart.so.x.y"},
+			expectedErr: ErrNotSupported,
+		},
+		{
+			description: "no library",
+			expectedErr: ErrNotSupported,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tc.files {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatalf("failed to create library file: %v", err)
+				}
+			}
+			oldDirs := cudaRuntimeLibraryDirs
+			cudaRuntimeLibraryDirs = []string{dir, filepath.Join(dir, "missing")}
+			t.Cleanup(func() { cudaRuntimeLibraryDirs = oldDirs })
+
+			major, minor, err := readCudaRuntimeLibraryVersion()
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if major != tc.expectedMajor || minor != tc.expectedMinor {
+				t.Errorf("expected %d.%d, got %d.%d", tc.expectedMajor, tc.expectedMinor, major, minor)
+			}
+		})
+	}
+}
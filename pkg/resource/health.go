@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// CheckHealth checks the health of every device of the manager, initializing the manager
+// for the duration of the check. An error is returned for each unhealthy device, or if the
+// devices cannot be retrieved, in which case their health is unknown. A manager without
+// devices is healthy.
+func CheckHealth(manager Manager) error {
+	if err := manager.Init(); err != nil {
+		return fmt.Errorf("failed to initialize resource manager: %w", err)
+	}
+	defer func() {
+		if err := manager.Shutdown(); err != nil {
+			klog.Errorf("failed to shutdown resource manager: %v", err)
+		}
+	}()
+
+	devices, err := manager.GetDevices()
+	if err != nil {
+		return fmt.Errorf("error retrieving devices: %w", err)
+	}
+
+	var errs []error
+	for i, d := range devices {
+		if err := d.CheckHealth(); err != nil {
+			errs = append(errs, fmt.Errorf("device %d is unhealthy: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkDeviceHealth checks that the basic queries of a device succeed and that its error
+// counters, where supported, report no uncorrectable errors. Queries that are not supported
+// by the device do not make it unhealthy.
+func checkDeviceHealth(d Device) error {
+	basicQueries := []struct {
+		name  string
+		query func() error
+	}{
+		{"name", func() error { _, err := d.GetName(); return err }},
+		{"UUID", func() error { _, err := d.GetUUID(); return err }},
+		{"memory", func() error { _, err := d.GetTotalMemoryMB(); return err }},
+		{"temperature", func() error { _, err := d.GetTemperatureCelsius(); return err }},
+	}
+	for _, q := range basicQueries {
+		if err := q.query(); err != nil && !errors.Is(err, ErrNotSupported) {
+			return fmt.Errorf("failed to query %s: %w", q.name, err)
+		}
+	}
+
+	_, double, err := d.GetECCErrors()
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		return fmt.Errorf("failed to query ECC errors: %w", err)
+	}
+	if err == nil && double > 0 {
+		return fmt.Errorf("%d uncorrectable ECC errors", double)
+	}
+
+	_, pending, err := d.GetRetiredPages()
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		return fmt.Errorf("failed to query retired pages: %w", err)
+	}
+	if err == nil && pending {
+		return fmt.Errorf("page retirement pending")
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDeviceHealth(t *testing.T) {
+	testCases := []struct {
+		description string
+		device      *MockDevice
+		expectError bool
+	}{
+		{
+			description: "healthy device",
+			device:      &MockDevice{},
+		},
+		{
+			description: "unsupported queries do not make a device unhealthy",
+			device:      &MockDevice{Error: ErrNotSupported},
+		},
+		{
+			description: "failing basic query",
+			device:      &MockDevice{Errors: map[string]error{"GetUUID": errTest}},
+			expectError: true,
+		},
+		{
+			description: "uncorrectable ECC errors",
+			device:      &MockDevice{ECCUncorrectedErrors: 1},
+			expectError: true,
+		},
+		{
+			description: "corrected ECC errors only",
+			device:      &MockDevice{ECCCorrectedErrors: 10},
+		},
+		{
+			description: "pending page retirement",
+			device:      &MockDevice{RetiredPagesPending: true},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := checkDeviceHealth(tc.device)
+			if tc.expectError && err == nil {
+				t.Error("expected the device to be unhealthy")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("expected the device to be healthy, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	testCases := []struct {
+		description string
+		manager     *MockManager
+		expectedErr error
+	}{
+		{
+			description: "healthy devices",
+			manager:     NewMockManager(WithDevices(&MockDevice{}, &MockDevice{})),
+		},
+		{
+			description: "no devices",
+			manager:     NewMockManager(),
+		},
+		{
+			description: "unhealthy device",
+			manager: NewMockManager(WithDevices(
+				&MockDevice{},
+				&MockDevice{Errors: map[string]error{"CheckHealth": errTest}},
+			)),
+			expectedErr: errTest,
+		},
+		{
+			description: "devices cannot be retrieved",
+			manager:     NewMockManager(WithError(errTest)),
+			expectedErr: errTest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := CheckHealth(tc.manager)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// procfsRoot is the root of the proc filesystem. It is a variable so that it can point to
+// a fake proc tree in tests.
+var procfsRoot = "/proc"
+
+// isVirtualMachine returns whether the node runs under a hypervisor. On x86 the CPU
+// advertises the hypervisor flag in guests; Xen guests also report the hypervisor type
+// in sysfs.
+func isVirtualMachine() bool {
+	if data, err := os.ReadFile(filepath.Join(sysfsRoot, "hypervisor", "type")); err == nil && strings.TrimSpace(string(data)) != "" {
+		return true
+	}
+
+	f, err := os.Open(filepath.Join(procfsRoot, "cpuinfo"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(key) != "flags" {
+			continue
+		}
+		return slices.Contains(strings.Fields(value), "hypervisor")
+	}
+	return false
+}
@@ -17,7 +17,9 @@
 package resource
 
 import (
+	"errors"
 	"fmt"
+	"runtime/debug"
 	"strconv"
 	"strings"
 
@@ -25,6 +27,9 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// goIXMLModulePath is the module path of the go-ixml bindings.
+const goIXMLModulePath = "gitee.com/deep-spark/go-ixml"
+
 type ixmlLib struct {
 }
 
@@ -36,18 +41,120 @@ func NewIXMLManager() Manager {
 	return m
 }
 
-// GetCudaRuntimeVersion : Return the cuda runtime version using IXML
-func (l ixmlLib) GetCudaRuntimeVersion() (*uint, *uint, error) {
+// DiscoverySource returns the IXML discovery source
+func (l ixmlLib) DiscoverySource() string {
+	return DiscoverySourceIXML
+}
+
+// FallbackManager is a manager that uses a primary manager, normally IXML, and falls back
+// to a second manager, normally sysfs, if the primary manager cannot be initialized. The
+// choice is made again on every Init.
+type FallbackManager struct {
+	primary  Manager
+	fallback Manager
+	active   Manager
+}
+
+var _ Manager = (*FallbackManager)(nil)
+
+// NewFallbackManager creates a new manager that falls back from primary to fallback
+func NewFallbackManager(primary, fallback Manager) *FallbackManager {
+	return &FallbackManager{
+		primary:  primary,
+		fallback: fallback,
+		active:   primary,
+	}
+}
+
+// Init initializes the primary manager, or the fallback manager if that fails
+func (m *FallbackManager) Init() error {
+	err := m.primary.Init()
+	if err == nil {
+		m.active = m.primary
+		return nil
+	}
+	klog.Warningf("Failed to initialize primary resource manager, falling back: %v", err)
+
+	if ferr := m.fallback.Init(); ferr != nil {
+		m.active = m.primary
+		return fmt.Errorf("failed to initialize fallback resource manager: %v (primary: %v)", ferr, err)
+	}
+	m.active = m.fallback
+	return nil
+}
+
+// Shutdown shuts down the active manager
+func (m *FallbackManager) Shutdown() error {
+	return m.active.Shutdown()
+}
+
+// GetDevices returns the devices of the active manager
+func (m *FallbackManager) GetDevices() ([]Device, error) {
+	return m.active.GetDevices()
+}
+
+// GetIXDriverVersion returns the ix driver version from the active manager
+func (m *FallbackManager) GetIXDriverVersion() (string, error) {
+	return m.active.GetIXDriverVersion()
+}
+
+// GetCudaDriverVersion returns the cuda driver version from the active manager
+func (m *FallbackManager) GetCudaDriverVersion() (*uint, *uint, error) {
+	return m.active.GetCudaDriverVersion()
+}
+
+// GetCudaRuntimeVersion returns the cuda runtime version from the active manager
+func (m *FallbackManager) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	return m.active.GetCudaRuntimeVersion()
+}
+
+// GetIXMLVersion returns the version of the IXML library from the active manager
+func (m *FallbackManager) GetIXMLVersion() (string, error) {
+	return m.active.GetIXMLVersion()
+}
+
+// DiscoverySource returns the discovery source of the active manager, or an empty string
+// if the active manager does not report one
+func (m *FallbackManager) DiscoverySource() string {
+	if s, ok := m.active.(DiscoverySourcer); ok {
+		return s.DiscoverySource()
+	}
+	return ""
+}
+
+// CircuitOpen returns whether the circuit breaker of the primary manager, if any, is open
+func (m *FallbackManager) CircuitOpen() bool {
+	if s, ok := m.primary.(CircuitStater); ok {
+		return s.CircuitOpen()
+	}
+	return false
+}
+
+// GetCudaDriverVersion : Return the highest cuda version supported by the driver using IXML
+func (l ixmlLib) GetCudaDriverVersion() (*uint, *uint, error) {
 	v, ret := ixml.SystemGetCudaDriverVersion()
 	if ret != ixml.SUCCESS {
-		return nil, nil, fmt.Errorf("failed to get cuda runtime version: %v", ret)
+		return nil, nil, fmt.Errorf("failed to get cuda driver version: %v", ret)
 	}
 	vi, err := strconv.Atoi(v)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to convert cuda runtime version: %v", err)
+		return nil, nil, fmt.Errorf("failed to convert cuda driver version: %v", err)
+	}
+	major, minor := decodeCudaVersion(vi)
+	klog.Infof("success to get cuda driver version, major: %d, minor: %d", major, minor)
+	return &major, &minor, nil
+}
+
+// GetCudaRuntimeVersion : Return the version of the cuda runtime library. If the library is
+// not found, the version is derived from the cuda version supported by the driver.
+func (l ixmlLib) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	major, minor, err := readCudaRuntimeLibraryVersion()
+	if err != nil {
+		if !errors.Is(err, ErrNotSupported) {
+			klog.Warningf("Failed to read cuda runtime library version, using cuda driver version: %v", err)
+		}
+		return l.GetCudaDriverVersion()
 	}
-	major := uint(vi) / 1000
-	minor := uint(vi) % 1000 / 10
 	klog.Infof("success to get cuda runtime version, major: %d, minor: %d", major, minor)
 	return &major, &minor, nil
 }
@@ -86,6 +193,43 @@ func (l ixmlLib) GetIXDriverVersion() (string, error) {
 	return v, nil
 }
 
+// GetIXMLVersion returns the version of the IXML library
+func (l ixmlLib) GetIXMLVersion() (string, error) {
+	v, ret := ixml.SystemGetIXMLVersion()
+	if ret != ixml.SUCCESS {
+		err := fmt.Errorf("failed to get ixml version: %w", ixmlError(ret))
+		if v, ok := goIXMLModuleVersion(); ok {
+			klog.Infof("%v, using the go-ixml module version: %s", err, v)
+			return v, nil
+		}
+		return "", err
+	}
+	klog.Infof("success to get ixml version: %s", v)
+	return v, nil
+}
+
+// goIXMLModuleVersion returns the version of the go-ixml module that the binary was built
+// with, if the build info is available.
+func goIXMLModuleVersion() (string, bool) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", false
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != goIXMLModulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version == "" || dep.Version == "(devel)" {
+			return "", false
+		}
+		return dep.Version, true
+	}
+	return "", false
+}
+
 // Init initialises the library
 func (l ixmlLib) Init() error {
 	ret := ixml.Init()
@@ -118,6 +262,12 @@ func (d ixmlDevice) GetName() (string, error) {
 	}
 	klog.Infof("success to get device name: %s", name)
 
+	return productName(name), nil
+}
+
+// productName returns the product name of a full device name, which is the name without
+// the vendor prefix, e.g. "BI-V150S" for "Iluvatar BI-V150S".
+func productName(name string) string {
 	prefixes := []string{"Iluvatar", "iluvatar"}
 	for _, prefix := range prefixes {
 		if len(name) >= len(prefix) && name[:len(prefix)] == prefix {
@@ -126,9 +276,46 @@ func (d ixmlDevice) GetName() (string, error) {
 		}
 	}
 
+	return strings.TrimSpace(name)
+}
+
+// GetBrand returns the device brand, which is the first word of the full device name,
+// e.g. "Iluvatar" for "Iluvatar BI-V150S". An empty string is returned if the full name
+// has no brand.
+func (d ixmlDevice) GetBrand() (string, error) {
+	name, ret := d.Device.GetName()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device name: %v", ret)
+	}
+
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+// GetFullName returns the full device name including the brand, e.g. "Iluvatar BI-V150S".
+func (d ixmlDevice) GetFullName() (string, error) {
+	name, ret := d.Device.GetName()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device name: %v", ret)
+	}
+
 	return strings.TrimSpace(name), nil
 }
 
+// GetUUID returns the device UUID.
+func (d ixmlDevice) GetUUID() (string, error) {
+	uuid, ret := d.Device.GetUUID()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device uuid: %v", ret)
+	}
+	klog.Infof("success to get device uuid: %s", uuid)
+
+	return uuid, nil
+}
+
 // GetTotalMemoryMB returns the total memory on a device in MB
 func (d ixmlDevice) GetTotalMemoryMB() (uint64, error) {
 	info, ret := d.Device.GetMemoryInfo()
@@ -139,3 +326,587 @@ func (d ixmlDevice) GetTotalMemoryMB() (uint64, error) {
 
 	return info.Total, nil
 }
+
+// GetUsedMemoryMB returns the used memory on a device in MB
+func (d ixmlDevice) GetUsedMemoryMB() (uint64, error) {
+	info, ret := d.Device.GetMemoryInfo()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device memory info: %v", ret)
+	}
+	klog.Infof("success to get device used memory: %d (MB)", info.Used)
+
+	return info.Used, nil
+}
+
+// GetTemperatureCelsius returns the current temperature of a device in degrees Celsius
+func (d ixmlDevice) GetTemperatureCelsius() (uint, error) {
+	temp, ret := d.Device.GetTemperature()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device temperature: %v", ret)
+	}
+	klog.Infof("success to get device temperature: %d (C)", temp)
+
+	return uint(temp), nil
+}
+
+// GetUtilizationPercent returns the current GPU utilization of a device in percent
+func (d ixmlDevice) GetUtilizationPercent() (uint, error) {
+	utilization, ret := d.Device.GetUtilizationRates()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device utilization: %v", ret)
+	}
+	klog.Infof("success to get device utilization: %d (%%)", utilization.GPU)
+
+	return uint(utilization.GPU), nil
+}
+
+// GetPCIBusID returns the PCI bus ID of a device, e.g. "0000:3b:00.0"
+func (d ixmlDevice) GetPCIBusID() (string, error) {
+	info, ret := d.Device.GetPciInfo()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device pci info: %v", ret)
+	}
+	klog.Infof("success to get device pci bus id: %s", info.BusId)
+
+	return strings.ToLower(info.BusId), nil
+}
+
+// GetPCIVendorID returns the PCI vendor ID of a device, e.g. "0x1e3e". It is read from the
+// sysfs entry of the device.
+func (d ixmlDevice) GetPCIVendorID() (string, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return "", err
+	}
+	id, err := readPCIID(busID, "vendor")
+	if err != nil {
+		return "", fmt.Errorf("failed to get device pci vendor id: %v", err)
+	}
+	klog.Infof("success to get device pci vendor id: %s", id)
+
+	return id, nil
+}
+
+// GetPCIDeviceID returns the PCI device ID of a device, e.g. "0x0001". It is read from the
+// sysfs entry of the device.
+func (d ixmlDevice) GetPCIDeviceID() (string, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return "", err
+	}
+	id, err := readPCIID(busID, "device")
+	if err != nil {
+		return "", fmt.Errorf("failed to get device pci device id: %v", err)
+	}
+	klog.Infof("success to get device pci device id: %s", id)
+
+	return id, nil
+}
+
+// GetPCISubsystemID returns the PCI subsystem ID of a device, e.g. "0x00011e3e". It is read
+// from the sysfs entry of the device.
+func (d ixmlDevice) GetPCISubsystemID() (string, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return "", err
+	}
+	id, err := readPCISubsystemID(busID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get device pci subsystem id: %v", err)
+	}
+	klog.Infof("success to get device pci subsystem id: %s", id)
+
+	return id, nil
+}
+
+// GetSerial returns the board serial number of a device
+func (d ixmlDevice) GetSerial() (string, error) {
+	serial, ret := d.Device.GetSerial()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device serial: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device serial: %s", serial)
+
+	return serial, nil
+}
+
+// GetBoardPartNumber returns the board part number of a device
+func (d ixmlDevice) GetBoardPartNumber() (string, error) {
+	partNumber, ret := d.Device.GetBoardPartNumber()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device board part number: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device board part number: %s", partNumber)
+
+	return partNumber, nil
+}
+
+// GetPowerDrawWatts returns the current power draw of a device rounded to whole watts
+func (d ixmlDevice) GetPowerDrawWatts() (uint, error) {
+	power, ret := d.Device.GetPowerUsage()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device power usage: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device power usage: %d (mW)", power)
+
+	return milliwattsToWatts(power), nil
+}
+
+// GetPowerLimitWatts returns the power management limit of a device rounded to whole watts
+func (d ixmlDevice) GetPowerLimitWatts() (uint, error) {
+	limit, ret := d.Device.GetPowerManagementLimit()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device power limit: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device power limit: %d (mW)", limit)
+
+	return milliwattsToWatts(limit), nil
+}
+
+// GetMaxPowerLimitWatts returns the maximum power management limit supported by a device
+// rounded to whole watts
+func (d ixmlDevice) GetMaxPowerLimitWatts() (uint, error) {
+	_, maxLimit, ret := d.Device.GetPowerManagementLimitConstraints()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device power limit constraints: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device max power limit: %d (mW)", maxLimit)
+
+	return milliwattsToWatts(maxLimit), nil
+}
+
+// GetPCIeLinkInfo returns the current PCIe link generation and width of a device. For a device
+// behind a PCIe switch these describe the link to the switch, not the physical slot.
+func (d ixmlDevice) GetPCIeLinkInfo() (uint, uint, error) {
+	gen, ret := d.Device.GetCurrPcieLinkGeneration()
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device pcie link generation: %w", ixmlError(ret))
+	}
+	width, ret := d.Device.GetCurrPcieLinkWidth()
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device pcie link width: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device pcie link info, gen: %d, width: x%d", gen, width)
+
+	return uint(gen), uint(width), nil
+}
+
+// GetPCIeMaxLinkInfo returns the maximum PCIe link generation and width supported by a device
+func (d ixmlDevice) GetPCIeMaxLinkInfo() (uint, uint, error) {
+	gen, ret := d.Device.GetMaxPcieLinkGeneration()
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device max pcie link generation: %w", ixmlError(ret))
+	}
+	width, ret := d.Device.GetMaxPcieLinkWidth()
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device max pcie link width: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device max pcie link info, gen: %d, width: x%d", gen, width)
+
+	return uint(gen), uint(width), nil
+}
+
+// GetVBIOSVersion returns the VBIOS version of a device
+func (d ixmlDevice) GetVBIOSVersion() (string, error) {
+	version, ret := d.Device.GetVbiosVersion()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device vbios version: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device vbios version: %s", version)
+
+	return version, nil
+}
+
+// GetCudaComputeCapability returns the CUDA compute capability of a device
+func (d ixmlDevice) GetCudaComputeCapability() (int, int, error) {
+	major, minor, ret := d.Device.GetCudaComputeCapability()
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device compute capability: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device compute capability, major: %d, minor: %d", major, minor)
+
+	return major, minor, nil
+}
+
+// HasTensorCores returns whether a device has tensor cores. IXML does not expose this
+// directly, so it is derived from the CUDA compute capability: devices with compute
+// capability 7.0 or later have tensor cores, earlier devices do not.
+func (d ixmlDevice) HasTensorCores() (bool, error) {
+	major, _, err := d.GetCudaComputeCapability()
+	if err != nil {
+		return false, err
+	}
+
+	return major >= 7, nil
+}
+
+// GetFP16Supported returns whether a device supports FP16 arithmetic. IXML does not expose
+// this directly, so it is derived from the CUDA compute capability: FP16 arithmetic is
+// supported from compute capability 5.3 on.
+func (d ixmlDevice) GetFP16Supported() (bool, error) {
+	major, minor, err := d.GetCudaComputeCapability()
+	if err != nil {
+		return false, err
+	}
+
+	return major > 5 || (major == 5 && minor >= 3), nil
+}
+
+// GetINT8Supported returns whether a device supports INT8 dot products. IXML does not
+// expose this directly, so it is derived from the CUDA compute capability: INT8 dot
+// products are supported from compute capability 6.1 on.
+func (d ixmlDevice) GetINT8Supported() (bool, error) {
+	major, minor, err := d.GetCudaComputeCapability()
+	if err != nil {
+		return false, err
+	}
+
+	return major > 6 || (major == 6 && minor >= 1), nil
+}
+
+// GetECCMode returns whether ECC is currently enabled on a device, and whether it will be
+// enabled after the next reboot
+func (d ixmlDevice) GetECCMode() (bool, bool, error) {
+	current, pending, ret := d.Device.GetEccMode()
+	if ret != ixml.SUCCESS {
+		return false, false, fmt.Errorf("failed to get device ecc mode: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device ecc mode, current: %v, pending: %v", current, pending)
+
+	return current == ixml.FEATURE_ENABLED, pending == ixml.FEATURE_ENABLED, nil
+}
+
+// GetECCErrors returns the aggregate single-bit (corrected) and double-bit (uncorrected)
+// ECC error counts of a device
+func (d ixmlDevice) GetECCErrors() (uint64, uint64, error) {
+	single, ret := d.Device.GetTotalEccErrors(ixml.MEMORY_ERROR_TYPE_CORRECTED, ixml.AGGREGATE_ECC)
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device corrected ecc errors: %w", ixmlError(ret))
+	}
+	double, ret := d.Device.GetTotalEccErrors(ixml.MEMORY_ERROR_TYPE_UNCORRECTED, ixml.AGGREGATE_ECC)
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device uncorrected ecc errors: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device ecc errors, single: %d, double: %d", single, double)
+
+	return single, double, nil
+}
+
+// GetRetiredPages returns the number of retired memory pages of a device, and whether
+// retirement of any pages is pending until the next reboot
+func (d ixmlDevice) GetRetiredPages() (uint, bool, error) {
+	var count uint
+	causes := []ixml.PageRetirementCause{
+		ixml.PAGE_RETIREMENT_CAUSE_MULTIPLE_SINGLE_BIT_ECC_ERRORS,
+		ixml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR,
+	}
+	for _, cause := range causes {
+		pages, ret := d.Device.GetRetiredPages(cause)
+		if ret != ixml.SUCCESS {
+			return 0, false, fmt.Errorf("failed to get device retired pages: %w", ixmlError(ret))
+		}
+		count += uint(len(pages))
+	}
+	pending, ret := d.Device.GetRetiredPagesPendingStatus()
+	if ret != ixml.SUCCESS {
+		return 0, false, fmt.Errorf("failed to get device retired pages pending status: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device retired pages: %d, pending: %v", count, pending)
+
+	return count, pending == ixml.FEATURE_ENABLED, nil
+}
+
+// GetMultiprocessorCount returns the number of multiprocessors of a device
+func (d ixmlDevice) GetMultiprocessorCount() (uint, error) {
+	attributes, ret := d.Device.GetAttributes()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device attributes: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device multiprocessor count: %d", attributes.MultiprocessorCount)
+
+	return uint(attributes.MultiprocessorCount), nil
+}
+
+// GetVideoEncoderCount returns the number of hardware video encoder engines of a device
+func (d ixmlDevice) GetVideoEncoderCount() (uint, error) {
+	attributes, ret := d.Device.GetAttributes()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device attributes: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device video encoder count: %d", attributes.SharedEncoderCount)
+
+	return uint(attributes.SharedEncoderCount), nil
+}
+
+// GetVideoDecoderCount returns the number of hardware video decoder engines of a device
+func (d ixmlDevice) GetVideoDecoderCount() (uint, error) {
+	attributes, ret := d.Device.GetAttributes()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device attributes: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device video decoder count: %d", attributes.SharedDecoderCount)
+
+	return uint(attributes.SharedDecoderCount), nil
+}
+
+// GetClocksMHz returns the current SM and memory clocks of a device in MHz
+func (d ixmlDevice) GetClocksMHz() (uint, uint, error) {
+	sm, ret := d.Device.GetClockInfo(ixml.CLOCK_SM)
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device sm clock: %w", ixmlError(ret))
+	}
+	mem, ret := d.Device.GetClockInfo(ixml.CLOCK_MEM)
+	if ret != ixml.SUCCESS {
+		return 0, 0, fmt.Errorf("failed to get device memory clock: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device clocks, sm: %d (MHz), memory: %d (MHz)", sm, mem)
+
+	return uint(sm), uint(mem), nil
+}
+
+// GetMaxClock returns the maximum clock of the specified clock domain of a device in MHz
+func (d ixmlDevice) GetMaxClock(clockType ClockType) (uint, error) {
+	var t ixml.ClockType
+	switch clockType {
+	case ClockSM:
+		t = ixml.CLOCK_SM
+	case ClockMemory:
+		t = ixml.CLOCK_MEM
+	default:
+		return 0, fmt.Errorf("unsupported clock type: %v", clockType)
+	}
+	clock, ret := d.Device.GetMaxClockInfo(t)
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device max clock: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device max clock: %d (MHz)", clock)
+
+	return uint(clock), nil
+}
+
+// GetMemoryBusWidth returns the memory bus width of a device in bits
+func (d ixmlDevice) GetMemoryBusWidth() (uint, error) {
+	width, ret := d.Device.GetMemoryBusWidth()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device memory bus width: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device memory bus width: %d (bits)", width)
+
+	return uint(width), nil
+}
+
+// GetPersistenceMode returns whether persistence mode is enabled on a device
+func (d ixmlDevice) GetPersistenceMode() (bool, error) {
+	mode, ret := d.Device.GetPersistenceMode()
+	if ret != ixml.SUCCESS {
+		return false, fmt.Errorf("failed to get device persistence mode: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device persistence mode: %v", mode)
+
+	return mode == ixml.FEATURE_ENABLED, nil
+}
+
+// GetDisplayActive returns whether a display is initialized on a device
+func (d ixmlDevice) GetDisplayActive() (bool, error) {
+	active, ret := d.Device.GetDisplayActive()
+	if ret != ixml.SUCCESS {
+		return false, fmt.Errorf("failed to get device display active: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device display active: %v", active)
+
+	return active == ixml.FEATURE_ENABLED, nil
+}
+
+// GetInterconnectLinks returns the number of active IXLink interconnect links of a device.
+// Links are enumerated until the driver reports that a link does not exist; a device
+// without interconnect has no active links.
+func (d ixmlDevice) GetInterconnectLinks() (int, error) {
+	active := 0
+	for link := 0; link < ixml.IXLINK_MAX_LINKS; link++ {
+		state, ret := d.Device.GetIxLinkState(link)
+		if ret == ixml.ERROR_NOT_SUPPORTED || ret == ixml.ERROR_INVALID_ARGUMENT {
+			break
+		}
+		if ret != ixml.SUCCESS {
+			return 0, fmt.Errorf("failed to get device interconnect link %d state: %w", link, ixmlError(ret))
+		}
+		if state == ixml.FEATURE_ENABLED {
+			active++
+		}
+	}
+	klog.Infof("success to get device interconnect links: %d", active)
+
+	return active, nil
+}
+
+// GetComputeMode returns the compute mode of a device
+func (d ixmlDevice) GetComputeMode() (ComputeMode, error) {
+	mode, ret := d.Device.GetComputeMode()
+	if ret != ixml.SUCCESS {
+		return "", fmt.Errorf("failed to get device compute mode: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device compute mode: %d", mode)
+
+	switch mode {
+	case ixml.COMPUTEMODE_DEFAULT:
+		return ComputeModeDefault, nil
+	case ixml.COMPUTEMODE_EXCLUSIVE_PROCESS:
+		return ComputeModeExclusiveProcess, nil
+	case ixml.COMPUTEMODE_PROHIBITED:
+		return ComputeModeProhibited, nil
+	}
+	return "", fmt.Errorf("unknown compute mode: %d", mode)
+}
+
+// GetNumaNode returns the NUMA node of a device, or -1 if the NUMA node is unknown.
+// IXML does not expose the NUMA node, so it is read from the sysfs entry of the device.
+func (d ixmlDevice) GetNumaNode() (int, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return -1, err
+	}
+	node, err := readNumaNode(busID)
+	if err != nil {
+		return -1, fmt.Errorf("failed to get device numa node: %v", err)
+	}
+	klog.Infof("success to get device numa node: %d", node)
+
+	return node, nil
+}
+
+// GetMinorNumber returns the minor number of the device node of a device
+func (d ixmlDevice) GetMinorNumber() (int, error) {
+	minor, ret := d.Device.GetMinorNumber()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device minor number: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device minor number: %d", minor)
+
+	return minor, nil
+}
+
+// GetBoardID returns the ID of the board of a device. Devices on the same board have the
+// same board ID.
+func (d ixmlDevice) GetBoardID() (uint, error) {
+	id, ret := d.Device.GetBoardId()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device board id: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device board id: %d", id)
+
+	return uint(id), nil
+}
+
+// IsMultiGPUBoard returns whether a device is on a board with multiple GPUs
+func (d ixmlDevice) IsMultiGPUBoard() (bool, error) {
+	multiGPU, ret := d.Device.GetMultiGpuBoard()
+	if ret != ixml.SUCCESS {
+		return false, fmt.Errorf("failed to get device multi-GPU board: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device multi-GPU board: %v", multiGPU != 0)
+
+	return multiGPU != 0, nil
+}
+
+// GetNumFans returns the number of fans on a device. An ErrNotSupported error is returned
+// if the device has no fan control, as is the case for passively cooled devices.
+func (d ixmlDevice) GetNumFans() (uint, error) {
+	numFans, ret := d.Device.GetNumFans()
+	if ret != ixml.SUCCESS {
+		return 0, fmt.Errorf("failed to get device number of fans: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device number of fans: %d", numFans)
+
+	return uint(numFans), nil
+}
+
+// GetPartitioningMode returns whether hardware partitioning is enabled on a device. An
+// ErrNotSupported error is returned if the device cannot be partitioned.
+func (d ixmlDevice) GetPartitioningMode() (bool, error) {
+	current, _, ret := d.Device.GetMigMode()
+	if ret != ixml.SUCCESS {
+		return false, fmt.Errorf("failed to get device partitioning mode: %w", ixmlError(ret))
+	}
+	klog.Infof("success to get device partitioning mode: %d", current)
+
+	return current == ixml.DEVICE_MIG_ENABLE, nil
+}
+
+// CheckHealth returns an error if the basic IXML queries of a device fail or its error
+// counters report uncorrectable errors
+func (d ixmlDevice) CheckHealth() error {
+	return checkDeviceHealth(d)
+}
+
+// GetIOMMUGroup returns the IOMMU group of a device. IXML does not expose the IOMMU group,
+// so it is read from the sysfs entry of the device. An ErrNotSupported error is returned if
+// IOMMU is disabled.
+func (d ixmlDevice) GetIOMMUGroup() (int, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return 0, err
+	}
+	group, err := readIOMMUGroup(busID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get device IOMMU group: %w", err)
+	}
+	klog.Infof("success to get device IOMMU group: %d", group)
+
+	return group, nil
+}
+
+// GetSRIOVNumVFs returns the number of enabled SR-IOV virtual functions of a device. IXML
+// does not expose SR-IOV, so it is read from the sysfs entry of the device. An
+// ErrNotSupported error is returned if the device does not support SR-IOV.
+func (d ixmlDevice) GetSRIOVNumVFs() (int, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return 0, err
+	}
+	numVFs, err := readSRIOVNumVFs(busID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get device SR-IOV VFs: %w", err)
+	}
+	klog.Infof("success to get device SR-IOV VFs: %d", numVFs)
+
+	return numVFs, nil
+}
+
+// GetVirtualizationMode returns the virtualization mode of a device. It is derived from
+// sysfs: SR-IOV virtual functions link to their physical function, and other devices are
+// passed through if the node is a virtual machine.
+func (d ixmlDevice) GetVirtualizationMode() (VirtualizationMode, error) {
+	busID, err := d.GetPCIBusID()
+	if err != nil {
+		return "", err
+	}
+	vf, err := isSRIOVVirtualFunction(busID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get device virtualization mode: %v", err)
+	}
+
+	mode := VirtualizationNone
+	switch {
+	case vf:
+		mode = VirtualizationVF
+	case isVirtualMachine():
+		mode = VirtualizationPassthrough
+	}
+	klog.Infof("success to get device virtualization mode: %s", mode)
+
+	return mode, nil
+}
+
+// milliwattsToWatts converts milliwatts to watts, rounded to the nearest whole watt
+func milliwattsToWatts(mw uint32) uint {
+	return uint((uint64(mw) + 500) / 1000)
+}
+
+// ixmlError converts an IXML return code into an error. ERROR_NOT_SUPPORTED is
+// converted to ErrNotSupported so callers can tell unsupported queries from failures.
+func ixmlError(ret ixml.Return) error {
+	if ret == ixml.ERROR_NOT_SUPPORTED {
+		return ErrNotSupported
+	}
+	return fmt.Errorf("%v", ret)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"testing"
+)
+
+// sourcedManager is a mock manager that reports a discovery source.
+type sourcedManager struct {
+	*MockManager
+	source string
+}
+
+// DiscoverySource returns the discovery source of the manager
+func (m *sourcedManager) DiscoverySource() string {
+	return m.source
+}
+
+func TestFallbackManager(t *testing.T) {
+	testCases := []struct {
+		description           string
+		primaryErr            error
+		fallbackErr           error
+		expectError           bool
+		expectedDriverVersion string
+		expectedSource        string
+	}{
+		{
+			description:           "primary manager is used if it initializes",
+			expectedDriverVersion: "4.1.0",
+			expectedSource:        DiscoverySourceIXML,
+		},
+		{
+			description:           "fallback manager is used if the primary fails",
+			primaryErr:            errTest,
+			expectedDriverVersion: "4.0.0",
+			expectedSource:        DiscoverySourceSysfs,
+		},
+		{
+			description: "error if both managers fail",
+			primaryErr:  errTest,
+			fallbackErr: errors.New("fallback error"),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			primary := &sourcedManager{NewMockManager(WithDriverVersion("4.1.0"), WithError(tc.primaryErr)), DiscoverySourceIXML}
+			fallback := &sourcedManager{NewMockManager(WithDriverVersion("4.0.0"), WithError(tc.fallbackErr)), DiscoverySourceSysfs}
+			m := NewFallbackManager(primary, fallback)
+
+			err := m.Init()
+			if tc.expectError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if version, err := m.GetIXDriverVersion(); err != nil || version != tc.expectedDriverVersion {
+				t.Errorf("expected driver version %q, got %q, %v", tc.expectedDriverVersion, version, err)
+			}
+			if source := m.DiscoverySource(); source != tc.expectedSource {
+				t.Errorf("expected discovery source %q, got %q", tc.expectedSource, source)
+			}
+		})
+	}
+}
+
+func TestFallbackManagerRetriesPrimary(t *testing.T) {
+	primary := &sourcedManager{NewMockManager(WithError(errTest)), DiscoverySourceIXML}
+	fallback := &sourcedManager{NewMockManager(), DiscoverySourceSysfs}
+	m := NewFallbackManager(primary, fallback)
+
+	if err := m.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source := m.DiscoverySource(); source != DiscoverySourceSysfs {
+		t.Fatalf("expected discovery source %q, got %q", DiscoverySourceSysfs, source)
+	}
+
+	// Once the primary manager recovers, the next Init switches back to it.
+	primary.Error = nil
+	if err := m.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source := m.DiscoverySource(); source != DiscoverySourceIXML {
+		t.Errorf("expected discovery source %q, got %q", DiscoverySourceIXML, source)
+	}
+}
+
+func TestProductName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"Iluvatar BI-V150S", "BI-V150S"},
+		{"iluvatar MR-V100", "MR-V100"},
+		{"MR-V100X", "MR-V100X"},
+		{"Iluvatar  BI-V150S  ", "BI-V150S"},
+		{"Iluvatar", ""},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if name := productName(tc.name); name != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,604 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import "fmt"
+
+// MockManager is a Manager with preset return values, for testing labelers without devices.
+type MockManager struct {
+	Devices          []Device
+	DriverVersion    string
+	CudaDriverMajor  uint
+	CudaDriverMinor  uint
+	CudaRuntimeMajor uint
+	CudaRuntimeMinor uint
+	IXMLVersion      string
+	// Error, if set, is returned by every method.
+	Error error
+}
+
+var _ Manager = (*MockManager)(nil)
+
+// MockManagerOption configures a MockManager.
+type MockManagerOption func(*MockManager)
+
+// NewMockManager creates a MockManager with the specified options. By default the manager
+// has no devices and reports driver version 1.0.0, CUDA driver and runtime 10.2 and IXML
+// version 1.0.0.
+func NewMockManager(opts ...MockManagerOption) *MockManager {
+	m := &MockManager{
+		DriverVersion:    "1.0.0",
+		CudaDriverMajor:  10,
+		CudaDriverMinor:  2,
+		CudaRuntimeMajor: 10,
+		CudaRuntimeMinor: 2,
+		IXMLVersion:      "1.0.0",
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithDevices sets the devices of the manager.
+func WithDevices(devices ...*MockDevice) MockManagerOption {
+	return func(m *MockManager) {
+		for _, d := range devices {
+			m.Devices = append(m.Devices, d)
+		}
+	}
+}
+
+// WithDriverVersion sets the driver version of the manager.
+func WithDriverVersion(version string) MockManagerOption {
+	return func(m *MockManager) {
+		m.DriverVersion = version
+	}
+}
+
+// WithCudaDriverVersion sets the CUDA driver version of the manager.
+func WithCudaDriverVersion(major, minor uint) MockManagerOption {
+	return func(m *MockManager) {
+		m.CudaDriverMajor = major
+		m.CudaDriverMinor = minor
+	}
+}
+
+// WithCudaRuntimeVersion sets the CUDA runtime version of the manager.
+func WithCudaRuntimeVersion(major, minor uint) MockManagerOption {
+	return func(m *MockManager) {
+		m.CudaRuntimeMajor = major
+		m.CudaRuntimeMinor = minor
+	}
+}
+
+// WithIXMLVersion sets the IXML version of the manager.
+func WithIXMLVersion(version string) MockManagerOption {
+	return func(m *MockManager) {
+		m.IXMLVersion = version
+	}
+}
+
+// WithError makes every method of the manager return err.
+func WithError(err error) MockManagerOption {
+	return func(m *MockManager) {
+		m.Error = err
+	}
+}
+
+// Init implements Manager
+func (m *MockManager) Init() error {
+	return m.Error
+}
+
+// Shutdown implements Manager
+func (m *MockManager) Shutdown() error {
+	return m.Error
+}
+
+// GetDevices implements Manager
+func (m *MockManager) GetDevices() ([]Device, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return m.Devices, nil
+}
+
+// GetIXDriverVersion implements Manager
+func (m *MockManager) GetIXDriverVersion() (string, error) {
+	if m.Error != nil {
+		return "", m.Error
+	}
+	return m.DriverVersion, nil
+}
+
+// GetCudaDriverVersion implements Manager
+func (m *MockManager) GetCudaDriverVersion() (*uint, *uint, error) {
+	if m.Error != nil {
+		return nil, nil, m.Error
+	}
+	major, minor := m.CudaDriverMajor, m.CudaDriverMinor
+	return &major, &minor, nil
+}
+
+// GetCudaRuntimeVersion implements Manager
+func (m *MockManager) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	if m.Error != nil {
+		return nil, nil, m.Error
+	}
+	major, minor := m.CudaRuntimeMajor, m.CudaRuntimeMinor
+	return &major, &minor, nil
+}
+
+// GetIXMLVersion implements Manager
+func (m *MockManager) GetIXMLVersion() (string, error) {
+	if m.Error != nil {
+		return "", m.Error
+	}
+	return m.IXMLVersion, nil
+}
+
+// MockDevice is a Device with preset return values. Each method returns the corresponding
+// fields, or an error if one is set for it.
+type MockDevice struct {
+	Name                   string
+	Brand                  string
+	FullName               string
+	UUID                   string
+	TotalMemoryMB          uint64
+	UsedMemoryMB           uint64
+	TemperatureCelsius     uint
+	UtilizationPercent     uint
+	PCIBusID               string
+	PCIVendorID            string
+	PCIDeviceID            string
+	PCISubsystemID         string
+	Serial                 string
+	BoardPartNumber        string
+	PowerDrawWatts         uint
+	PowerLimitWatts        uint
+	MaxPowerLimitWatts     uint
+	PCIeGen                uint
+	PCIeWidth              uint
+	PCIeMaxGen             uint
+	PCIeMaxWidth           uint
+	VBIOSVersion           string
+	ComputeCapabilityMajor int
+	ComputeCapabilityMinor int
+	TensorCores            bool
+	FP16Supported          bool
+	INT8Supported          bool
+	ECCEnabled             bool
+	ECCPendingEnabled      bool
+	ECCCorrectedErrors     uint64
+	ECCUncorrectedErrors   uint64
+	RetiredPages           uint
+	RetiredPagesPending    bool
+	MultiprocessorCount    uint
+	VideoEncoderCount      uint
+	VideoDecoderCount      uint
+	SMClockMHz             uint
+	MemoryClockMHz         uint
+	MaxClocksMHz           map[ClockType]uint
+	MemoryBusWidth         uint
+	PersistenceMode        bool
+	NumaNode               int
+	MinorNumber            int
+	PartitioningMode       bool
+	SRIOVNumVFs            int
+	VirtualizationMode     VirtualizationMode
+	ComputeMode            ComputeMode
+	DisplayActive          bool
+	InterconnectLinks      int
+	IOMMUGroup             int
+	BoardID                uint
+	MultiGPUBoard          bool
+	NumFans                uint
+
+	// Errors holds the error returned by individual methods, keyed by method name, e.g.
+	// "GetSerial". It takes precedence over Error.
+	Errors map[string]error
+	// Error, if set, is returned by every method without an entry in Errors.
+	Error error
+}
+
+var _ Device = (*MockDevice)(nil)
+
+// err returns the error to return from the specified method.
+func (d *MockDevice) err(method string) error {
+	if err, ok := d.Errors[method]; ok {
+		return err
+	}
+	return d.Error
+}
+
+// GetName implements Device
+func (d *MockDevice) GetName() (string, error) {
+	if err := d.err("GetName"); err != nil {
+		return "", err
+	}
+	return d.Name, nil
+}
+
+// GetBrand implements Device
+func (d *MockDevice) GetBrand() (string, error) {
+	if err := d.err("GetBrand"); err != nil {
+		return "", err
+	}
+	return d.Brand, nil
+}
+
+// GetFullName implements Device
+func (d *MockDevice) GetFullName() (string, error) {
+	if err := d.err("GetFullName"); err != nil {
+		return "", err
+	}
+	return d.FullName, nil
+}
+
+// GetUUID implements Device
+func (d *MockDevice) GetUUID() (string, error) {
+	if err := d.err("GetUUID"); err != nil {
+		return "", err
+	}
+	return d.UUID, nil
+}
+
+// GetTotalMemoryMB implements Device
+func (d *MockDevice) GetTotalMemoryMB() (uint64, error) {
+	if err := d.err("GetTotalMemoryMB"); err != nil {
+		return 0, err
+	}
+	return d.TotalMemoryMB, nil
+}
+
+// GetUsedMemoryMB implements Device
+func (d *MockDevice) GetUsedMemoryMB() (uint64, error) {
+	if err := d.err("GetUsedMemoryMB"); err != nil {
+		return 0, err
+	}
+	return d.UsedMemoryMB, nil
+}
+
+// GetTemperatureCelsius implements Device
+func (d *MockDevice) GetTemperatureCelsius() (uint, error) {
+	if err := d.err("GetTemperatureCelsius"); err != nil {
+		return 0, err
+	}
+	return d.TemperatureCelsius, nil
+}
+
+// GetUtilizationPercent implements Device
+func (d *MockDevice) GetUtilizationPercent() (uint, error) {
+	if err := d.err("GetUtilizationPercent"); err != nil {
+		return 0, err
+	}
+	return d.UtilizationPercent, nil
+}
+
+// GetPCIBusID implements Device
+func (d *MockDevice) GetPCIBusID() (string, error) {
+	if err := d.err("GetPCIBusID"); err != nil {
+		return "", err
+	}
+	return d.PCIBusID, nil
+}
+
+// GetPCIVendorID implements Device
+func (d *MockDevice) GetPCIVendorID() (string, error) {
+	if err := d.err("GetPCIVendorID"); err != nil {
+		return "", err
+	}
+	return d.PCIVendorID, nil
+}
+
+// GetPCIDeviceID implements Device
+func (d *MockDevice) GetPCIDeviceID() (string, error) {
+	if err := d.err("GetPCIDeviceID"); err != nil {
+		return "", err
+	}
+	return d.PCIDeviceID, nil
+}
+
+// GetPCISubsystemID implements Device
+func (d *MockDevice) GetPCISubsystemID() (string, error) {
+	if err := d.err("GetPCISubsystemID"); err != nil {
+		return "", err
+	}
+	return d.PCISubsystemID, nil
+}
+
+// GetSerial implements Device
+func (d *MockDevice) GetSerial() (string, error) {
+	if err := d.err("GetSerial"); err != nil {
+		return "", err
+	}
+	return d.Serial, nil
+}
+
+// GetBoardPartNumber implements Device
+func (d *MockDevice) GetBoardPartNumber() (string, error) {
+	if err := d.err("GetBoardPartNumber"); err != nil {
+		return "", err
+	}
+	return d.BoardPartNumber, nil
+}
+
+// GetPowerDrawWatts implements Device
+func (d *MockDevice) GetPowerDrawWatts() (uint, error) {
+	if err := d.err("GetPowerDrawWatts"); err != nil {
+		return 0, err
+	}
+	return d.PowerDrawWatts, nil
+}
+
+// GetPowerLimitWatts implements Device
+func (d *MockDevice) GetPowerLimitWatts() (uint, error) {
+	if err := d.err("GetPowerLimitWatts"); err != nil {
+		return 0, err
+	}
+	return d.PowerLimitWatts, nil
+}
+
+// GetMaxPowerLimitWatts implements Device
+func (d *MockDevice) GetMaxPowerLimitWatts() (uint, error) {
+	if err := d.err("GetMaxPowerLimitWatts"); err != nil {
+		return 0, err
+	}
+	return d.MaxPowerLimitWatts, nil
+}
+
+// GetPCIeLinkInfo implements Device
+func (d *MockDevice) GetPCIeLinkInfo() (uint, uint, error) {
+	if err := d.err("GetPCIeLinkInfo"); err != nil {
+		return 0, 0, err
+	}
+	return d.PCIeGen, d.PCIeWidth, nil
+}
+
+// GetPCIeMaxLinkInfo implements Device
+func (d *MockDevice) GetPCIeMaxLinkInfo() (uint, uint, error) {
+	if err := d.err("GetPCIeMaxLinkInfo"); err != nil {
+		return 0, 0, err
+	}
+	return d.PCIeMaxGen, d.PCIeMaxWidth, nil
+}
+
+// GetVBIOSVersion implements Device
+func (d *MockDevice) GetVBIOSVersion() (string, error) {
+	if err := d.err("GetVBIOSVersion"); err != nil {
+		return "", err
+	}
+	return d.VBIOSVersion, nil
+}
+
+// GetCudaComputeCapability implements Device
+func (d *MockDevice) GetCudaComputeCapability() (int, int, error) {
+	if err := d.err("GetCudaComputeCapability"); err != nil {
+		return 0, 0, err
+	}
+	return d.ComputeCapabilityMajor, d.ComputeCapabilityMinor, nil
+}
+
+// HasTensorCores implements Device
+func (d *MockDevice) HasTensorCores() (bool, error) {
+	if err := d.err("HasTensorCores"); err != nil {
+		return false, err
+	}
+	return d.TensorCores, nil
+}
+
+// GetFP16Supported implements Device
+func (d *MockDevice) GetFP16Supported() (bool, error) {
+	if err := d.err("GetFP16Supported"); err != nil {
+		return false, err
+	}
+	return d.FP16Supported, nil
+}
+
+// GetINT8Supported implements Device
+func (d *MockDevice) GetINT8Supported() (bool, error) {
+	if err := d.err("GetINT8Supported"); err != nil {
+		return false, err
+	}
+	return d.INT8Supported, nil
+}
+
+// GetECCMode implements Device
+func (d *MockDevice) GetECCMode() (bool, bool, error) {
+	if err := d.err("GetECCMode"); err != nil {
+		return false, false, err
+	}
+	return d.ECCEnabled, d.ECCPendingEnabled, nil
+}
+
+// GetECCErrors implements Device
+func (d *MockDevice) GetECCErrors() (uint64, uint64, error) {
+	if err := d.err("GetECCErrors"); err != nil {
+		return 0, 0, err
+	}
+	return d.ECCCorrectedErrors, d.ECCUncorrectedErrors, nil
+}
+
+// GetRetiredPages implements Device
+func (d *MockDevice) GetRetiredPages() (uint, bool, error) {
+	if err := d.err("GetRetiredPages"); err != nil {
+		return 0, false, err
+	}
+	return d.RetiredPages, d.RetiredPagesPending, nil
+}
+
+// GetMultiprocessorCount implements Device
+func (d *MockDevice) GetMultiprocessorCount() (uint, error) {
+	if err := d.err("GetMultiprocessorCount"); err != nil {
+		return 0, err
+	}
+	return d.MultiprocessorCount, nil
+}
+
+// GetVideoEncoderCount implements Device
+func (d *MockDevice) GetVideoEncoderCount() (uint, error) {
+	if err := d.err("GetVideoEncoderCount"); err != nil {
+		return 0, err
+	}
+	return d.VideoEncoderCount, nil
+}
+
+// GetVideoDecoderCount implements Device
+func (d *MockDevice) GetVideoDecoderCount() (uint, error) {
+	if err := d.err("GetVideoDecoderCount"); err != nil {
+		return 0, err
+	}
+	return d.VideoDecoderCount, nil
+}
+
+// GetClocksMHz implements Device
+func (d *MockDevice) GetClocksMHz() (uint, uint, error) {
+	if err := d.err("GetClocksMHz"); err != nil {
+		return 0, 0, err
+	}
+	return d.SMClockMHz, d.MemoryClockMHz, nil
+}
+
+// GetMaxClock implements Device
+func (d *MockDevice) GetMaxClock(clockType ClockType) (uint, error) {
+	if err := d.err("GetMaxClock"); err != nil {
+		return 0, err
+	}
+	clock, ok := d.MaxClocksMHz[clockType]
+	if !ok {
+		return 0, fmt.Errorf("no max clock set for clock type %v: %w", clockType, ErrNotSupported)
+	}
+	return clock, nil
+}
+
+// GetMemoryBusWidth implements Device
+func (d *MockDevice) GetMemoryBusWidth() (uint, error) {
+	if err := d.err("GetMemoryBusWidth"); err != nil {
+		return 0, err
+	}
+	return d.MemoryBusWidth, nil
+}
+
+// GetPersistenceMode implements Device
+func (d *MockDevice) GetPersistenceMode() (bool, error) {
+	if err := d.err("GetPersistenceMode"); err != nil {
+		return false, err
+	}
+	return d.PersistenceMode, nil
+}
+
+// GetNumaNode implements Device
+func (d *MockDevice) GetNumaNode() (int, error) {
+	if err := d.err("GetNumaNode"); err != nil {
+		return 0, err
+	}
+	return d.NumaNode, nil
+}
+
+// GetMinorNumber implements Device
+func (d *MockDevice) GetMinorNumber() (int, error) {
+	if err := d.err("GetMinorNumber"); err != nil {
+		return 0, err
+	}
+	return d.MinorNumber, nil
+}
+
+// GetPartitioningMode implements Device
+func (d *MockDevice) GetPartitioningMode() (bool, error) {
+	if err := d.err("GetPartitioningMode"); err != nil {
+		return false, err
+	}
+	return d.PartitioningMode, nil
+}
+
+// GetSRIOVNumVFs implements Device
+func (d *MockDevice) GetSRIOVNumVFs() (int, error) {
+	if err := d.err("GetSRIOVNumVFs"); err != nil {
+		return 0, err
+	}
+	return d.SRIOVNumVFs, nil
+}
+
+// GetVirtualizationMode implements Device
+func (d *MockDevice) GetVirtualizationMode() (VirtualizationMode, error) {
+	if err := d.err("GetVirtualizationMode"); err != nil {
+		return "", err
+	}
+	return d.VirtualizationMode, nil
+}
+
+// GetComputeMode implements Device
+func (d *MockDevice) GetComputeMode() (ComputeMode, error) {
+	if err := d.err("GetComputeMode"); err != nil {
+		return "", err
+	}
+	return d.ComputeMode, nil
+}
+
+// GetDisplayActive implements Device
+func (d *MockDevice) GetDisplayActive() (bool, error) {
+	if err := d.err("GetDisplayActive"); err != nil {
+		return false, err
+	}
+	return d.DisplayActive, nil
+}
+
+// GetInterconnectLinks implements Device
+func (d *MockDevice) GetInterconnectLinks() (int, error) {
+	if err := d.err("GetInterconnectLinks"); err != nil {
+		return 0, err
+	}
+	return d.InterconnectLinks, nil
+}
+
+// GetIOMMUGroup implements Device
+func (d *MockDevice) GetIOMMUGroup() (int, error) {
+	if err := d.err("GetIOMMUGroup"); err != nil {
+		return 0, err
+	}
+	return d.IOMMUGroup, nil
+}
+
+// GetBoardID implements Device
+func (d *MockDevice) GetBoardID() (uint, error) {
+	if err := d.err("GetBoardID"); err != nil {
+		return 0, err
+	}
+	return d.BoardID, nil
+}
+
+// IsMultiGPUBoard implements Device
+func (d *MockDevice) IsMultiGPUBoard() (bool, error) {
+	if err := d.err("IsMultiGPUBoard"); err != nil {
+		return false, err
+	}
+	return d.MultiGPUBoard, nil
+}
+
+// GetNumFans implements Device
+func (d *MockDevice) GetNumFans() (uint, error) {
+	if err := d.err("GetNumFans"); err != nil {
+		return 0, err
+	}
+	return d.NumFans, nil
+}
+
+// CheckHealth implements Device
+func (d *MockDevice) CheckHealth() error {
+	return d.err("CheckHealth")
+}
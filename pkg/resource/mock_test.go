@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// errTest is the error returned by the failing methods of mocks.
+var errTest = errors.New("test error")
+
+func TestNewMockManager(t *testing.T) {
+	device := &MockDevice{Name: "MR-V100"}
+	testCases := []struct {
+		description string
+		opts        []MockManagerOption
+		expected    *MockManager
+	}{
+		{
+			description: "defaults",
+			expected: &MockManager{
+				DriverVersion:    "1.0.0",
+				CudaDriverMajor:  10,
+				CudaDriverMinor:  2,
+				CudaRuntimeMajor: 10,
+				CudaRuntimeMinor: 2,
+				IXMLVersion:      "1.0.0",
+			},
+		},
+		{
+			description: "options",
+			opts: []MockManagerOption{
+				WithDevices(device),
+				WithDriverVersion("4.1.0"),
+				WithCudaDriverVersion(10, 1),
+				WithCudaRuntimeVersion(10, 0),
+				WithIXMLVersion("2.0.0"),
+				WithError(errTest),
+			},
+			expected: &MockManager{
+				Devices:          []Device{device},
+				DriverVersion:    "4.1.0",
+				CudaDriverMajor:  10,
+				CudaDriverMinor:  1,
+				CudaRuntimeMajor: 10,
+				CudaRuntimeMinor: 0,
+				IXMLVersion:      "2.0.0",
+				Error:            errTest,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			if m := NewMockManager(tc.opts...); !reflect.DeepEqual(m, tc.expected) {
+				t.Errorf("expected manager %+v, got %+v", tc.expected, m)
+			}
+		})
+	}
+}
+
+func TestMockManager(t *testing.T) {
+	m := NewMockManager(WithDriverVersion("4.1.0"), WithCudaDriverVersion(10, 1))
+
+	if version, err := m.GetIXDriverVersion(); err != nil || version != "4.1.0" {
+		t.Errorf("expected driver version 4.1.0, got %q, %v", version, err)
+	}
+	if major, minor, err := m.GetCudaDriverVersion(); err != nil || *major != 10 || *minor != 1 {
+		t.Errorf("expected CUDA driver version 10.1, got %d.%d, %v", *major, *minor, err)
+	}
+	if devices, err := m.GetDevices(); err != nil || len(devices) != 0 {
+		t.Errorf("expected no devices, got %v, %v", devices, err)
+	}
+
+	m.Error = errTest
+	for name, call := range map[string]func() error{
+		"Init":                  m.Init,
+		"Shutdown":              m.Shutdown,
+		"GetDevices":            func() error { _, err := m.GetDevices(); return err },
+		"GetIXDriverVersion":    func() error { _, err := m.GetIXDriverVersion(); return err },
+		"GetCudaDriverVersion":  func() error { _, _, err := m.GetCudaDriverVersion(); return err },
+		"GetCudaRuntimeVersion": func() error { _, _, err := m.GetCudaRuntimeVersion(); return err },
+		"GetIXMLVersion":        func() error { _, err := m.GetIXMLVersion(); return err },
+	} {
+		if err := call(); !errors.Is(err, errTest) {
+			t.Errorf("expected %s to return the error, got %v", name, err)
+		}
+	}
+}
+
+// callDeviceMethods calls every method of the Device interface on d and returns their
+// errors, keyed by method name.
+func callDeviceMethods(t *testing.T, d Device) map[string]error {
+	t.Helper()
+	errs := make(map[string]error)
+	deviceType := reflect.TypeOf((*Device)(nil)).Elem()
+	value := reflect.ValueOf(d)
+	for i := range deviceType.NumMethod() {
+		method := deviceType.Method(i)
+		var args []reflect.Value
+		for j := range method.Type.NumIn() {
+			args = append(args, reflect.Zero(method.Type.In(j)))
+		}
+		results := value.MethodByName(method.Name).Call(args)
+		err, _ := results[len(results)-1].Interface().(error)
+		errs[method.Name] = err
+	}
+	return errs
+}
+
+func TestMockDeviceErrors(t *testing.T) {
+	errSerial := errors.New("serial error")
+	d := &MockDevice{
+		Error:  errTest,
+		Errors: map[string]error{"GetSerial": errSerial, "GetName": nil},
+	}
+
+	for method, err := range callDeviceMethods(t, d) {
+		var expected error
+		switch method {
+		case "GetSerial":
+			expected = errSerial
+		case "GetName":
+			expected = nil
+		default:
+			expected = errTest
+		}
+		if !errors.Is(err, expected) {
+			t.Errorf("expected %s to return %v, got %v", method, expected, err)
+		}
+	}
+}
+
+func TestMockDeviceValues(t *testing.T) {
+	d := &MockDevice{
+		Name:            "MR-V100",
+		TotalMemoryMB:   32768,
+		PCIeGen:         4,
+		PCIeWidth:       16,
+		PersistenceMode: true,
+		NumaNode:        1,
+		MaxClocksMHz:    map[ClockType]uint{ClockSM: 1600},
+	}
+
+	if name, err := d.GetName(); err != nil || name != "MR-V100" {
+		t.Errorf("expected name MR-V100, got %q, %v", name, err)
+	}
+	if memory, err := d.GetTotalMemoryMB(); err != nil || memory != 32768 {
+		t.Errorf("expected memory 32768, got %d, %v", memory, err)
+	}
+	if gen, width, err := d.GetPCIeLinkInfo(); err != nil || gen != 4 || width != 16 {
+		t.Errorf("expected PCIe link gen 4 x16, got gen %d x%d, %v", gen, width, err)
+	}
+	if enabled, err := d.GetPersistenceMode(); err != nil || !enabled {
+		t.Errorf("expected persistence mode enabled, got %v, %v", enabled, err)
+	}
+	if node, err := d.GetNumaNode(); err != nil || node != 1 {
+		t.Errorf("expected NUMA node 1, got %d, %v", node, err)
+	}
+	if clock, err := d.GetMaxClock(ClockSM); err != nil || clock != 1600 {
+		t.Errorf("expected max SM clock 1600, got %d, %v", clock, err)
+	}
+	if _, err := d.GetMaxClock(ClockMemory); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected an unset max clock to be unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysfsRoot is the root of the sysfs filesystem. It is a variable so that it can point to a
+// fake sysfs tree in tests.
+var sysfsRoot = "/sys"
+
+// pciDevicePath returns the sysfs directory of the PCI device with the specified bus ID.
+func pciDevicePath(busID string) string {
+	return filepath.Join(sysfsRoot, "bus", "pci", "devices", pciSysfsAddress(busID))
+}
+
+// pciSysfsAddress converts a PCI bus ID to the address format used by sysfs. IXML may
+// report the domain with 8 hex digits ("00000000:3b:00.0"), while sysfs uses 4 ("0000:3b:00.0").
+func pciSysfsAddress(busID string) string {
+	busID = strings.ToLower(busID)
+	domain, rest, found := strings.Cut(busID, ":")
+	if found && len(domain) > 4 {
+		domain = domain[len(domain)-4:]
+		return domain + ":" + rest
+	}
+	return busID
+}
+
+// readPCIID reads a PCI ID attribute, e.g. "vendor", of the PCI device with the specified
+// bus ID from sysfs. The ID is returned in lowercase hex with a 0x prefix, e.g. "0x1e3e".
+func readPCIID(busID string, attribute string) (string, error) {
+	id, err := readSysfsString(filepath.Join(pciDevicePath(busID), attribute))
+	if err != nil {
+		return "", fmt.Errorf("could not read pci %s id: %v", attribute, err)
+	}
+
+	return strings.ToLower(id), nil
+}
+
+// readPCISubsystemID reads the subsystem ID of the PCI device with the specified bus ID from
+// sysfs. It is returned as a single hex value holding the subsystem device ID followed by the
+// subsystem vendor ID, as in the PCI configuration space, e.g. "0x00011e3e".
+func readPCISubsystemID(busID string) (string, error) {
+	vendor, err := readPCIID(busID, "subsystem_vendor")
+	if err != nil {
+		return "", err
+	}
+	device, err := readPCIID(busID, "subsystem_device")
+	if err != nil {
+		return "", err
+	}
+
+	return device + strings.TrimPrefix(vendor, "0x"), nil
+}
+
+// readNumaNode reads the NUMA node of the PCI device with the specified bus ID from sysfs.
+// A value of -1 means that the NUMA node is unknown.
+func readNumaNode(busID string) (int, error) {
+	node, err := readSysfsInt(filepath.Join(pciDevicePath(busID), "numa_node"))
+	if err != nil {
+		return -1, fmt.Errorf("could not read numa node: %v", err)
+	}
+
+	return node, nil
+}
+
+// readSRIOVNumVFs reads the number of enabled SR-IOV virtual functions of the PCI device with
+// the specified bus ID from sysfs. ErrNotSupported is returned if the device does not
+// support SR-IOV.
+func readSRIOVNumVFs(busID string) (int, error) {
+	devicePath := pciDevicePath(busID)
+	totalVFs, err := readSysfsInt(filepath.Join(devicePath, "sriov_totalvfs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrNotSupported
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not read total SR-IOV VFs: %v", err)
+	}
+	if totalVFs == 0 {
+		return 0, ErrNotSupported
+	}
+
+	numVFs, err := readSysfsInt(filepath.Join(devicePath, "sriov_numvfs"))
+	if err != nil {
+		return 0, fmt.Errorf("could not read SR-IOV VFs: %v", err)
+	}
+
+	return numVFs, nil
+}
+
+// isSRIOVVirtualFunction returns whether the PCI device with the specified bus ID is an
+// SR-IOV virtual function, i.e. whether it links to a physical function in sysfs.
+func isSRIOVVirtualFunction(busID string) (bool, error) {
+	_, err := os.Lstat(filepath.Join(pciDevicePath(busID), "physfn"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not read physical function link: %v", err)
+	}
+	return true, nil
+}
+
+// readIOMMUGroup reads the IOMMU group of the PCI device with the specified bus ID from
+// sysfs. ErrNotSupported is returned if IOMMU is disabled on the host, i.e. if there are no
+// IOMMU groups.
+func readIOMMUGroup(busID string) (int, error) {
+	groups, err := os.ReadDir(filepath.Join(sysfsRoot, "kernel", "iommu_groups"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrNotSupported
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not read IOMMU groups: %v", err)
+	}
+	if len(groups) == 0 {
+		return 0, ErrNotSupported
+	}
+
+	link, err := os.Readlink(filepath.Join(pciDevicePath(busID), "iommu_group"))
+	if err != nil {
+		return 0, fmt.Errorf("could not read IOMMU group link: %v", err)
+	}
+	group, err := strconv.Atoi(filepath.Base(link))
+	if err != nil {
+		return 0, fmt.Errorf("could not parse IOMMU group link %q: %v", link, err)
+	}
+
+	return group, nil
+}
+
+// readSysfsInt reads a sysfs file holding a single integer.
+func readSysfsInt(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %q: %v", data, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,265 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSysfs points sysfsRoot to a temporary directory for the duration of the test, and
+// creates the files, keyed by path relative to the PCI device directory of busID.
+func fakeSysfs(t *testing.T, busID string, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	devicePath := filepath.Join(root, "bus", "pci", "devices", pciSysfsAddress(busID))
+	if err := os.MkdirAll(devicePath, 0755); err != nil {
+		t.Fatalf("failed to create fake sysfs: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(devicePath, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create fake sysfs: %v", err)
+		}
+	}
+
+	oldRoot := sysfsRoot
+	sysfsRoot = root
+	t.Cleanup(func() { sysfsRoot = oldRoot })
+}
+
+func TestReadSRIOVNumVFs(t *testing.T) {
+	const busID = "00000000:3B:00.0"
+
+	testCases := []struct {
+		description string
+		files       map[string]string
+		expected    int
+		expectedErr error
+	}{
+		{
+			description: "enabled virtual functions",
+			files:       map[string]string{"sriov_totalvfs": "16\n", "sriov_numvfs": "4\n"},
+			expected:    4,
+		},
+		{
+			description: "no enabled virtual functions",
+			files:       map[string]string{"sriov_totalvfs": "16\n", "sriov_numvfs": "0\n"},
+			expected:    0,
+		},
+		{
+			description: "device without SR-IOV",
+			files:       map[string]string{},
+			expectedErr: ErrNotSupported,
+		},
+		{
+			description: "device without virtual functions",
+			files:       map[string]string{"sriov_totalvfs": "0\n", "sriov_numvfs": "0\n"},
+			expectedErr: ErrNotSupported,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			fakeSysfs(t, busID, tc.files)
+			numVFs, err := readSRIOVNumVFs(busID)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if numVFs != tc.expected {
+				t.Errorf("expected %d virtual functions, got %d", tc.expected, numVFs)
+			}
+		})
+	}
+}
+
+func TestSysfsDeviceVirtualizationMode(t *testing.T) {
+	const busID = "0000:3b:00.0"
+
+	testCases := []struct {
+		description string
+		physfn      bool
+		cpuFlags    string
+		expected    VirtualizationMode
+	}{
+		{
+			description: "physical device on bare metal",
+			cpuFlags:    "fpu vme sse sse2",
+			expected:    VirtualizationNone,
+		},
+		{
+			description: "physical device in a virtual machine",
+			cpuFlags:    "fpu vme sse sse2 hypervisor",
+			expected:    VirtualizationPassthrough,
+		},
+		{
+			description: "virtual function",
+			physfn:      true,
+			cpuFlags:    "fpu vme sse sse2 hypervisor",
+			expected:    VirtualizationVF,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			files := map[string]string{}
+			if tc.physfn {
+				files["physfn"] = ""
+			}
+			fakeSysfs(t, busID, files)
+
+			procfs := t.TempDir()
+			if err := os.WriteFile(filepath.Join(procfs, "cpuinfo"), []byte("processor\t: 0\nflags\t\t: "+tc.cpuFlags+"\n"), 0644); err != nil {
+				t.Fatalf("failed to create fake procfs: %v", err)
+			}
+			oldRoot := procfsRoot
+			procfsRoot = procfs
+			t.Cleanup(func() { procfsRoot = oldRoot })
+
+			mode, err := sysfsDevice{busID: busID}.GetVirtualizationMode()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mode != tc.expected {
+				t.Errorf("expected mode %q, got %q", tc.expected, mode)
+			}
+		})
+	}
+}
+
+func TestReadIOMMUGroup(t *testing.T) {
+	const busID = "0000:3b:00.0"
+
+	testCases := []struct {
+		description string
+		groups      []string
+		link        string
+		expected    int
+		expectedErr error
+		expectError bool
+	}{
+		{
+			description: "device in a group",
+			groups:      []string{"5", "12"},
+			link:        "12",
+			expected:    12,
+		},
+		{
+			description: "IOMMU disabled",
+			expectedErr: ErrNotSupported,
+			expectError: true,
+		},
+		{
+			description: "device without a group",
+			groups:      []string{"5"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			fakeSysfs(t, busID, nil)
+			groupsPath := filepath.Join(sysfsRoot, "kernel", "iommu_groups")
+			for _, group := range tc.groups {
+				if err := os.MkdirAll(filepath.Join(groupsPath, group), 0755); err != nil {
+					t.Fatalf("failed to create fake sysfs: %v", err)
+				}
+			}
+			if tc.link != "" {
+				target := filepath.Join("..", "..", "..", "..", "kernel", "iommu_groups", tc.link)
+				if err := os.Symlink(target, filepath.Join(pciDevicePath(busID), "iommu_group")); err != nil {
+					t.Fatalf("failed to create fake sysfs: %v", err)
+				}
+			}
+
+			group, err := readIOMMUGroup(busID)
+			if tc.expectError {
+				if err == nil || (tc.expectedErr != nil && !errors.Is(err, tc.expectedErr)) {
+					t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if group != tc.expected {
+				t.Errorf("expected group %d, got %d", tc.expected, group)
+			}
+		})
+	}
+}
+
+func TestReadPCIIDs(t *testing.T) {
+	const busID = "0000:3b:00.0"
+
+	testCases := []struct {
+		description         string
+		files               map[string]string
+		expectedDeviceID    string
+		expectedSubsystemID string
+		expectError         bool
+	}{
+		{
+			description: "lowercase IDs",
+			files: map[string]string{
+				"device":           "0x0001\n",
+				"subsystem_vendor": "0x1e3e\n",
+				"subsystem_device": "0x0002\n",
+			},
+			expectedDeviceID:    "0x0001",
+			expectedSubsystemID: "0x00021e3e",
+		},
+		{
+			description: "uppercase IDs",
+			files: map[string]string{
+				"device":           "0x00AB\n",
+				"subsystem_vendor": "0x1E3E\n",
+				"subsystem_device": "0x00CD\n",
+			},
+			expectedDeviceID:    "0x00ab",
+			expectedSubsystemID: "0x00cd1e3e",
+		},
+		{
+			description: "missing attributes",
+			files:       map[string]string{},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			fakeSysfs(t, busID, tc.files)
+
+			deviceID, err := readPCIID(busID, "device")
+			if tc.expectError != (err != nil) {
+				t.Fatalf("unexpected error for device ID: %v", err)
+			}
+			if deviceID != tc.expectedDeviceID {
+				t.Errorf("expected device ID %q, got %q", tc.expectedDeviceID, deviceID)
+			}
+
+			subsystemID, err := readPCISubsystemID(busID)
+			if tc.expectError != (err != nil) {
+				t.Fatalf("unexpected error for subsystem ID: %v", err)
+			}
+			if subsystemID != tc.expectedSubsystemID {
+				t.Errorf("expected subsystem ID %q, got %q", tc.expectedSubsystemID, subsystemID)
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// iluvatarPCIVendorID is the PCI vendor ID of Iluvatar CoreX.
+const iluvatarPCIVendorID = "0x1e3e"
+
+// sysfsDeviceNames maps the PCI device IDs of Iluvatar GPUs to their product names.
+var sysfsDeviceNames = map[string]string{
+	"0x0001": "BI-V100",
+	"0x0002": "BI-V150",
+	"0x0003": "MR-V100",
+}
+
+// pcieLinkSpeedGenerations maps the PCIe link speeds reported by sysfs to PCIe generations.
+var pcieLinkSpeedGenerations = map[string]uint{
+	"2.5":  1,
+	"5.0":  2,
+	"8.0":  3,
+	"16.0": 4,
+	"32.0": 5,
+	"64.0": 6,
+}
+
+type sysfsLib struct {
+}
+
+var _ Manager = (*sysfsLib)(nil)
+
+// NewSysfsManager creates a new manager that discovers devices from the PCI devices in sysfs.
+// It does not require IXML, but only reports the device properties that sysfs exposes;
+// other queries return ErrNotSupported.
+func NewSysfsManager() Manager {
+	return sysfsLib{}
+}
+
+// DiscoverySource returns the sysfs discovery source
+func (l sysfsLib) DiscoverySource() string {
+	return DiscoverySourceSysfs
+}
+
+// Init checks that the PCI devices can be read from sysfs
+func (l sysfsLib) Init() error {
+	if _, err := os.Stat(filepath.Join(sysfsRoot, "bus", "pci", "devices")); err != nil {
+		return fmt.Errorf("failed to access sysfs PCI devices: %v", err)
+	}
+	return nil
+}
+
+// Shutdown does nothing, as the sysfs manager holds no resources
+func (l sysfsLib) Shutdown() error {
+	return nil
+}
+
+// GetDevices returns the Iluvatar GPUs found in sysfs, ordered by PCI bus ID
+func (l sysfsLib) GetDevices() ([]Device, error) {
+	sysfsDevices, err := l.devices()
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, d := range sysfsDevices {
+		devices = append(devices, d)
+	}
+	klog.Infof("success to get sysfs devices: %d", len(devices))
+
+	return devices, nil
+}
+
+// devices returns the PCI devices in sysfs that are Iluvatar GPUs.
+func (l sysfsLib) devices() ([]sysfsDevice, error) {
+	entries, err := os.ReadDir(filepath.Join(sysfsRoot, "bus", "pci", "devices"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sysfs PCI devices: %v", err)
+	}
+
+	var devices []sysfsDevice
+	for _, entry := range entries {
+		busID := entry.Name()
+		vendor, err := readSysfsString(filepath.Join(pciDevicePath(busID), "vendor"))
+		if err != nil || vendor != iluvatarPCIVendorID {
+			continue
+		}
+		class, err := readSysfsString(filepath.Join(pciDevicePath(busID), "class"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read class of PCI device %s: %v", busID, err)
+		}
+		// Only display controllers (0x03) and processing accelerators (0x12) are GPUs.
+		if !strings.HasPrefix(class, "0x03") && !strings.HasPrefix(class, "0x12") {
+			continue
+		}
+		devices = append(devices, sysfsDevice{busID: busID})
+	}
+
+	return devices, nil
+}
+
+// GetIXDriverVersion returns the version of the kernel module bound to the devices
+func (l sysfsLib) GetIXDriverVersion() (string, error) {
+	devices, err := l.devices()
+	if err != nil {
+		return "", err
+	}
+	if len(devices) == 0 {
+		return "", fmt.Errorf("failed to get ix driver version: no devices: %w", ErrNotSupported)
+	}
+
+	module, err := filepath.EvalSymlinks(filepath.Join(pciDevicePath(devices[0].busID), "driver", "module"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get ix driver module: %v", err)
+	}
+	version, err := readSysfsString(filepath.Join(module, "version"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get ix driver version: %v", err)
+	}
+	klog.Infof("success to get ix driver version: %s", version)
+
+	return version, nil
+}
+
+// GetCudaDriverVersion is not supported without IXML
+func (l sysfsLib) GetCudaDriverVersion() (*uint, *uint, error) {
+	return nil, nil, ErrNotSupported
+}
+
+// GetCudaRuntimeVersion returns the version of the cuda runtime library, if it is found
+func (l sysfsLib) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	major, minor, err := readCudaRuntimeLibraryVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &major, &minor, nil
+}
+
+// GetIXMLVersion is not supported without IXML
+func (l sysfsLib) GetIXMLVersion() (string, error) {
+	return "", ErrNotSupported
+}
+
+type sysfsDevice struct {
+	busID string
+}
+
+var _ Device = (*sysfsDevice)(nil)
+
+// GetName returns the product name of a device, looked up from its PCI device ID
+func (d sysfsDevice) GetName() (string, error) {
+	id, err := readSysfsString(filepath.Join(pciDevicePath(d.busID), "device"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get device id: %v", err)
+	}
+	if name, ok := sysfsDeviceNames[id]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown device id %s", id)
+}
+
+// GetTotalMemoryMB returns the size of the largest BAR of a device in MB. The memory BAR
+// maps the whole device memory only if the BAR is resizable, so this may underestimate
+// the memory.
+func (d sysfsDevice) GetTotalMemoryMB() (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(pciDevicePath(d.busID), "resource"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get device resources: %v", err)
+	}
+
+	var largest uint64
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		start, err1 := strconv.ParseUint(fields[0], 0, 64)
+		end, err2 := strconv.ParseUint(fields[1], 0, 64)
+		if err1 != nil || err2 != nil || end <= start {
+			continue
+		}
+		largest = max(largest, end-start+1)
+	}
+	if largest == 0 {
+		return 0, fmt.Errorf("failed to get device memory: no memory BAR")
+	}
+
+	return largest >> 20, nil
+}
+
+// GetPCIBusID returns the PCI bus ID of a device
+func (d sysfsDevice) GetPCIBusID() (string, error) {
+	return d.busID, nil
+}
+
+// GetPCIVendorID returns the PCI vendor ID of a device
+func (d sysfsDevice) GetPCIVendorID() (string, error) {
+	return readPCIID(d.busID, "vendor")
+}
+
+// GetPCIDeviceID returns the PCI device ID of a device
+func (d sysfsDevice) GetPCIDeviceID() (string, error) {
+	return readPCIID(d.busID, "device")
+}
+
+// GetPCISubsystemID returns the PCI subsystem ID of a device
+func (d sysfsDevice) GetPCISubsystemID() (string, error) {
+	return readPCISubsystemID(d.busID)
+}
+
+// GetPCIeLinkInfo returns the current PCIe link generation and width of a device
+func (d sysfsDevice) GetPCIeLinkInfo() (uint, uint, error) {
+	return readPCIeLink(d.busID, "current")
+}
+
+// GetPCIeMaxLinkInfo returns the maximum PCIe link generation and width of a device
+func (d sysfsDevice) GetPCIeMaxLinkInfo() (uint, uint, error) {
+	return readPCIeLink(d.busID, "max")
+}
+
+// GetNumaNode returns the NUMA node of a device, or -1 if the NUMA node is unknown
+func (d sysfsDevice) GetNumaNode() (int, error) {
+	return readNumaNode(d.busID)
+}
+
+// GetSRIOVNumVFs returns the number of enabled SR-IOV virtual functions of a device
+func (d sysfsDevice) GetSRIOVNumVFs() (int, error) {
+	return readSRIOVNumVFs(d.busID)
+}
+
+// GetIOMMUGroup returns the IOMMU group of a device
+func (d sysfsDevice) GetIOMMUGroup() (int, error) {
+	return readIOMMUGroup(d.busID)
+}
+
+// GetBoardID is not supported without IXML
+func (d sysfsDevice) GetBoardID() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// IsMultiGPUBoard is not supported without IXML
+func (d sysfsDevice) IsMultiGPUBoard() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// GetNumFans is not supported without IXML
+func (d sysfsDevice) GetNumFans() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// CheckHealth returns an error if the sysfs entries of a device cannot be read
+func (d sysfsDevice) CheckHealth() error {
+	return checkDeviceHealth(d)
+}
+
+// GetVirtualizationMode returns the virtualization mode of a device
+func (d sysfsDevice) GetVirtualizationMode() (VirtualizationMode, error) {
+	vf, err := isSRIOVVirtualFunction(d.busID)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case vf:
+		return VirtualizationVF, nil
+	case isVirtualMachine():
+		return VirtualizationPassthrough, nil
+	}
+	return VirtualizationNone, nil
+}
+
+// GetBrand is not supported without IXML
+func (d sysfsDevice) GetBrand() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetFullName is not supported without IXML
+func (d sysfsDevice) GetFullName() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetUUID is not supported without IXML
+func (d sysfsDevice) GetUUID() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetUsedMemoryMB is not supported without IXML
+func (d sysfsDevice) GetUsedMemoryMB() (uint64, error) {
+	return 0, ErrNotSupported
+}
+
+// GetTemperatureCelsius is not supported without IXML
+func (d sysfsDevice) GetTemperatureCelsius() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetUtilizationPercent is not supported without IXML
+func (d sysfsDevice) GetUtilizationPercent() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetSerial is not supported without IXML
+func (d sysfsDevice) GetSerial() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetBoardPartNumber is not supported without IXML
+func (d sysfsDevice) GetBoardPartNumber() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetPowerDrawWatts is not supported without IXML
+func (d sysfsDevice) GetPowerDrawWatts() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetPowerLimitWatts is not supported without IXML
+func (d sysfsDevice) GetPowerLimitWatts() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetMaxPowerLimitWatts is not supported without IXML
+func (d sysfsDevice) GetMaxPowerLimitWatts() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetVBIOSVersion is not supported without IXML
+func (d sysfsDevice) GetVBIOSVersion() (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetCudaComputeCapability is not supported without IXML
+func (d sysfsDevice) GetCudaComputeCapability() (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
+
+// HasTensorCores is not supported without IXML
+func (d sysfsDevice) HasTensorCores() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// GetFP16Supported is not supported without IXML
+func (d sysfsDevice) GetFP16Supported() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// GetINT8Supported is not supported without IXML
+func (d sysfsDevice) GetINT8Supported() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// GetECCMode is not supported without IXML
+func (d sysfsDevice) GetECCMode() (bool, bool, error) {
+	return false, false, ErrNotSupported
+}
+
+// GetECCErrors is not supported without IXML
+func (d sysfsDevice) GetECCErrors() (uint64, uint64, error) {
+	return 0, 0, ErrNotSupported
+}
+
+// GetRetiredPages is not supported without IXML
+func (d sysfsDevice) GetRetiredPages() (uint, bool, error) {
+	return 0, false, ErrNotSupported
+}
+
+// GetMultiprocessorCount is not supported without IXML
+func (d sysfsDevice) GetMultiprocessorCount() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetVideoEncoderCount is not supported without IXML
+func (d sysfsDevice) GetVideoEncoderCount() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetVideoDecoderCount is not supported without IXML
+func (d sysfsDevice) GetVideoDecoderCount() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetClocksMHz is not supported without IXML
+func (d sysfsDevice) GetClocksMHz() (uint, uint, error) {
+	return 0, 0, ErrNotSupported
+}
+
+// GetMaxClock is not supported without IXML
+func (d sysfsDevice) GetMaxClock(clockType ClockType) (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetMemoryBusWidth is not supported without IXML
+func (d sysfsDevice) GetMemoryBusWidth() (uint, error) {
+	return 0, ErrNotSupported
+}
+
+// GetPersistenceMode is not supported without IXML
+func (d sysfsDevice) GetPersistenceMode() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// GetMinorNumber is not supported without IXML
+func (d sysfsDevice) GetMinorNumber() (int, error) {
+	return 0, ErrNotSupported
+}
+
+// GetPartitioningMode is not supported without IXML
+func (d sysfsDevice) GetPartitioningMode() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// GetComputeMode is not supported without IXML
+func (d sysfsDevice) GetComputeMode() (ComputeMode, error) {
+	return "", ErrNotSupported
+}
+
+// GetDisplayActive is not supported without IXML
+func (d sysfsDevice) GetDisplayActive() (bool, error) {
+	return false, ErrNotSupported
+}
+
+// GetInterconnectLinks is not supported without IXML
+func (d sysfsDevice) GetInterconnectLinks() (int, error) {
+	return 0, ErrNotSupported
+}
+
+// readPCIeLink reads the current or maximum PCIe link generation and width of the PCI device
+// with the specified bus ID from sysfs.
+func readPCIeLink(busID string, kind string) (uint, uint, error) {
+	speed, err := readSysfsString(filepath.Join(pciDevicePath(busID), kind+"_link_speed"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not read PCIe link speed: %v", err)
+	}
+	// The speed is reported as e.g. "16.0 GT/s PCIe" or "2.5 GT/s".
+	value, _, _ := strings.Cut(speed, " ")
+	gen, ok := pcieLinkSpeedGenerations[value]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown PCIe link speed %q", speed)
+	}
+
+	width, err := readSysfsInt(filepath.Join(pciDevicePath(busID), kind+"_link_width"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not read PCIe link width: %v", err)
+	}
+
+	return gen, uint(width), nil
+}
+
+// readSysfsString reads a sysfs file holding a single value.
+func readSysfsString(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
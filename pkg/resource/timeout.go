@@ -0,0 +1,434 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeoutManager is a manager that bounds the duration of every call to the wrapped
+// manager. A call that does not complete in time fails with an error wrapping
+// context.DeadlineExceeded; the call itself cannot be interrupted and is left to finish in
+// the background.
+type TimeoutManager struct {
+	Manager
+	timeout time.Duration
+}
+
+var _ Manager = (*TimeoutManager)(nil)
+
+// NewTimeoutManager creates a new manager that fails calls to the given manager taking
+// longer than timeout. A timeout of zero or less disables the timeout.
+func NewTimeoutManager(manager Manager, timeout time.Duration) *TimeoutManager {
+	return &TimeoutManager{
+		Manager: manager,
+		timeout: timeout,
+	}
+}
+
+// Init initializes the wrapped manager
+func (m *TimeoutManager) Init() error {
+	_, err := callWithTimeout(m.timeout, "Init", func() (struct{}, error) {
+		return struct{}{}, m.Manager.Init()
+	})
+	return err
+}
+
+// Shutdown shuts down the wrapped manager
+func (m *TimeoutManager) Shutdown() error {
+	_, err := callWithTimeout(m.timeout, "Shutdown", func() (struct{}, error) {
+		return struct{}{}, m.Manager.Shutdown()
+	})
+	return err
+}
+
+// GetDevices returns the devices of the wrapped manager, whose calls are bounded by the
+// timeout as well
+func (m *TimeoutManager) GetDevices() ([]Device, error) {
+	devices, err := callWithTimeout(m.timeout, "GetDevices", m.Manager.GetDevices)
+	if err != nil || m.timeout <= 0 {
+		return devices, err
+	}
+
+	wrapped := make([]Device, len(devices))
+	for i, device := range devices {
+		wrapped[i] = &timeoutDevice{
+			device:  device,
+			timeout: m.timeout,
+		}
+	}
+	return wrapped, nil
+}
+
+// GetIXDriverVersion returns the ix driver version of the wrapped manager
+func (m *TimeoutManager) GetIXDriverVersion() (string, error) {
+	return callWithTimeout(m.timeout, "GetIXDriverVersion", m.Manager.GetIXDriverVersion)
+}
+
+// GetCudaDriverVersion returns the cuda driver version of the wrapped manager
+func (m *TimeoutManager) GetCudaDriverVersion() (*uint, *uint, error) {
+	version, err := callWithTimeout(m.timeout, "GetCudaDriverVersion", func() ([2]*uint, error) {
+		major, minor, err := m.Manager.GetCudaDriverVersion()
+		return [2]*uint{major, minor}, err
+	})
+	return version[0], version[1], err
+}
+
+// GetCudaRuntimeVersion returns the cuda runtime version of the wrapped manager
+func (m *TimeoutManager) GetCudaRuntimeVersion() (*uint, *uint, error) {
+	version, err := callWithTimeout(m.timeout, "GetCudaRuntimeVersion", func() ([2]*uint, error) {
+		major, minor, err := m.Manager.GetCudaRuntimeVersion()
+		return [2]*uint{major, minor}, err
+	})
+	return version[0], version[1], err
+}
+
+// GetIXMLVersion returns the IXML library version of the wrapped manager
+func (m *TimeoutManager) GetIXMLVersion() (string, error) {
+	return callWithTimeout(m.timeout, "GetIXMLVersion", m.Manager.GetIXMLVersion)
+}
+
+// DiscoverySource returns the discovery source of the wrapped manager, or an empty string
+// if the wrapped manager does not report one
+func (m *TimeoutManager) DiscoverySource() string {
+	if s, ok := m.Manager.(DiscoverySourcer); ok {
+		return s.DiscoverySource()
+	}
+	return ""
+}
+
+// CircuitOpen returns whether the circuit breaker of the wrapped manager, if any, is open
+func (m *TimeoutManager) CircuitOpen() bool {
+	if s, ok := m.Manager.(CircuitStater); ok {
+		return s.CircuitOpen()
+	}
+	return false
+}
+
+// timeoutDevice is a device that bounds the duration of every call to the wrapped device,
+// as the TimeoutManager does for the calls to the manager.
+type timeoutDevice struct {
+	device  Device
+	timeout time.Duration
+}
+
+var _ Device = (*timeoutDevice)(nil)
+
+// pair holds the two values returned by a device query.
+type pair[A, B any] struct {
+	a A
+	b B
+}
+
+// GetName implements Device
+func (d *timeoutDevice) GetName() (string, error) {
+	return callWithTimeout(d.timeout, "GetName", d.device.GetName)
+}
+
+// GetBrand implements Device
+func (d *timeoutDevice) GetBrand() (string, error) {
+	return callWithTimeout(d.timeout, "GetBrand", d.device.GetBrand)
+}
+
+// GetFullName implements Device
+func (d *timeoutDevice) GetFullName() (string, error) {
+	return callWithTimeout(d.timeout, "GetFullName", d.device.GetFullName)
+}
+
+// GetUUID implements Device
+func (d *timeoutDevice) GetUUID() (string, error) {
+	return callWithTimeout(d.timeout, "GetUUID", d.device.GetUUID)
+}
+
+// GetTotalMemoryMB implements Device
+func (d *timeoutDevice) GetTotalMemoryMB() (uint64, error) {
+	return callWithTimeout(d.timeout, "GetTotalMemoryMB", d.device.GetTotalMemoryMB)
+}
+
+// GetUsedMemoryMB implements Device
+func (d *timeoutDevice) GetUsedMemoryMB() (uint64, error) {
+	return callWithTimeout(d.timeout, "GetUsedMemoryMB", d.device.GetUsedMemoryMB)
+}
+
+// GetTemperatureCelsius implements Device
+func (d *timeoutDevice) GetTemperatureCelsius() (uint, error) {
+	return callWithTimeout(d.timeout, "GetTemperatureCelsius", d.device.GetTemperatureCelsius)
+}
+
+// GetUtilizationPercent implements Device
+func (d *timeoutDevice) GetUtilizationPercent() (uint, error) {
+	return callWithTimeout(d.timeout, "GetUtilizationPercent", d.device.GetUtilizationPercent)
+}
+
+// GetPCIBusID implements Device
+func (d *timeoutDevice) GetPCIBusID() (string, error) {
+	return callWithTimeout(d.timeout, "GetPCIBusID", d.device.GetPCIBusID)
+}
+
+// GetPCIVendorID implements Device
+func (d *timeoutDevice) GetPCIVendorID() (string, error) {
+	return callWithTimeout(d.timeout, "GetPCIVendorID", d.device.GetPCIVendorID)
+}
+
+// GetPCIDeviceID implements Device
+func (d *timeoutDevice) GetPCIDeviceID() (string, error) {
+	return callWithTimeout(d.timeout, "GetPCIDeviceID", d.device.GetPCIDeviceID)
+}
+
+// GetPCISubsystemID implements Device
+func (d *timeoutDevice) GetPCISubsystemID() (string, error) {
+	return callWithTimeout(d.timeout, "GetPCISubsystemID", d.device.GetPCISubsystemID)
+}
+
+// GetSerial implements Device
+func (d *timeoutDevice) GetSerial() (string, error) {
+	return callWithTimeout(d.timeout, "GetSerial", d.device.GetSerial)
+}
+
+// GetBoardPartNumber implements Device
+func (d *timeoutDevice) GetBoardPartNumber() (string, error) {
+	return callWithTimeout(d.timeout, "GetBoardPartNumber", d.device.GetBoardPartNumber)
+}
+
+// GetPowerDrawWatts implements Device
+func (d *timeoutDevice) GetPowerDrawWatts() (uint, error) {
+	return callWithTimeout(d.timeout, "GetPowerDrawWatts", d.device.GetPowerDrawWatts)
+}
+
+// GetPowerLimitWatts implements Device
+func (d *timeoutDevice) GetPowerLimitWatts() (uint, error) {
+	return callWithTimeout(d.timeout, "GetPowerLimitWatts", d.device.GetPowerLimitWatts)
+}
+
+// GetMaxPowerLimitWatts implements Device
+func (d *timeoutDevice) GetMaxPowerLimitWatts() (uint, error) {
+	return callWithTimeout(d.timeout, "GetMaxPowerLimitWatts", d.device.GetMaxPowerLimitWatts)
+}
+
+// GetPCIeLinkInfo implements Device
+func (d *timeoutDevice) GetPCIeLinkInfo() (uint, uint, error) {
+	p, err := callWithTimeout(d.timeout, "GetPCIeLinkInfo", func() (pair[uint, uint], error) {
+		a, b, err := d.device.GetPCIeLinkInfo()
+		return pair[uint, uint]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetPCIeMaxLinkInfo implements Device
+func (d *timeoutDevice) GetPCIeMaxLinkInfo() (uint, uint, error) {
+	p, err := callWithTimeout(d.timeout, "GetPCIeMaxLinkInfo", func() (pair[uint, uint], error) {
+		a, b, err := d.device.GetPCIeMaxLinkInfo()
+		return pair[uint, uint]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetVBIOSVersion implements Device
+func (d *timeoutDevice) GetVBIOSVersion() (string, error) {
+	return callWithTimeout(d.timeout, "GetVBIOSVersion", d.device.GetVBIOSVersion)
+}
+
+// GetCudaComputeCapability implements Device
+func (d *timeoutDevice) GetCudaComputeCapability() (int, int, error) {
+	p, err := callWithTimeout(d.timeout, "GetCudaComputeCapability", func() (pair[int, int], error) {
+		a, b, err := d.device.GetCudaComputeCapability()
+		return pair[int, int]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// HasTensorCores implements Device
+func (d *timeoutDevice) HasTensorCores() (bool, error) {
+	return callWithTimeout(d.timeout, "HasTensorCores", d.device.HasTensorCores)
+}
+
+// GetFP16Supported implements Device
+func (d *timeoutDevice) GetFP16Supported() (bool, error) {
+	return callWithTimeout(d.timeout, "GetFP16Supported", d.device.GetFP16Supported)
+}
+
+// GetINT8Supported implements Device
+func (d *timeoutDevice) GetINT8Supported() (bool, error) {
+	return callWithTimeout(d.timeout, "GetINT8Supported", d.device.GetINT8Supported)
+}
+
+// GetECCMode implements Device
+func (d *timeoutDevice) GetECCMode() (bool, bool, error) {
+	p, err := callWithTimeout(d.timeout, "GetECCMode", func() (pair[bool, bool], error) {
+		a, b, err := d.device.GetECCMode()
+		return pair[bool, bool]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetECCErrors implements Device
+func (d *timeoutDevice) GetECCErrors() (uint64, uint64, error) {
+	p, err := callWithTimeout(d.timeout, "GetECCErrors", func() (pair[uint64, uint64], error) {
+		a, b, err := d.device.GetECCErrors()
+		return pair[uint64, uint64]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetRetiredPages implements Device
+func (d *timeoutDevice) GetRetiredPages() (uint, bool, error) {
+	p, err := callWithTimeout(d.timeout, "GetRetiredPages", func() (pair[uint, bool], error) {
+		a, b, err := d.device.GetRetiredPages()
+		return pair[uint, bool]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetMultiprocessorCount implements Device
+func (d *timeoutDevice) GetMultiprocessorCount() (uint, error) {
+	return callWithTimeout(d.timeout, "GetMultiprocessorCount", d.device.GetMultiprocessorCount)
+}
+
+// GetVideoEncoderCount implements Device
+func (d *timeoutDevice) GetVideoEncoderCount() (uint, error) {
+	return callWithTimeout(d.timeout, "GetVideoEncoderCount", d.device.GetVideoEncoderCount)
+}
+
+// GetVideoDecoderCount implements Device
+func (d *timeoutDevice) GetVideoDecoderCount() (uint, error) {
+	return callWithTimeout(d.timeout, "GetVideoDecoderCount", d.device.GetVideoDecoderCount)
+}
+
+// GetClocksMHz implements Device
+func (d *timeoutDevice) GetClocksMHz() (uint, uint, error) {
+	p, err := callWithTimeout(d.timeout, "GetClocksMHz", func() (pair[uint, uint], error) {
+		a, b, err := d.device.GetClocksMHz()
+		return pair[uint, uint]{a, b}, err
+	})
+	return p.a, p.b, err
+}
+
+// GetMaxClock implements Device
+func (d *timeoutDevice) GetMaxClock(clockType ClockType) (uint, error) {
+	return callWithTimeout(d.timeout, "GetMaxClock", func() (uint, error) {
+		return d.device.GetMaxClock(clockType)
+	})
+}
+
+// GetMemoryBusWidth implements Device
+func (d *timeoutDevice) GetMemoryBusWidth() (uint, error) {
+	return callWithTimeout(d.timeout, "GetMemoryBusWidth", d.device.GetMemoryBusWidth)
+}
+
+// GetPersistenceMode implements Device
+func (d *timeoutDevice) GetPersistenceMode() (bool, error) {
+	return callWithTimeout(d.timeout, "GetPersistenceMode", d.device.GetPersistenceMode)
+}
+
+// GetNumaNode implements Device
+func (d *timeoutDevice) GetNumaNode() (int, error) {
+	return callWithTimeout(d.timeout, "GetNumaNode", d.device.GetNumaNode)
+}
+
+// GetMinorNumber implements Device
+func (d *timeoutDevice) GetMinorNumber() (int, error) {
+	return callWithTimeout(d.timeout, "GetMinorNumber", d.device.GetMinorNumber)
+}
+
+// GetPartitioningMode implements Device
+func (d *timeoutDevice) GetPartitioningMode() (bool, error) {
+	return callWithTimeout(d.timeout, "GetPartitioningMode", d.device.GetPartitioningMode)
+}
+
+// GetSRIOVNumVFs implements Device
+func (d *timeoutDevice) GetSRIOVNumVFs() (int, error) {
+	return callWithTimeout(d.timeout, "GetSRIOVNumVFs", d.device.GetSRIOVNumVFs)
+}
+
+// GetVirtualizationMode implements Device
+func (d *timeoutDevice) GetVirtualizationMode() (VirtualizationMode, error) {
+	return callWithTimeout(d.timeout, "GetVirtualizationMode", d.device.GetVirtualizationMode)
+}
+
+// GetComputeMode implements Device
+func (d *timeoutDevice) GetComputeMode() (ComputeMode, error) {
+	return callWithTimeout(d.timeout, "GetComputeMode", d.device.GetComputeMode)
+}
+
+// GetDisplayActive implements Device
+func (d *timeoutDevice) GetDisplayActive() (bool, error) {
+	return callWithTimeout(d.timeout, "GetDisplayActive", d.device.GetDisplayActive)
+}
+
+// GetInterconnectLinks implements Device
+func (d *timeoutDevice) GetInterconnectLinks() (int, error) {
+	return callWithTimeout(d.timeout, "GetInterconnectLinks", d.device.GetInterconnectLinks)
+}
+
+// GetIOMMUGroup implements Device
+func (d *timeoutDevice) GetIOMMUGroup() (int, error) {
+	return callWithTimeout(d.timeout, "GetIOMMUGroup", d.device.GetIOMMUGroup)
+}
+
+// GetBoardID implements Device
+func (d *timeoutDevice) GetBoardID() (uint, error) {
+	return callWithTimeout(d.timeout, "GetBoardID", d.device.GetBoardID)
+}
+
+// IsMultiGPUBoard implements Device
+func (d *timeoutDevice) IsMultiGPUBoard() (bool, error) {
+	return callWithTimeout(d.timeout, "IsMultiGPUBoard", d.device.IsMultiGPUBoard)
+}
+
+// GetNumFans implements Device
+func (d *timeoutDevice) GetNumFans() (uint, error) {
+	return callWithTimeout(d.timeout, "GetNumFans", d.device.GetNumFans)
+}
+
+// CheckHealth implements Device
+func (d *timeoutDevice) CheckHealth() error {
+	_, err := callWithTimeout(d.timeout, "CheckHealth", func() (struct{}, error) {
+		return struct{}{}, d.device.CheckHealth()
+	})
+	return err
+}
+
+// callWithTimeout runs f in a goroutine and returns its result, or an error wrapping
+// context.DeadlineExceeded if f does not return within timeout.
+func callWithTimeout[T any](timeout time.Duration, name string, f func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		return f()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	// The channel is buffered so that the goroutine can exit after a timeout.
+	done := make(chan result, 1)
+	go func() {
+		value, err := f()
+		done <- result{value, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		var zero T
+		return zero, fmt.Errorf("%s did not complete within %v: %w", name, timeout, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package resource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sleepingManager is a mock manager whose driver version query sleeps for delay.
+type sleepingManager struct {
+	*MockManager
+	delay time.Duration
+}
+
+// GetIXDriverVersion sleeps for the delay of the manager before returning the driver version
+func (m *sleepingManager) GetIXDriverVersion() (string, error) {
+	time.Sleep(m.delay)
+	return m.MockManager.GetIXDriverVersion()
+}
+
+// sleepingDevice is a mock device whose serial query sleeps for delay.
+type sleepingDevice struct {
+	*MockDevice
+	delay time.Duration
+}
+
+// GetSerial sleeps for the delay of the device before returning the serial
+func (d *sleepingDevice) GetSerial() (string, error) {
+	time.Sleep(d.delay)
+	return d.MockDevice.GetSerial()
+}
+
+func TestTimeoutManager(t *testing.T) {
+	testCases := []struct {
+		description string
+		timeout     time.Duration
+		delay       time.Duration
+		expectedErr error
+	}{
+		{
+			description: "call completing in time succeeds",
+			timeout:     time.Second,
+			delay:       time.Millisecond,
+		},
+		{
+			description: "call not completing in time fails",
+			timeout:     10 * time.Millisecond,
+			delay:       time.Second,
+			expectedErr: context.DeadlineExceeded,
+		},
+		{
+			description: "zero timeout disables the timeout",
+			timeout:     0,
+			delay:       50 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			device := &sleepingDevice{
+				MockDevice: &MockDevice{Serial: "serial-1"},
+				delay:      tc.delay,
+			}
+			mock := NewMockManager(WithDriverVersion("4.1.0"))
+			mock.Devices = []Device{device}
+			m := NewTimeoutManager(&sleepingManager{MockManager: mock, delay: tc.delay}, tc.timeout)
+
+			version, err := m.GetIXDriverVersion()
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected driver version error %v, got %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr == nil && version != "4.1.0" {
+				t.Errorf("expected driver version 4.1.0, got %q", version)
+			}
+
+			devices, err := m.GetDevices()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			serial, err := devices[0].GetSerial()
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected serial error %v, got %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr == nil && serial != "serial-1" {
+				t.Errorf("expected serial serial-1, got %q", serial)
+			}
+		})
+	}
+}
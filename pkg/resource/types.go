@@ -16,17 +16,122 @@
  */
 package resource
 
+import "errors"
+
+// ErrNotSupported is returned when a query is not supported by the device or driver.
+var ErrNotSupported = errors.New("not supported")
+
+// ClockType identifies a clock domain of a device
+type ClockType int
+
+// Clock domains of a device
+const (
+	ClockSM ClockType = iota
+	ClockMemory
+)
+
+// VirtualizationMode describes how a device is virtualized
+type VirtualizationMode string
+
+// Virtualization modes of a device
+const (
+	// VirtualizationNone is a physical device on bare metal.
+	VirtualizationNone VirtualizationMode = "none"
+	// VirtualizationPassthrough is a physical device passed through to a virtual machine.
+	VirtualizationPassthrough VirtualizationMode = "passthrough"
+	// VirtualizationVF is an SR-IOV virtual function.
+	VirtualizationVF VirtualizationMode = "vf"
+)
+
+// ComputeMode describes which processes may use a device
+type ComputeMode string
+
+// Compute modes of a device, from the least to the most restrictive
+const (
+	// ComputeModeDefault allows multiple processes to use the device.
+	ComputeModeDefault ComputeMode = "default"
+	// ComputeModeExclusiveProcess allows only one process to use the device.
+	ComputeModeExclusiveProcess ComputeMode = "exclusive-process"
+	// ComputeModeProhibited allows no process to use the device.
+	ComputeModeProhibited ComputeMode = "prohibited"
+)
+
+// Backends with which a manager discovers devices
+const (
+	DiscoverySourceIXML  = "ixml"
+	DiscoverySourceSysfs = "sysfs"
+)
+
+// DiscoverySourcer is implemented by managers that report the backend they discover
+// devices with.
+type DiscoverySourcer interface {
+	DiscoverySource() string
+}
+
+// CircuitStater is implemented by managers that report whether a circuit breaker has
+// stopped calls to the underlying library.
+type CircuitStater interface {
+	CircuitOpen() bool
+}
+
 // Manager defines an interface for managing devices
 type Manager interface {
 	Init() error
 	Shutdown() error
 	GetDevices() ([]Device, error)
 	GetIXDriverVersion() (string, error)
+	GetCudaDriverVersion() (*uint, *uint, error)
 	GetCudaRuntimeVersion() (*uint, *uint, error)
+	GetIXMLVersion() (string, error)
 }
 
 // Device defines an interface for a device with which labels are associated
 type Device interface {
 	GetName() (string, error)
+	GetBrand() (string, error)
+	GetFullName() (string, error)
+	GetUUID() (string, error)
 	GetTotalMemoryMB() (uint64, error)
+	GetUsedMemoryMB() (uint64, error)
+	GetTemperatureCelsius() (uint, error)
+	GetUtilizationPercent() (uint, error)
+	GetPCIBusID() (string, error)
+	GetPCIVendorID() (string, error)
+	GetPCIDeviceID() (string, error)
+	GetPCISubsystemID() (string, error)
+	GetSerial() (string, error)
+	GetBoardPartNumber() (string, error)
+	GetPowerDrawWatts() (uint, error)
+	GetPowerLimitWatts() (uint, error)
+	GetMaxPowerLimitWatts() (uint, error)
+	GetPCIeLinkInfo() (gen uint, width uint, err error)
+	GetPCIeMaxLinkInfo() (gen uint, width uint, err error)
+	GetVBIOSVersion() (string, error)
+	GetCudaComputeCapability() (major int, minor int, err error)
+	HasTensorCores() (bool, error)
+	GetFP16Supported() (bool, error)
+	GetINT8Supported() (bool, error)
+	GetECCMode() (current bool, pending bool, err error)
+	GetECCErrors() (single uint64, double uint64, err error)
+	GetRetiredPages() (count uint, pending bool, err error)
+	GetMultiprocessorCount() (uint, error)
+	GetVideoEncoderCount() (uint, error)
+	GetVideoDecoderCount() (uint, error)
+	GetClocksMHz() (smClock uint, memClock uint, err error)
+	GetMaxClock(clockType ClockType) (uint, error)
+	GetMemoryBusWidth() (uint, error)
+	GetPersistenceMode() (enabled bool, err error)
+	GetNumaNode() (int, error)
+	GetMinorNumber() (int, error)
+	GetPartitioningMode() (enabled bool, err error)
+	GetSRIOVNumVFs() (int, error)
+	GetVirtualizationMode() (VirtualizationMode, error)
+	GetComputeMode() (ComputeMode, error)
+	GetDisplayActive() (bool, error)
+	GetInterconnectLinks() (int, error)
+	GetIOMMUGroup() (int, error)
+	GetBoardID() (uint, error)
+	IsMultiGPUBoard() (bool, error)
+	GetNumFans() (uint, error)
+	CheckHealth() error
 }
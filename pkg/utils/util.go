@@ -17,8 +17,13 @@
 package utils
 
 import (
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
 )
 
 // Signals creates a channel for the specified signals.
@@ -28,3 +33,48 @@ func Signals(sigs ...os.Signal) chan os.Signal {
 
 	return sigChan
 }
+
+// WatchFile watches the file at path and signals changes to it on the returned channel.
+// The directory of the file is watched, so that a file replaced by a rename, such as a
+// mounted ConfigMap, is followed. The watch stops when the returned closer is closed.
+func WatchFile(path string) (<-chan struct{}, io.Closer, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, nil, err
+	}
+
+	path = filepath.Clean(path)
+	changes := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Mounted ConfigMaps are updated by swapping the ..data symlink.
+				if filepath.Clean(event.Name) != path && filepath.Base(event.Name) != "..data" {
+					continue
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) && !event.Has(fsnotify.Remove) {
+					continue
+				}
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				klog.Warningf("Error watching %s: %v", path, err)
+			}
+		}
+	}()
+
+	return changes, watcher, nil
+}
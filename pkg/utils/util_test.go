@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2024, Shanghai Iluvatar CoreX Semiconductor Co., Ltd.
+ * All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may
+ * not use this file except in compliance with the License. You may obtain
+ * a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFile(t *testing.T) {
+	testCases := []struct {
+		description string
+		change      func(t *testing.T, dir, path string)
+		expected    bool
+	}{
+		{
+			description: "write",
+			change: func(t *testing.T, dir, path string) {
+				writeFile(t, path, "b=2\n")
+			},
+			expected: true,
+		},
+		{
+			description: "replace by rename",
+			change: func(t *testing.T, dir, path string) {
+				tmp := filepath.Join(dir, ".labels.tmp")
+				writeFile(t, tmp, "b=2\n")
+				if err := os.Rename(tmp, path); err != nil {
+					t.Fatalf("failed to rename file: %v", err)
+				}
+			},
+			expected: true,
+		},
+		{
+			description: "remove",
+			change: func(t *testing.T, dir, path string) {
+				if err := os.Remove(path); err != nil {
+					t.Fatalf("failed to remove file: %v", err)
+				}
+			},
+			expected: true,
+		},
+		{
+			description: "ConfigMap update",
+			change: func(t *testing.T, dir, path string) {
+				if err := os.Symlink(dir, filepath.Join(dir, "..data")); err != nil {
+					t.Fatalf("failed to create symlink: %v", err)
+				}
+			},
+			expected: true,
+		},
+		{
+			description: "other file in the directory",
+			change: func(t *testing.T, dir, path string) {
+				writeFile(t, filepath.Join(dir, "other"), "c=3\n")
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "labels")
+			writeFile(t, path, "a=1\n")
+
+			changes, watcher, err := WatchFile(path)
+			if err != nil {
+				t.Fatalf("failed to watch file: %v", err)
+			}
+			defer watcher.Close()
+
+			tc.change(t, dir, path)
+
+			// A missed change is waited for longer than an unexpected one, to keep the test
+			// reliable on slow machines.
+			timeout := 200 * time.Millisecond
+			if tc.expected {
+				timeout = 5 * time.Second
+			}
+			var changed bool
+			select {
+			case <-changes:
+				changed = true
+			case <-time.After(timeout):
+			}
+			if changed != tc.expected {
+				t.Errorf("expected change signaled %v, got %v", tc.expected, changed)
+			}
+		})
+	}
+}
+
+func TestWatchFileMissingDirectory(t *testing.T) {
+	if _, _, err := WatchFile(filepath.Join(t.TempDir(), "missing", "labels")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+// writeFile writes data to the file at path.
+func writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}